@@ -17,6 +17,7 @@ import (
 	"github.com/origo-stack/performance-simulator/internal/simulator"
 	"github.com/origo-stack/performance-simulator/internal/websocket"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -31,13 +32,32 @@ func main() {
 	}
 
 	// Initialize database
-	db, err := database.Initialize(cfg.Database.DSN())
+	var db *gorm.DB
+	if cfg.Database.Driver == "sqlite" {
+		db, err = database.InitializeSQLite(cfg.Database.Path)
+	} else {
+		db, err = database.Initialize(cfg.Database.DSN())
+	}
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
-	// Initialize metrics collector
-	metricsCollector := metrics.NewCollector()
+	// Initialize the historical metrics store and metrics collector
+	metricsStore, err := metrics.NewSQLiteStore(cfg.Metrics.StorePath, metrics.DefaultRetentionPolicy())
+	if err != nil {
+		log.Fatalf("Failed to initialize metrics store: %v", err)
+	}
+	defer metricsStore.Close()
+
+	metricsCollector := metrics.NewCollectorWithStore(metricsStore)
+
+	// Initialize Prometheus exporter and, if configured, start pushing to a
+	// Pushgateway for simulations that finish faster than any scrape interval.
+	promExporter := metrics.NewPrometheusExporter(metricsCollector)
+	if cfg.Metrics.PushgatewayURL != "" {
+		promExporter.StartPushing(context.Background(), cfg.Metrics.PushgatewayURL, cfg.Metrics.PushJobName,
+			time.Duration(cfg.Metrics.PushIntervalMs)*time.Millisecond)
+	}
 
 	// Initialize WebSocket hub
 	wsHub := websocket.NewHub()
@@ -47,7 +67,7 @@ func main() {
 	simEngine := simulator.NewEngine(db, metricsCollector, wsHub)
 
 	// Setup HTTP server
-	router := setupRoutes(simEngine, wsHub, metricsCollector)
+	router := setupRoutes(simEngine, wsHub, metricsCollector, promExporter)
 	
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
@@ -80,7 +100,7 @@ func main() {
 	logrus.Info("Performance simulator stopped")
 }
 
-func setupRoutes(simEngine *simulator.Engine, wsHub *websocket.Hub, metricsCollector *metrics.Collector) *gin.Engine {
+func setupRoutes(simEngine *simulator.Engine, wsHub *websocket.Hub, metricsCollector *metrics.Collector, promExporter *metrics.PrometheusExporter) *gin.Engine {
 	// Set Gin to release mode for production
 	gin.SetMode(gin.ReleaseMode)
 	
@@ -118,11 +138,13 @@ func setupRoutes(simEngine *simulator.Engine, wsHub *websocket.Hub, metricsColle
 		api.GET("/simulations", simEngine.ListSimulations)
 		api.GET("/simulations/:id", simEngine.GetSimulation)
 		api.POST("/simulations/:id/stop", simEngine.StopSimulation)
+		api.POST("/simulations/:id/replay", simEngine.ReplaySimulation)
 		api.DELETE("/simulations/:id", simEngine.DeleteSimulation)
 
 		// Configuration
 		api.GET("/configs", simEngine.GetConfigurations)
 		api.POST("/configs", simEngine.SaveConfiguration)
+		api.GET("/configs/saved", simEngine.GetSavedConfigurations)
 		
 		// Real-time metrics
 		api.GET("/metrics/live", metricsCollector.GetLiveMetrics)
@@ -130,6 +152,7 @@ func setupRoutes(simEngine *simulator.Engine, wsHub *websocket.Hub, metricsColle
 		
 		// Time-series data
 		api.GET("/simulations/:id/timeseries", simEngine.GetTimeSeriesData)
+		api.GET("/simulations/:id/export", simEngine.ExportSimulation)
 		
 		// Mega-scale presets
 		api.GET("/presets/megascale", simEngine.GetMegaScalePresets)
@@ -137,6 +160,11 @@ func setupRoutes(simEngine *simulator.Engine, wsHub *websocket.Hub, metricsColle
 		// Service profiles
 		api.GET("/services", simEngine.GetServiceProfiles)
 		api.POST("/services", simEngine.CreateServiceProfile)
+		api.POST("/services/invalidate", simEngine.InvalidateServiceCapabilities)
+
+		// Corpus import
+		api.POST("/simulations/import/har", simEngine.ImportHAR)
+		api.POST("/simulations/import/openapi", simEngine.ImportOpenAPI)
 		
 		// Testing endpoints
 		api.POST("/test-connection", simEngine.TestConnection)
@@ -148,11 +176,15 @@ func setupRoutes(simEngine *simulator.Engine, wsHub *websocket.Hub, metricsColle
 		// Validation endpoints
 		api.POST("/validation/test", simEngine.TestValidation)
 		api.GET("/validation/results/:id", simEngine.GetValidationResults)
+		api.GET("/validation/stats/:id", simEngine.GetValidationStats)
 	}
 
 	// WebSocket endpoint for real-time data
 	router.GET("/ws", wsHub.HandleWebSocket)
 
+	// Prometheus scrape endpoint
+	router.GET("/metrics", gin.WrapH(promExporter.Handler()))
+
 	// Static file serving for frontend (if built)
 	router.Static("/static", "../frontend/build/static")
 	router.StaticFile("/", "../frontend/build/index.html")
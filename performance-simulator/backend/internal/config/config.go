@@ -13,6 +13,7 @@ type Config struct {
 	Server   ServerConfig   `yaml:"server"`
 	Database DatabaseConfig `yaml:"database"`
 	Logging  LoggingConfig  `yaml:"logging"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -21,8 +22,12 @@ type ServerConfig struct {
 	Host string `yaml:"host"`
 }
 
-// DatabaseConfig contains database connection settings
+// DatabaseConfig contains database connection settings. Driver selects
+// between "postgres" (the default) and "sqlite"; Path is only used for
+// "sqlite" and the rest only for "postgres".
 type DatabaseConfig struct {
+	Driver   string `yaml:"driver"`
+	Path     string `yaml:"path"`
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	User     string `yaml:"user"`
@@ -43,6 +48,14 @@ type LoggingConfig struct {
 	Format string `yaml:"format"`
 }
 
+// MetricsConfig contains Prometheus exporter and historical store settings
+type MetricsConfig struct {
+	PushgatewayURL string `yaml:"pushgateway_url"`
+	PushJobName    string `yaml:"push_job_name"`
+	PushIntervalMs int    `yaml:"push_interval_ms"`
+	StorePath      string `yaml:"store_path"`
+}
+
 // Load reads configuration from a YAML file
 func Load(filepath string) (*Config, error) {
 	// Default configuration
@@ -52,6 +65,7 @@ func Load(filepath string) (*Config, error) {
 			Host: "localhost",
 		},
 		Database: DatabaseConfig{
+			Driver:   "postgres",
 			Host:     "localhost",
 			Port:     5434,
 			User:     "simulator_user",
@@ -63,6 +77,11 @@ func Load(filepath string) (*Config, error) {
 			Level:  "info",
 			Format: "json",
 		},
+		Metrics: MetricsConfig{
+			PushJobName:    "performance_simulator",
+			PushIntervalMs: 15000,
+			StorePath:      "data/metrics.db",
+		},
 	}
 
 	// Try to read config file
@@ -82,6 +101,12 @@ func Load(filepath string) (*Config, error) {
 
 // loadEnvOverrides overrides configuration with environment variables
 func loadEnvOverrides(config *Config) {
+	if driver := os.Getenv("DB_DRIVER"); driver != "" {
+		config.Database.Driver = driver
+	}
+	if path := os.Getenv("DB_PATH"); path != "" {
+		config.Database.Path = path
+	}
 	if host := os.Getenv("DB_HOST"); host != "" {
 		config.Database.Host = host
 	}
@@ -105,4 +130,7 @@ func loadEnvOverrides(config *Config) {
 	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
 		config.Logging.Level = logLevel
 	}
+	if pushgatewayURL := os.Getenv("METRICS_PUSHGATEWAY_URL"); pushgatewayURL != "" {
+		config.Metrics.PushgatewayURL = pushgatewayURL
+	}
 }
@@ -5,12 +5,24 @@ import (
 	"time"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// Initialize sets up the database connection and creates tables
+// Initialize sets up the Postgres database connection and creates tables.
 func Initialize(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	return initialize(postgres.Open(dsn))
+}
+
+// InitializeSQLite sets up a local SQLite database at path and creates
+// tables, for operators who don't want to run Postgres for a single
+// simulator instance.
+func InitializeSQLite(path string) (*gorm.DB, error) {
+	return initialize(sqlite.Open(path))
+}
+
+func initialize(dialector gorm.Dialector) (*gorm.DB, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -21,6 +33,10 @@ func Initialize(dsn string) (*gorm.DB, error) {
 		&SimulationResult{},
 		&ServiceProfile{},
 		&Configuration{},
+		&GoldenResponse{},
+		&SimulationValidationConfig{},
+		&TimeSeriesEntry{},
+		&ValidationRecord{},
 	)
 	if err != nil {
 		return nil, err
@@ -39,31 +55,39 @@ type Simulation struct {
 	Duration    int64     `gorm:"not null" json:"duration"` // Duration in seconds
 	Users       int       `gorm:"not null" json:"users"`
 	Pattern     string    `gorm:"size:50" json:"pattern"`
-	Status      string    `gorm:"size:20;default:'created'" json:"status"`
-	StartTime   time.Time `json:"start_time"`
+	Status      string    `gorm:"size:20;default:'created';index" json:"status"`
+	StartTime   time.Time `gorm:"index" json:"start_time"`
 	EndTime     *time.Time `json:"end_time,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
-	
+
+	// ConfigJSON holds the full simulator.SimulationConfig (protocol-specific
+	// settings, auth, validation, capture rules, ...) so a simulation can be
+	// replayed exactly as configured; the scalar columns above exist purely
+	// so ListSimulations can filter/sort in SQL without decoding this blob.
+	ConfigJSON string `gorm:"type:text" json:"-"`
+
 	// Relations
-	Results []SimulationResult `gorm:"foreignKey:SimulationID" json:"results,omitempty"`
+	Results    []SimulationResult          `gorm:"foreignKey:SimulationID" json:"results,omitempty"`
+	Validation *SimulationValidationConfig `gorm:"foreignKey:SimulationID" json:"validation,omitempty"`
 }
 
 // SimulationResult stores performance metrics for a simulation
 type SimulationResult struct {
-	ID              int64     `gorm:"primaryKey" json:"id"`
-	SimulationID    int64     `gorm:"index;not null" json:"simulation_id"`
-	TotalRequests   int64     `gorm:"not null" json:"total_requests"`
-	SuccessfulReqs  int64     `gorm:"not null" json:"successful_requests"`
-	FailedRequests  int64     `gorm:"not null" json:"failed_requests"`
-	AverageRPS      float64   `gorm:"not null" json:"average_rps"`
-	MinResponseTime int64     `json:"min_response_time"` // in microseconds
-	MaxResponseTime int64     `json:"max_response_time"`
-	AvgResponseTime int64     `json:"avg_response_time"`
-	P95ResponseTime int64     `json:"p95_response_time"`
-	P99ResponseTime int64     `json:"p99_response_time"`
-	ErrorRate       float64   `json:"error_rate"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID                 int64     `gorm:"primaryKey" json:"id"`
+	SimulationID       int64     `gorm:"index;not null" json:"simulation_id"`
+	TotalRequests      int64     `gorm:"not null" json:"total_requests"`
+	SuccessfulReqs     int64     `gorm:"not null" json:"successful_requests"`
+	FailedRequests     int64     `gorm:"not null" json:"failed_requests"`
+	AverageRPS         float64   `gorm:"not null" json:"average_rps"`
+	MinResponseTime    int64     `json:"min_response_time"` // in microseconds
+	MaxResponseTime    int64     `json:"max_response_time"`
+	AvgResponseTime    int64     `json:"avg_response_time"`
+	MedianResponseTime int64     `json:"median_response_time"`
+	P95ResponseTime    int64     `json:"p95_response_time"`
+	P99ResponseTime    int64     `json:"p99_response_time"`
+	ErrorRate          float64   `json:"error_rate"`
+	CreatedAt          time.Time `json:"created_at"`
 
 	// Relation
 	Simulation Simulation `gorm:"foreignKey:SimulationID" json:"simulation,omitempty"`
@@ -78,11 +102,47 @@ type ServiceProfile struct {
 	Endpoints   string    `gorm:"type:text" json:"endpoints"` // JSON array of endpoints
 	Headers     string    `gorm:"type:text" json:"headers"`   // JSON object of default headers
 	Description string    `gorm:"type:text" json:"description"`
+	// CapabilityPaths is a JSON array of paths CapabilityProber probes for
+	// this profile's BaseURL, e.g. ["/health","/metrics"]; empty means the
+	// prober's own defaults.
+	CapabilityPaths string `gorm:"type:text" json:"capability_paths"`
 	IsActive    bool      `gorm:"default:true" json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// GoldenResponse stores a recorded "golden" response a simulation's
+// differential (Body.Diff) validation compares live responses against,
+// scoped to the simulation it was recorded under and a caller-chosen key
+// (e.g. an endpoint name) identifying which request it belongs to.
+type GoldenResponse struct {
+	ID           int64     `gorm:"primaryKey" json:"id"`
+	SimulationID int64     `gorm:"index:idx_golden_sim_key,unique;not null" json:"simulation_id"`
+	Key          string    `gorm:"size:255;index:idx_golden_sim_key,unique;not null" json:"key"`
+	StatusCode   int       `gorm:"not null" json:"status_code"`
+	Headers      string    `gorm:"type:text" json:"headers"` // JSON object of recorded headers
+	Body         string    `gorm:"type:text" json:"body"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SimulationValidationConfig persists a Simulation's simulation-wide
+// statistical thresholds (simulator.SimulationValidation), the counterpart
+// to the per-request checks already covered by ResponseValidation. One row
+// per simulation; durations are stored in nanoseconds to match time.Duration.
+type SimulationValidationConfig struct {
+	ID                          int64     `gorm:"primaryKey" json:"id"`
+	SimulationID                int64     `gorm:"uniqueIndex;not null" json:"simulation_id"`
+	MaxP95ResponseTime          int64     `json:"max_p95_response_time"`
+	MaxP99ResponseTime          int64     `json:"max_p99_response_time"`
+	MaxErrorRate                float64   `json:"max_error_rate"`
+	MaxResponseTimeShiftPercent float64   `json:"max_response_time_shift_percent"`
+	ShiftSignificanceAlpha      float64   `json:"shift_significance_alpha"`
+	MaxBodySizeSigma            float64   `json:"max_body_size_sigma"`
+	CreatedAt                   time.Time `json:"created_at"`
+	UpdatedAt                   time.Time `json:"updated_at"`
+}
+
 // Configuration stores reusable simulation configurations
 type Configuration struct {
 	ID          int64     `gorm:"primaryKey" json:"id"`
@@ -94,3 +154,30 @@ type Configuration struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// TimeSeriesEntry is one batched-write row of a simulation's
+// simulator.TimeSeriesPoint history, so GetSimulation/GetTimeSeriesData can
+// serve a run's time series after the process restarts.
+type TimeSeriesEntry struct {
+	ID           int64     `gorm:"primaryKey" json:"id"`
+	SimulationID int64     `gorm:"index:idx_ts_sim_time;not null" json:"simulation_id"`
+	Timestamp    time.Time `gorm:"index:idx_ts_sim_time" json:"timestamp"`
+	RPS          float64   `json:"rps"`
+	TargetRPS    float64   `json:"target_rps"`
+	ResponseTime float64   `json:"response_time"`
+	ErrorRate    float64   `json:"error_rate"`
+	ActiveUsers  int       `json:"active_users"`
+}
+
+// ValidationRecord persists one simulator.ValidationRecord so validation
+// history survives past the in-memory 1000-record-per-simulation cap and a
+// process restart.
+type ValidationRecord struct {
+	ID             int64     `gorm:"primaryKey" json:"id"`
+	SimulationID   int64     `gorm:"index:idx_validation_sim_time;not null" json:"simulation_id"`
+	Timestamp      time.Time `gorm:"index:idx_validation_sim_time" json:"timestamp"`
+	ResponseTimeNs int64     `json:"response_time_ns"`
+	StatusCode     int       `json:"status_code"`
+	Passed         bool      `gorm:"index" json:"passed"`
+	ResultJSON     string    `gorm:"type:text" json:"-"` // marshaled simulator.ValidationResult
+}
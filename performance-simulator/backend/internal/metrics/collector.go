@@ -1,18 +1,23 @@
 package metrics
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"math"
 	"net/http"
-	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 // Collector manages performance metrics collection and analysis
 type Collector struct {
-	data map[int64]*SimulationMetrics
+	mu    sync.RWMutex
+	data  map[int64]*SimulationMetrics
+	store Store
 }
 
 // SimulationMetrics holds comprehensive performance data for a simulation
@@ -27,6 +32,11 @@ type SimulationMetrics struct {
 	ThroughputData  []ThroughputPoint        `json:"throughput_data"`
 	ErrorRates      []ErrorRatePoint         `json:"error_rates"`
 	ResourceUsage   *ResourceUsage           `json:"resource_usage"`
+	CaptureHits     int64                    `json:"capture_hits"`
+	CaptureMisses   int64                    `json:"capture_misses"`
+	// Protocol is the simulator.Protocol ("http", "grpc", "graphql") the
+	// simulation's requests are sent over.
+	Protocol        string                   `json:"protocol,omitempty"`
 }
 
 // ResponseTimes contains response time statistics
@@ -76,164 +86,329 @@ type ServicePerformance struct {
 	P95Latency    time.Duration  `json:"p95_latency"`
 	ErrorRate     float64        `json:"error_rate"`
 	ResponseTimes *ResponseTimes `json:"response_times"`
+
+	// RawResponseTimes, when populated, lets CompareServicesWithStrategy run
+	// the Mann-Whitney U significance test and bootstrap confidence interval
+	// between two services. It's omitted from API responses since it can be
+	// tens of thousands of samples.
+	RawResponseTimes []time.Duration `json:"-"`
 }
 
 // ComparisonSummary provides high-level comparison insights
 type ComparisonSummary struct {
-	BestPerformer    string  `json:"best_performer"`
-	PerformanceGap   float64 `json:"performance_gap"`
-	Recommendation   string  `json:"recommendation"`
+	BestPerformer      string              `json:"best_performer"`
+	PerformanceGap     float64             `json:"performance_gap"`
+	Recommendation     string              `json:"recommendation"`
+	PValue             float64             `json:"p_value,omitempty"`
+	ConfidenceInterval *ConfidenceInterval `json:"confidence_interval,omitempty"`
+	EffectSize         float64             `json:"effect_size,omitempty"`
 }
 
-// NewCollector creates a new metrics collector
+// NewCollector creates a new metrics collector with no persistent store;
+// RecordMetrics only keeps the latest snapshot in memory, and
+// GetHistoricalMetrics has nothing to serve.
 func NewCollector() *Collector {
 	return &Collector{
 		data: make(map[int64]*SimulationMetrics),
 	}
 }
 
-// RecordMetrics stores performance data for a simulation
+// NewCollectorWithStore creates a collector that also persists every
+// recorded simulation's time series to store, so GetHistoricalMetrics can
+// serve ranges that outlive the in-memory snapshot (including across
+// process restarts).
+func NewCollectorWithStore(store Store) *Collector {
+	return &Collector{
+		data:  make(map[int64]*SimulationMetrics),
+		store: store,
+	}
+}
+
+// RecordMetrics stores performance data for a simulation, and, when a Store
+// is configured, persists its ThroughputData/ErrorRates/ResourceUsage
+// points so they survive a restart.
 func (c *Collector) RecordMetrics(simulationID int64, metrics *SimulationMetrics) {
+	c.mu.Lock()
 	c.data[simulationID] = metrics
+	c.mu.Unlock()
+
+	if c.store == nil {
+		return
+	}
+	c.persistPoints(simulationID, metrics)
+}
+
+// RecordCaptureResult records a single response-capture rule evaluation
+// (see simulator.CaptureRule) as a hit or a miss, so a capture rule that
+// silently stops matching shows up in a simulation's metrics instead of
+// just quietly leaving a template variable unresolved.
+func (c *Collector) RecordCaptureResult(simulationID int64, name string, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sm, exists := c.data[simulationID]
+	if !exists {
+		sm = &SimulationMetrics{SimulationID: simulationID}
+		c.data[simulationID] = sm
+	}
+	if hit {
+		sm.CaptureHits++
+	} else {
+		sm.CaptureMisses++
+		logrus.Debugf("capture rule %q missed for simulation %d", name, simulationID)
+	}
+}
+
+// RecordRequestOutcome tallies a single request's outcome for simulationID
+// and tags its metrics with protocol, so a simulation running gRPC or
+// GraphQL requests (see simulator.Protocol) is distinguishable from a plain
+// HTTP one in the same snapshot Prometheus/comparisons read.
+func (c *Collector) RecordRequestOutcome(simulationID int64, protocol string, success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sm, exists := c.data[simulationID]
+	if !exists {
+		sm = &SimulationMetrics{SimulationID: simulationID}
+		c.data[simulationID] = sm
+	}
+	sm.Protocol = protocol
+	sm.TotalRequests++
+	if success {
+		sm.SuccessfulReqs++
+	} else {
+		sm.FailedRequests++
+	}
+}
+
+// persistPoints writes every raw sample a SimulationMetrics carries to the
+// configured store. Write failures are logged rather than returned, since
+// callers record metrics on a hot path and shouldn't fail a simulation
+// because the store had a transient error.
+func (c *Collector) persistPoints(simulationID int64, metrics *SimulationMetrics) {
+	ctx := context.Background()
+
+	for _, tp := range metrics.ThroughputData {
+		point := MetricPoint{SimulationID: simulationID, Kind: KindThroughput, Timestamp: tp.Timestamp, Value: tp.RPS}
+		if err := c.store.WritePoint(ctx, point); err != nil {
+			logrus.Warnf("failed to persist throughput point for simulation %d: %v", simulationID, err)
+		}
+	}
+	for _, ep := range metrics.ErrorRates {
+		point := MetricPoint{SimulationID: simulationID, Kind: KindErrorRate, Timestamp: ep.Timestamp, Value: ep.ErrorRate}
+		if err := c.store.WritePoint(ctx, point); err != nil {
+			logrus.Warnf("failed to persist error rate point for simulation %d: %v", simulationID, err)
+		}
+	}
+	if metrics.ResourceUsage != nil {
+		now := time.Now()
+		cpuPoint := MetricPoint{SimulationID: simulationID, Kind: KindCPUPercent, Timestamp: now, Value: metrics.ResourceUsage.CPUPercent}
+		if err := c.store.WritePoint(ctx, cpuPoint); err != nil {
+			logrus.Warnf("failed to persist CPU usage point for simulation %d: %v", simulationID, err)
+		}
+		memPoint := MetricPoint{SimulationID: simulationID, Kind: KindMemoryPercent, Timestamp: now, Value: metrics.ResourceUsage.MemoryPercent}
+		if err := c.store.WritePoint(ctx, memPoint); err != nil {
+			logrus.Warnf("failed to persist memory usage point for simulation %d: %v", simulationID, err)
+		}
+	}
+}
+
+// Snapshot returns a point-in-time copy of every recorded simulation's
+// metrics, safe to range over without holding the collector's lock. This is
+// what PrometheusExporter.Collect reads on every scrape.
+func (c *Collector) Snapshot() map[int64]*SimulationMetrics {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := make(map[int64]*SimulationMetrics, len(c.data))
+	for id, m := range c.data {
+		snapshot[id] = m
+	}
+	return snapshot
 }
 
 // GetLiveMetrics returns real-time metrics for active simulations
 func (c *Collector) GetLiveMetrics(ctx *gin.Context) {
+	c.mu.RLock()
+	active := len(c.data)
+	c.mu.RUnlock()
+
 	// This would return real-time metrics from active simulations
 	// For now, returning mock data structure
 	ctx.JSON(http.StatusOK, gin.H{
-		"active_simulations": len(c.data),
+		"active_simulations": active,
 		"message":           "Live metrics endpoint",
 	})
 }
 
-// GetHistoricalMetrics returns historical performance data
+// historicalKinds lists every series GetHistoricalMetrics streams back, in
+// a fixed order so the response shape is predictable for the frontend.
+var historicalKinds = []MetricKind{KindThroughput, KindErrorRate, KindCPUPercent, KindMemoryPercent}
+
+// GetHistoricalMetrics returns historical performance data for a
+// simulation over [from, to], downsampled to the coarsest rollup that
+// still satisfies step. Without a configured Store it falls back to the
+// old stub response, since there's nothing durable to query.
+//
+// Series are streamed as a JSON array, one HistoricalSeries object flushed
+// at a time, so a long range doesn't require buffering the whole response
+// in memory before the first byte goes out.
 func (c *Collector) GetHistoricalMetrics(ctx *gin.Context) {
-	simulationID := ctx.Param("simulationId")
-	
-	// Implementation would retrieve from database
-	ctx.JSON(http.StatusOK, gin.H{
-		"simulation_id": simulationID,
-		"message":      "Historical metrics endpoint",
-	})
-}
+	simulationIDParam := ctx.Param("simulationId")
+
+	if c.store == nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"simulation_id": simulationIDParam,
+			"message":       "Historical metrics endpoint",
+		})
+		return
+	}
 
-// CalculatePercentiles computes response time percentiles from raw data
-func CalculatePercentiles(responseTimes []time.Duration) *ResponseTimes {
-	if len(responseTimes) == 0 {
-		return &ResponseTimes{}
+	simulationID, err := strconv.ParseInt(simulationIDParam, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation id"})
+		return
 	}
 
-	// Sort response times
-	sorted := make([]time.Duration, len(responseTimes))
-	copy(sorted, responseTimes)
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i] < sorted[j]
-	})
+	from, to, step, err := parseHistoricalRange(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Calculate basic statistics
-	min := sorted[0]
-	max := sorted[len(sorted)-1]
-	
-	// Calculate mean
-	var sum time.Duration
-	for _, rt := range sorted {
-		sum += rt
-	}
-	mean := time.Duration(int64(sum) / int64(len(sorted)))
-
-	// Calculate percentiles
-	median := percentile(sorted, 50)
-	p95 := percentile(sorted, 95)
-	p99 := percentile(sorted, 99)
-
-	// Calculate standard deviation
-	stdDev := calculateStdDev(sorted, mean)
-
-	return &ResponseTimes{
-		Min:    min,
-		Max:    max,
-		Mean:   mean,
-		Median: median,
-		P95:    p95,
-		P99:    p99,
-		StdDev: stdDev,
+	ctx.Writer.Header().Set("Content-Type", "application/json")
+	ctx.Writer.WriteHeader(http.StatusOK)
+	flusher, canFlush := ctx.Writer.(http.Flusher)
+
+	encoder := json.NewEncoder(ctx.Writer)
+	ctx.Writer.Write([]byte("["))
+
+	for i, kind := range historicalKinds {
+		series, err := c.store.Query(ctx.Request.Context(), simulationID, kind, from, to, step)
+		if err != nil {
+			logrus.Warnf("failed to query %s history for simulation %d: %v", kind, simulationID, err)
+			continue
+		}
+
+		if i > 0 {
+			ctx.Writer.Write([]byte(","))
+		}
+		if err := encoder.Encode(series); err != nil {
+			logrus.Warnf("failed to encode %s history for simulation %d: %v", kind, simulationID, err)
+		}
+		if canFlush {
+			flusher.Flush()
+		}
 	}
+
+	ctx.Writer.Write([]byte("]"))
 }
 
-// percentile calculates the nth percentile from sorted data
-func percentile(sorted []time.Duration, n int) time.Duration {
-	if len(sorted) == 0 {
-		return 0
+// parseHistoricalRange reads from/to/step query params, defaulting to the
+// last hour at 10-second resolution.
+func parseHistoricalRange(ctx *gin.Context) (from, to time.Time, step time.Duration, err error) {
+	to = time.Now()
+	from = to.Add(-time.Hour)
+	step = 10 * time.Second
+
+	if v := ctx.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, step, fmt.Errorf("invalid from: %w", err)
+		}
 	}
-	
-	index := float64(n) / 100.0 * float64(len(sorted)-1)
-	lower := int(math.Floor(index))
-	upper := int(math.Ceil(index))
-	
-	if lower == upper {
-		return sorted[lower]
+	if v := ctx.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, step, fmt.Errorf("invalid to: %w", err)
+		}
 	}
-	
-	// Linear interpolation
-	weight := index - float64(lower)
-	return time.Duration(float64(sorted[lower]) + weight*float64(sorted[upper]-sorted[lower]))
+	if v := ctx.Query("step"); v != "" {
+		if step, err = time.ParseDuration(v); err != nil {
+			return from, to, step, fmt.Errorf("invalid step: %w", err)
+		}
+	}
+	return from, to, step, nil
 }
 
-// calculateStdDev computes standard deviation of response times
-func calculateStdDev(data []time.Duration, mean time.Duration) time.Duration {
-	if len(data) <= 1 {
-		return 0
+// CalculatePercentiles computes response time percentiles from raw data.
+//
+// This used to sort the entire slice on every call, which meant a 45k RPS
+// simulation kept every single response time in memory just to report P95.
+// It now folds the samples into a t-digest and reads percentiles off that,
+// so callers that can feed samples incrementally (see Digest) never need to
+// hold the raw slice at all; this function remains for callers that only
+// have a batch of samples on hand.
+func CalculatePercentiles(responseTimes []time.Duration) *ResponseTimes {
+	if len(responseTimes) == 0 {
+		return &ResponseTimes{}
 	}
 
-	var sum float64
-	for _, value := range data {
-		diff := float64(value - mean)
-		sum += diff * diff
+	digest := NewDigest()
+	for _, rt := range responseTimes {
+		digest.Add(rt)
 	}
 
-	variance := sum / float64(len(data)-1)
-	return time.Duration(math.Sqrt(variance))
+	return digest.ResponseTimes()
 }
 
-// CompareServices analyzes performance differences between services
+// CompareServices analyzes performance differences between services using
+// the default ThroughputWeighted strategy. It's kept as a thin wrapper over
+// CompareServicesWithStrategy for callers that don't need to choose a
+// comparator or tune significance testing.
 func (c *Collector) CompareServices(services []ServicePerformance) *PerformanceComparison {
+	return c.CompareServicesWithStrategy(services, ThroughputWeightedComparator{})
+}
+
+// CompareServicesWithStrategy analyzes performance differences using a
+// pluggable Comparator, and, when exactly two services are compared and
+// both carry RawResponseTimes, layers on a Mann-Whitney U significance test
+// and a bootstrap confidence interval for the reported PerformanceGap.
+func (c *Collector) CompareServicesWithStrategy(services []ServicePerformance, comparator Comparator, opts ...ComparisonOption) *PerformanceComparison {
 	if len(services) == 0 {
 		return &PerformanceComparison{}
 	}
 
-	// Find best performer (highest RPS with acceptable latency)
-	bestPerformer := services[0]
-	for _, service := range services[1:] {
-		if service.MaxRPS > bestPerformer.MaxRPS && 
-		   service.P95Latency < 2*bestPerformer.P95Latency {
-			bestPerformer = service
-		}
+	cfg := defaultComparisonConfig()
+	for _, opt := range opts {
+		opt(&cfg)
 	}
 
-	// Calculate performance gap
-	var totalRPS float64
-	for _, service := range services {
-		totalRPS += service.MaxRPS
+	bestPerformer, performanceGap := comparator.Compare(services)
+
+	summary := &ComparisonSummary{
+		BestPerformer:  bestPerformer.Name,
+		PerformanceGap: performanceGap,
 	}
-	avgRPS := totalRPS / float64(len(services))
-	performanceGap := (bestPerformer.MaxRPS - avgRPS) / avgRPS * 100
 
-	// Generate recommendation
-	recommendation := generateRecommendation(bestPerformer, performanceGap)
+	significant := false
+	haveStats := false
+	if len(services) == 2 {
+		a, b := services[0].RawResponseTimes, services[1].RawResponseTimes
+		if len(a) > 0 && len(b) > 0 {
+			haveStats = true
+			_, pValue := mannWhitneyU(a, b)
+			summary.PValue = pValue
+			summary.EffectSize = rankBiserialEffectSize(a, b)
+			ci := bootstrapP95Diff(a, b, cfg.bootstrapResamples)
+			summary.ConfidenceInterval = &ci
+			significant = pValue < cfg.alpha
+		}
+	}
+
+	summary.Recommendation = generateRecommendation(bestPerformer, performanceGap, haveStats, significant, cfg.alpha)
 
 	return &PerformanceComparison{
 		Services: services,
-		Summary: &ComparisonSummary{
-			BestPerformer:  bestPerformer.Name,
-			PerformanceGap: performanceGap,
-			Recommendation: recommendation,
-		},
+		Summary:  summary,
 	}
 }
 
-// generateRecommendation creates performance optimization suggestions
-func generateRecommendation(bestPerformer ServicePerformance, gap float64) string {
+// generateRecommendation creates performance optimization suggestions. When
+// a significance test ran and found the difference not significant, it
+// refuses to suggest migrating regardless of the raw gap percentage.
+func generateRecommendation(bestPerformer ServicePerformance, gap float64, haveStats, significant bool, alpha float64) string {
+	if haveStats && !significant {
+		return fmt.Sprintf("Observed difference is not statistically significant at alpha=%.2f; no migration recommended.", alpha)
+	}
 	if gap > 50 {
 		return fmt.Sprintf("Consider migrating services to %s (%s) for significant performance gains. Performance improvement potential: %.1f%%",
 			bestPerformer.Name, bestPerformer.Technology, gap)
@@ -254,8 +429,9 @@ func (c *Collector) GenerateReport(simulationIDs []int64) *PerformanceReport {
 	}
 
 	// Aggregate data from multiple simulations
+	snapshot := c.Snapshot()
 	for _, id := range simulationIDs {
-		if metrics, exists := c.data[id]; exists {
+		if metrics, exists := snapshot[id]; exists {
 			summary := c.createSimulationSummary(metrics)
 			report.Simulations = append(report.Simulations, summary)
 		}
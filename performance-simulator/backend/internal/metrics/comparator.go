@@ -0,0 +1,390 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Comparator picks the best performer out of a set of services and reports
+// how large its advantage is, as a percentage gap. Implementations encode a
+// specific notion of "best" (raw throughput, latency, SLO compliance, cost).
+type Comparator interface {
+	Compare(services []ServicePerformance) (best ServicePerformance, gap float64)
+	Name() string
+}
+
+// ConfidenceInterval is a 95% bootstrap confidence interval.
+type ConfidenceInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// ComparisonOption configures significance testing in
+// CompareServicesWithStrategy.
+type ComparisonOption func(*comparisonConfig)
+
+type comparisonConfig struct {
+	alpha              float64
+	bootstrapResamples int
+}
+
+func defaultComparisonConfig() comparisonConfig {
+	return comparisonConfig{
+		alpha:              0.05,
+		bootstrapResamples: 1000,
+	}
+}
+
+// WithSignificanceAlpha sets the p-value threshold below which a latency
+// difference is treated as statistically significant. Defaults to 0.05.
+func WithSignificanceAlpha(alpha float64) ComparisonOption {
+	return func(c *comparisonConfig) { c.alpha = alpha }
+}
+
+// WithBootstrapResamples sets how many resamples the P95 confidence
+// interval is built from. Defaults to 1000.
+func WithBootstrapResamples(n int) ComparisonOption {
+	return func(c *comparisonConfig) { c.bootstrapResamples = n }
+}
+
+// ThroughputWeightedComparator picks the service with the highest max RPS,
+// as long as its P95 latency isn't more than double the current best's.
+// This is the comparator CompareServices used before strategies existed.
+type ThroughputWeightedComparator struct{}
+
+func (ThroughputWeightedComparator) Name() string { return "throughput_weighted" }
+
+func (ThroughputWeightedComparator) Compare(services []ServicePerformance) (ServicePerformance, float64) {
+	best := services[0]
+	for _, service := range services[1:] {
+		if service.MaxRPS > best.MaxRPS && service.P95Latency < 2*best.P95Latency {
+			best = service
+		}
+	}
+	return best, percentGapAboveMean(services, best.MaxRPS, func(s ServicePerformance) float64 { return s.MaxRPS })
+}
+
+// LatencyWeightedComparator picks the service with the lowest P95 latency.
+type LatencyWeightedComparator struct{}
+
+func (LatencyWeightedComparator) Name() string { return "latency_weighted" }
+
+func (LatencyWeightedComparator) Compare(services []ServicePerformance) (ServicePerformance, float64) {
+	best := services[0]
+	for _, service := range services[1:] {
+		if service.P95Latency < best.P95Latency {
+			best = service
+		}
+	}
+	// Lower latency is better, so the gap is how far below the mean the
+	// winner sits, as a percentage of the mean.
+	meanLatency := meanOf(services, func(s ServicePerformance) float64 { return float64(s.P95Latency) })
+	if meanLatency == 0 {
+		return best, 0
+	}
+	gap := (meanLatency - float64(best.P95Latency)) / meanLatency * 100
+	return best, gap
+}
+
+// SLOBasedComparator picks the service with the highest throughput among
+// those meeting both latency and error-rate SLOs; if none qualify, it falls
+// back to the one closest to meeting them.
+type SLOBasedComparator struct {
+	MaxP95Latency time.Duration
+	MaxErrorRate  float64
+}
+
+func (SLOBasedComparator) Name() string { return "slo_based" }
+
+func (c SLOBasedComparator) Compare(services []ServicePerformance) (ServicePerformance, float64) {
+	meetsSLO := func(s ServicePerformance) bool {
+		return s.P95Latency <= c.MaxP95Latency && s.ErrorRate <= c.MaxErrorRate
+	}
+
+	var qualifying []ServicePerformance
+	for _, s := range services {
+		if meetsSLO(s) {
+			qualifying = append(qualifying, s)
+		}
+	}
+
+	candidates := qualifying
+	if len(candidates) == 0 {
+		candidates = services
+	}
+
+	best := candidates[0]
+	for _, s := range candidates[1:] {
+		if s.MaxRPS > best.MaxRPS {
+			best = s
+		}
+	}
+
+	if len(qualifying) == 0 {
+		// Nobody meets the SLO; report how far the closest service is,
+		// as a negative gap, rather than claiming a performance win.
+		latencyOverage := float64(best.P95Latency-c.MaxP95Latency) / float64(c.MaxP95Latency) * 100
+		return best, -latencyOverage
+	}
+
+	return best, percentGapAboveMean(services, best.MaxRPS, func(s ServicePerformance) float64 { return s.MaxRPS })
+}
+
+// CostAdjustedComparator picks the service with the best throughput per
+// unit cost, using a caller-supplied cost-per-request map keyed by service
+// name. Services missing from the map are treated as free.
+type CostAdjustedComparator struct {
+	CostPerRequest map[string]float64
+}
+
+func (CostAdjustedComparator) Name() string { return "cost_adjusted" }
+
+func (c CostAdjustedComparator) Compare(services []ServicePerformance) (ServicePerformance, float64) {
+	valueOf := func(s ServicePerformance) float64 {
+		cost := c.CostPerRequest[s.Name]
+		if cost <= 0 {
+			return s.MaxRPS
+		}
+		return s.MaxRPS / cost
+	}
+
+	best := services[0]
+	bestValue := valueOf(best)
+	for _, s := range services[1:] {
+		if v := valueOf(s); v > bestValue {
+			best = s
+			bestValue = v
+		}
+	}
+
+	meanValue := meanOf(services, valueOf)
+	if meanValue == 0 {
+		return best, 0
+	}
+	return best, (bestValue - meanValue) / meanValue * 100
+}
+
+func meanOf(services []ServicePerformance, value func(ServicePerformance) float64) float64 {
+	var sum float64
+	for _, s := range services {
+		sum += value(s)
+	}
+	return sum / float64(len(services))
+}
+
+func percentGapAboveMean(services []ServicePerformance, bestValue float64, value func(ServicePerformance) float64) float64 {
+	mean := meanOf(services, value)
+	if mean == 0 {
+		return 0
+	}
+	return (bestValue - mean) / mean * 100
+}
+
+// CompareResponseTimeSamples runs a two-sample Mann-Whitney U test between a
+// and b, reporting whether their difference clears alpha significance
+// (p <= alpha) rather than plausibly being noise. Exposed for callers
+// outside this package that need to compare two latency samples, e.g.
+// ValidationEngine.ValidateSimulation comparing a run's latencies against a
+// prior baseline's.
+func CompareResponseTimeSamples(a, b []time.Duration, alpha float64) (significant bool, pValue float64) {
+	_, p := mannWhitneyU(a, b)
+	return p <= alpha, p
+}
+
+// exactMannWhitneySampleLimit is the largest combined sample size for which
+// mannWhitneyU enumerates the exact permutation distribution instead of
+// falling back to the normal approximation. 20 keeps the enumeration
+// (C(n, n1) combinations) comfortably fast while covering the small
+// hand-authored samples (e.g. n=3,3) where the normal approximation is
+// known to be unreliable.
+const exactMannWhitneySampleLimit = 20
+
+// mannWhitneyU runs a two-sample Mann-Whitney U test on response time
+// samples a and b, returning the U statistic and a two-tailed p-value. For
+// small samples (combined size <= exactMannWhitneySampleLimit) the p-value
+// is computed exactly, by enumerating every way to split the combined ranks
+// between the two groups; otherwise it falls back to the normal
+// approximation (valid once either sample has more than ~20 points, which
+// simulations comfortably exceed, but which can understate the p-value
+// badly for tiny samples — a fully separated n=3,3 sample reports p=0.0495
+// under the normal approximation, just under a default alpha of 0.05, even
+// though the true p-value for that sample size can never fall below 0.1).
+func mannWhitneyU(a, b []time.Duration) (u float64, pValue float64) {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type sample struct {
+		value float64
+		group int
+	}
+	combined := make([]sample, 0, n1+n2)
+	for _, v := range a {
+		combined = append(combined, sample{value: float64(v), group: 0})
+	}
+	for _, v := range b {
+		combined = append(combined, sample{value: float64(v), group: 1})
+	}
+	sort.Slice(combined, func(i, j int) bool { return combined[i].value < combined[j].value })
+
+	ranks := make([]float64, len(combined))
+	for i := 0; i < len(combined); {
+		j := i
+		for j < len(combined) && combined[j].value == combined[i].value {
+			j++
+		}
+		// Tied values share the average of the ranks they span.
+		avgRank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			ranks[k] = avgRank
+		}
+		i = j
+	}
+
+	var rankSumA float64
+	for i, s := range combined {
+		if s.group == 0 {
+			rankSumA += ranks[i]
+		}
+	}
+
+	u1 := rankSumA - float64(n1*(n1+1))/2
+	u2 := float64(n1*n2) - u1
+	u = math.Min(u1, u2)
+
+	if n1+n2 <= exactMannWhitneySampleLimit {
+		return u, exactMannWhitneyPValue(ranks, n1, rankSumA)
+	}
+
+	meanU := float64(n1*n2) / 2
+	stdU := math.Sqrt(float64(n1*n2*(n1+n2+1)) / 12)
+	if stdU == 0 {
+		return u, 1
+	}
+
+	z := (u - meanU) / stdU
+	p := 2 * (1 - normalCDF(math.Abs(z)))
+	if p < 0 {
+		p = 0
+	}
+	if p > 1 {
+		p = 1
+	}
+	return u, p
+}
+
+// exactMannWhitneyPValue computes the exact two-tailed Mann-Whitney p-value
+// by enumerating every way to assign n1 of the combined, tie-averaged ranks
+// to group A, and comparing each permutation's rank sum against the one
+// actually observed (observedRankSumA).
+func exactMannWhitneyPValue(ranks []float64, n1 int, observedRankSumA float64) float64 {
+	total, le, ge := 0, 0, 0
+	forEachCombination(len(ranks), n1, func(subset []int) {
+		var sum float64
+		for _, idx := range subset {
+			sum += ranks[idx]
+		}
+		total++
+		if sum <= observedRankSumA {
+			le++
+		}
+		if sum >= observedRankSumA {
+			ge++
+		}
+	})
+	if total == 0 {
+		return 1
+	}
+
+	p := 2 * math.Min(float64(le)/float64(total), float64(ge)/float64(total))
+	if p > 1 {
+		p = 1
+	}
+	return p
+}
+
+// forEachCombination calls fn, in lexicographic order, with every k-size
+// subset of indices {0, ..., n-1}, reusing the same backing slice across
+// calls.
+func forEachCombination(n, k int, fn func(subset []int)) {
+	if k <= 0 || k > n {
+		return
+	}
+	indices := make([]int, k)
+	for i := range indices {
+		indices[i] = i
+	}
+	for {
+		fn(indices)
+		i := k - 1
+		for i >= 0 && indices[i] == i+n-k {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		indices[i]++
+		for j := i + 1; j < k; j++ {
+			indices[j] = indices[j-1] + 1
+		}
+	}
+}
+
+// normalCDF evaluates the standard normal cumulative distribution function.
+func normalCDF(z float64) float64 {
+	return 0.5 * (1 + math.Erf(z/math.Sqrt2))
+}
+
+// rankBiserialEffectSize reports the rank-biserial correlation for the
+// Mann-Whitney U test, a scale-free measure (-1..1) of how much two
+// distributions overlap, independent of the raw millisecond gap.
+func rankBiserialEffectSize(a, b []time.Duration) float64 {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 0
+	}
+	u, _ := mannWhitneyU(a, b)
+	return 1 - (2*u)/float64(n1*n2)
+}
+
+// bootstrapP95Diff resamples a and b with replacement `resamples` times,
+// computing the P95 difference (b - a) on each resample, and returns the
+// 2.5th/97.5th percentile of that distribution as a 95% confidence interval
+// for the true P95 gap. Callers pass the candidate's samples as a and the
+// incumbent's as b, so a positive interval means the candidate is slower.
+func bootstrapP95Diff(a, b []time.Duration, resamples int) ConfidenceInterval {
+	if len(a) == 0 || len(b) == 0 || resamples <= 0 {
+		return ConfidenceInterval{}
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	diffs := make([]float64, resamples)
+	for i := 0; i < resamples; i++ {
+		diffs[i] = percentileOfResample(rng, b, 0.95) - percentileOfResample(rng, a, 0.95)
+	}
+	sort.Float64s(diffs)
+
+	lowerIdx := int(0.025 * float64(resamples))
+	upperIdx := int(0.975 * float64(resamples))
+	if upperIdx >= resamples {
+		upperIdx = resamples - 1
+	}
+
+	return ConfidenceInterval{Lower: diffs[lowerIdx], Upper: diffs[upperIdx]}
+}
+
+// percentileOfResample draws len(values) samples from values with
+// replacement and returns the quantile q (0..1) of that resample.
+func percentileOfResample(rng *rand.Rand, values []time.Duration, q float64) float64 {
+	resample := make([]time.Duration, len(values))
+	for i := range resample {
+		resample[i] = values[rng.Intn(len(values))]
+	}
+	sort.Slice(resample, func(i, j int) bool { return resample[i] < resample[j] })
+
+	idx := int(q * float64(len(resample)-1))
+	return float64(resample[idx])
+}
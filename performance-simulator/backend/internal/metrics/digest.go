@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// defaultCompression bounds the number of centroids a Digest keeps, which in
+// turn bounds its memory to O(compression) regardless of how many samples
+// flow through it. 100 keeps P95/P99 accurate to within a fraction of a
+// percent while costing a few KB per simulation.
+const defaultCompression = 100.0
+
+// centroid is a single cluster in the digest: a mean value and the number of
+// samples that have been merged into it.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// Digest is a streaming quantile sketch (Ted Dunning's t-digest). It lets
+// Collector.RecordMetrics feed response times in online as they arrive,
+// instead of buffering every sample in a slice, which is what made 45k RPS
+// runs blow up memory: CalculatePercentiles used to sort a slice with one
+// entry per request.
+type Digest struct {
+	compression float64
+	centroids   []centroid
+	totalWeight float64
+	unmerged    int
+}
+
+// NewDigest creates a digest with the default compression factor.
+func NewDigest() *Digest {
+	return NewDigestWithCompression(defaultCompression)
+}
+
+// NewDigestWithCompression creates a digest bounding centroid count/accuracy
+// tradeoff via compression (higher = more accurate, more memory).
+func NewDigestWithCompression(compression float64) *Digest {
+	return &Digest{compression: compression}
+}
+
+// Add records a single response time sample into the digest.
+func (d *Digest) Add(value time.Duration) {
+	d.addWeighted(float64(value), 1)
+}
+
+// addWeighted merges a value with an explicit weight, finding the centroid
+// closest to it whose post-merge weight still respects the t-digest size
+// bound k(q) = compression * q * (1-q), or inserting a new centroid.
+func (d *Digest) addWeighted(value float64, weight float64) {
+	d.totalWeight += weight
+
+	if len(d.centroids) == 0 {
+		d.centroids = append(d.centroids, centroid{mean: value, weight: weight})
+		return
+	}
+
+	idx := sort.Search(len(d.centroids), func(i int) bool {
+		return d.centroids[i].mean >= value
+	})
+
+	best := -1
+	bestDist := 0.0
+	for _, i := range []int{idx - 1, idx} {
+		if i < 0 || i >= len(d.centroids) {
+			continue
+		}
+		dist := value - d.centroids[i].mean
+		if dist < 0 {
+			dist = -dist
+		}
+		if best == -1 || dist < bestDist {
+			best = i
+			bestDist = dist
+		}
+	}
+
+	if best != -1 && d.centroids[best].weight+weight <= d.sizeBound(best) {
+		c := &d.centroids[best]
+		c.mean += (value - c.mean) * weight / (c.weight + weight)
+		c.weight += weight
+	} else {
+		d.centroids = append(d.centroids, centroid{mean: value, weight: weight})
+		sort.Slice(d.centroids, func(i, j int) bool { return d.centroids[i].mean < d.centroids[j].mean })
+	}
+
+	d.unmerged++
+	// Re-cluster periodically rather than on every insert, so Add stays cheap
+	// under sustained high-RPS load.
+	if d.unmerged >= 500 {
+		d.compact()
+	}
+}
+
+// sizeBound returns the maximum weight centroid i is allowed to carry,
+// derived from its approximate quantile position.
+func (d *Digest) sizeBound(i int) float64 {
+	if d.totalWeight == 0 {
+		return d.compression
+	}
+	cumulative := 0.0
+	for j := 0; j < i; j++ {
+		cumulative += d.centroids[j].weight
+	}
+	q := (cumulative + d.centroids[i].weight/2) / d.totalWeight
+	return d.compression * q * (1 - q) * 4
+}
+
+// compact re-clusters centroids in sorted order, merging any that fit within
+// the size bound, which keeps centroid count close to `compression`.
+func (d *Digest) compact() {
+	if len(d.centroids) == 0 {
+		d.unmerged = 0
+		return
+	}
+
+	sort.Slice(d.centroids, func(i, j int) bool { return d.centroids[i].mean < d.centroids[j].mean })
+
+	merged := make([]centroid, 0, len(d.centroids))
+	current := d.centroids[0]
+	cumulative := 0.0
+
+	for _, c := range d.centroids[1:] {
+		q := (cumulative + current.weight/2) / d.totalWeight
+		bound := d.compression * q * (1 - q) * 4
+		if current.weight+c.weight <= bound {
+			current.mean = (current.mean*current.weight + c.mean*c.weight) / (current.weight + c.weight)
+			current.weight += c.weight
+		} else {
+			cumulative += current.weight
+			merged = append(merged, current)
+			current = c
+		}
+	}
+	merged = append(merged, current)
+
+	d.centroids = merged
+	d.unmerged = 0
+}
+
+// Merge folds another digest's centroids into this one, letting per-worker
+// digests be combined into a simulation-wide view.
+func (d *Digest) Merge(other *Digest) {
+	if other == nil {
+		return
+	}
+	for _, c := range other.centroids {
+		d.addWeighted(c.mean, c.weight)
+	}
+}
+
+// Count returns the total number of samples folded into the digest.
+func (d *Digest) Count() int64 {
+	return int64(d.totalWeight)
+}
+
+// Quantile returns the estimated value at quantile q (0..1) by walking
+// centroids in order and interpolating between the two straddling q*totalWeight.
+func (d *Digest) Quantile(q float64) time.Duration {
+	d.compact()
+
+	if len(d.centroids) == 0 {
+		return 0
+	}
+	if len(d.centroids) == 1 {
+		return time.Duration(d.centroids[0].mean)
+	}
+
+	target := q * d.totalWeight
+	cumulative := 0.0
+
+	for i, c := range d.centroids {
+		next := cumulative + c.weight
+		if next >= target || i == len(d.centroids)-1 {
+			if i == 0 {
+				return time.Duration(c.mean)
+			}
+			prev := d.centroids[i-1]
+			span := next - cumulative
+			if span == 0 {
+				return time.Duration(c.mean)
+			}
+			weight := (target - cumulative) / span
+			return time.Duration(prev.mean + weight*(c.mean-prev.mean))
+		}
+		cumulative = next
+	}
+
+	return time.Duration(d.centroids[len(d.centroids)-1].mean)
+}
+
+// Min returns the smallest observed value.
+func (d *Digest) Min() time.Duration {
+	if len(d.centroids) == 0 {
+		return 0
+	}
+	d.compact()
+	return time.Duration(d.centroids[0].mean)
+}
+
+// Max returns the largest observed value.
+func (d *Digest) Max() time.Duration {
+	if len(d.centroids) == 0 {
+		return 0
+	}
+	d.compact()
+	return time.Duration(d.centroids[len(d.centroids)-1].mean)
+}
+
+// Mean returns the weighted mean of all observed values.
+func (d *Digest) Mean() time.Duration {
+	if d.totalWeight == 0 {
+		return 0
+	}
+	var sum float64
+	for _, c := range d.centroids {
+		sum += c.mean * c.weight
+	}
+	return time.Duration(sum / d.totalWeight)
+}
+
+// ResponseTimes builds the familiar ResponseTimes summary from the digest's
+// current centroids, so callers don't need to know a digest was involved.
+func (d *Digest) ResponseTimes() *ResponseTimes {
+	if d.totalWeight == 0 {
+		return &ResponseTimes{}
+	}
+
+	return &ResponseTimes{
+		Min:    d.Min(),
+		Max:    d.Max(),
+		Mean:   d.Mean(),
+		Median: d.Quantile(0.5),
+		P95:    d.Quantile(0.95),
+		P99:    d.Quantile(0.99),
+		StdDev: d.stdDev(),
+	}
+}
+
+// stdDev computes standard deviation from the weighted centroids.
+func (d *Digest) stdDev() time.Duration {
+	if d.totalWeight <= 1 {
+		return 0
+	}
+	mean := float64(d.Mean())
+	var sumSq float64
+	for _, c := range d.centroids {
+		diff := c.mean - mean
+		sumSq += diff * diff * c.weight
+	}
+	variance := sumSq / (d.totalWeight - 1)
+	if variance < 0 {
+		variance = 0
+	}
+	return time.Duration(math.Sqrt(variance))
+}
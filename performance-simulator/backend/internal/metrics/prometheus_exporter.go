@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/sirupsen/logrus"
+)
+
+// responseTimeDesc describes simulation_response_time_seconds, emitted as a
+// Prometheus Summary whose quantiles are read straight off each
+// simulation's t-digest rather than recomputed from raw samples.
+var responseTimeDesc = prometheus.NewDesc(
+	"simulation_response_time_seconds",
+	"Response time quantiles for a simulation, sourced from its t-digest",
+	[]string{"sim_id", "technology", "service_name"},
+	nil,
+)
+
+// serviceLabels captures the technology/service_name pair a simulation ID is
+// tagged with, sourced from ServicePerformance when the caller knows it.
+type serviceLabels struct {
+	technology  string
+	serviceName string
+}
+
+// PrometheusExporter turns a Collector's SimulationMetrics into Prometheus
+// collectors, exposed over /metrics and optionally pushed to a Pushgateway
+// for simulations short-lived enough that nobody scrapes them in time.
+type PrometheusExporter struct {
+	collector *Collector
+	registry  *prometheus.Registry
+
+	requestsTotal   *prometheus.CounterVec
+	throughputGauge *prometheus.GaugeVec
+	errorRateGauge  *prometheus.GaugeVec
+	cpuGauge        *prometheus.GaugeVec
+	memoryGauge     *prometheus.GaugeVec
+
+	mu     sync.RWMutex
+	labels map[int64]serviceLabels
+}
+
+// NewPrometheusExporter creates an exporter backed by the given collector
+// and registers all simulation collectors against a private registry, so
+// mounting /metrics never pulls in Go runtime or process metrics the caller
+// didn't ask for.
+func NewPrometheusExporter(collector *Collector) *PrometheusExporter {
+	e := &PrometheusExporter{
+		collector: collector,
+		registry:  prometheus.NewRegistry(),
+		labels:    make(map[int64]serviceLabels),
+
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "simulation_requests_total",
+			Help: "Total number of requests issued by a simulation, by outcome",
+		}, []string{"sim_id", "status", "technology", "service_name"}),
+
+		throughputGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "simulation_throughput_rps",
+			Help: "Current throughput of a simulation in requests per second",
+		}, []string{"sim_id", "technology", "service_name"}),
+
+		errorRateGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "simulation_error_rate",
+			Help: "Current error rate of a simulation, as a fraction between 0 and 1",
+		}, []string{"sim_id", "technology", "service_name"}),
+
+		cpuGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "simulation_resource_cpu_percent",
+			Help: "CPU utilization observed for a simulation's target service",
+		}, []string{"sim_id", "technology", "service_name"}),
+
+		memoryGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "simulation_resource_memory_percent",
+			Help: "Memory utilization observed for a simulation's target service",
+		}, []string{"sim_id", "technology", "service_name"}),
+	}
+
+	e.registry.MustRegister(
+		e.requestsTotal,
+		e.throughputGauge,
+		e.errorRateGauge,
+		e.cpuGauge,
+		e.memoryGauge,
+		e,
+	)
+
+	return e
+}
+
+// SetServiceLabels tags a simulation ID with the technology/service_name
+// pair its requests are labeled with, sourced from ServicePerformance.
+// Call this once when a simulation starts; it defaults to empty labels
+// otherwise.
+func (e *PrometheusExporter) SetServiceLabels(simulationID int64, technology, serviceName string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.labels[simulationID] = serviceLabels{technology: technology, serviceName: serviceName}
+}
+
+func (e *PrometheusExporter) labelsFor(simulationID int64) serviceLabels {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.labels[simulationID]
+}
+
+// RecordRequest increments the request counter for a simulation's outcome,
+// e.g. status "success" or "error".
+func (e *PrometheusExporter) RecordRequest(simulationID int64, status string) {
+	labels := e.labelsFor(simulationID)
+	e.requestsTotal.WithLabelValues(fmt.Sprint(simulationID), status, labels.technology, labels.serviceName).Inc()
+}
+
+// UpdateThroughput sets the current RPS gauge for a simulation.
+func (e *PrometheusExporter) UpdateThroughput(simulationID int64, rps float64) {
+	labels := e.labelsFor(simulationID)
+	e.throughputGauge.WithLabelValues(fmt.Sprint(simulationID), labels.technology, labels.serviceName).Set(rps)
+}
+
+// UpdateErrorRate sets the current error-rate gauge for a simulation.
+func (e *PrometheusExporter) UpdateErrorRate(simulationID int64, errorRate float64) {
+	labels := e.labelsFor(simulationID)
+	e.errorRateGauge.WithLabelValues(fmt.Sprint(simulationID), labels.technology, labels.serviceName).Set(errorRate)
+}
+
+// UpdateResourceUsage sets the CPU/memory gauges for a simulation's target
+// service.
+func (e *PrometheusExporter) UpdateResourceUsage(simulationID int64, usage *ResourceUsage) {
+	if usage == nil {
+		return
+	}
+	labels := e.labelsFor(simulationID)
+	e.cpuGauge.WithLabelValues(fmt.Sprint(simulationID), labels.technology, labels.serviceName).Set(usage.CPUPercent)
+	e.memoryGauge.WithLabelValues(fmt.Sprint(simulationID), labels.technology, labels.serviceName).Set(usage.MemoryPercent)
+}
+
+// Describe implements prometheus.Collector.
+func (e *PrometheusExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- responseTimeDesc
+}
+
+// Collect implements prometheus.Collector, emitting one response-time
+// summary per simulation the collector currently has data for.
+func (e *PrometheusExporter) Collect(ch chan<- prometheus.Metric) {
+	for simulationID, sm := range e.collector.Snapshot() {
+		if sm.ResponseTimes == nil || sm.TotalRequests == 0 {
+			continue
+		}
+
+		labels := e.labelsFor(simulationID)
+		quantiles := map[float64]float64{
+			0.5:  sm.ResponseTimes.Median.Seconds(),
+			0.95: sm.ResponseTimes.P95.Seconds(),
+			0.99: sm.ResponseTimes.P99.Seconds(),
+		}
+		sum := sm.ResponseTimes.Mean.Seconds() * float64(sm.TotalRequests)
+
+		metric, err := prometheus.NewConstSummary(
+			responseTimeDesc,
+			uint64(sm.TotalRequests),
+			sum,
+			quantiles,
+			fmt.Sprint(simulationID), labels.technology, labels.serviceName,
+		)
+		if err != nil {
+			logrus.Warnf("failed to build response time summary for simulation %d: %v", simulationID, err)
+			continue
+		}
+		ch <- metric
+	}
+}
+
+// Handler returns the http.Handler to mount at /metrics.
+func (e *PrometheusExporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+}
+
+// StartPushing periodically pushes the current metrics to a Pushgateway at
+// url under jobName, for simulations short-lived enough that a scrape
+// interval would miss them entirely. It stops when ctx is canceled.
+func (e *PrometheusExporter) StartPushing(ctx context.Context, url, jobName string, interval time.Duration) {
+	if url == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	pusher := push.New(url, jobName).Gatherer(e.registry)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := pusher.Push(); err != nil {
+					logrus.Warnf("failed to push metrics to pushgateway %s: %v", url, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+// Granularity identifies a rollup resolution. GranularityRaw is never
+// stored itself — it labels points written at their native resolution
+// before the compactor has had a chance to downsample them.
+type Granularity string
+
+const (
+	GranularityRaw Granularity = "raw"
+	Granularity10s Granularity = "10s"
+	Granularity1m  Granularity = "1m"
+	Granularity5m  Granularity = "5m"
+	Granularity1h  Granularity = "1h"
+)
+
+// Duration returns the bucket width for a granularity, or 0 for GranularityRaw.
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case Granularity10s:
+		return 10 * time.Second
+	case Granularity1m:
+		return time.Minute
+	case Granularity5m:
+		return 5 * time.Minute
+	case Granularity1h:
+		return time.Hour
+	default:
+		return 0
+	}
+}
+
+// rollupLadder lists every rollup granularity the compactor maintains,
+// finest to coarsest.
+var rollupLadder = []Granularity{Granularity10s, Granularity1m, Granularity5m, Granularity1h}
+
+// PickGranularity returns the coarsest rollup whose bucket width still
+// satisfies step (i.e. is no wider than it), so GetHistoricalMetrics can
+// serve long ranges from a rollup instead of scanning raw points. Falls
+// back to the finest rollup if step is smaller than all of them.
+func PickGranularity(step time.Duration) Granularity {
+	chosen := rollupLadder[0]
+	if step <= 0 {
+		return chosen
+	}
+	for _, g := range rollupLadder {
+		if g.Duration() <= step {
+			chosen = g
+		}
+	}
+	return chosen
+}
+
+// MetricKind distinguishes which series a stored point or rollup belongs to.
+type MetricKind string
+
+const (
+	KindThroughput    MetricKind = "throughput"
+	KindErrorRate     MetricKind = "error_rate"
+	KindCPUPercent    MetricKind = "cpu_percent"
+	KindMemoryPercent MetricKind = "memory_percent"
+)
+
+// MetricPoint is a single raw sample at its native resolution.
+type MetricPoint struct {
+	SimulationID int64
+	Kind         MetricKind
+	Timestamp    time.Time
+	Value        float64
+}
+
+// MetricRollup is a downsampled aggregate over one bucket of a granularity.
+type MetricRollup struct {
+	SimulationID int64       `json:"simulation_id"`
+	Kind         MetricKind  `json:"kind"`
+	Granularity  Granularity `json:"granularity"`
+	BucketStart  time.Time   `json:"bucket_start"`
+	Avg          float64     `json:"avg"`
+	Min          float64     `json:"min"`
+	Max          float64     `json:"max"`
+	Count        int64       `json:"count"`
+}
+
+// HistoricalSeries is one Kind's rollup points for a simulation over a
+// queried range, at the granularity the store picked to satisfy the
+// requested step.
+type HistoricalSeries struct {
+	Kind        MetricKind     `json:"kind"`
+	Granularity Granularity    `json:"granularity"`
+	Points      []MetricRollup `json:"points"`
+}
+
+// RetentionPolicy controls how long raw points and each rollup granularity
+// are kept before the compactor deletes them.
+type RetentionPolicy struct {
+	RawRetention    time.Duration
+	RollupRetention map[Granularity]time.Duration
+}
+
+// DefaultRetentionPolicy keeps raw points for an hour (enough to roll them
+// all up at least once) and progressively longer windows for coarser
+// rollups, since they cost far less to retain.
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		RawRetention: time.Hour,
+		RollupRetention: map[Granularity]time.Duration{
+			Granularity10s: 24 * time.Hour,
+			Granularity1m:  7 * 24 * time.Hour,
+			Granularity5m:  30 * 24 * time.Hour,
+			Granularity1h:  365 * 24 * time.Hour,
+		},
+	}
+}
+
+// Store persists simulation time-series data so historical metrics survive
+// process restarts, and exposes rollups so long ranges can be queried
+// without scanning every raw sample.
+type Store interface {
+	// WritePoint appends a single raw sample.
+	WritePoint(ctx context.Context, point MetricPoint) error
+	// Query returns a simulation/kind's points between from and to, at the
+	// coarsest granularity that still satisfies step.
+	Query(ctx context.Context, simulationID int64, kind MetricKind, from, to time.Time, step time.Duration) (*HistoricalSeries, error)
+	// Close releases any underlying connection or background goroutines.
+	Close() error
+}
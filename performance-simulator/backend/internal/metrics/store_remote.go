@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// InfluxStore writes metric points to an InfluxDB bucket via the v2 client,
+// and reads rollups back through a Flux aggregateWindow query matching the
+// requested step.
+type InfluxStore struct {
+	client   influxdb2.Client
+	writeAPI api.WriteAPIBlocking
+	queryAPI api.QueryAPI
+	bucket   string
+}
+
+// NewInfluxStore connects to an InfluxDB instance at url using token, and
+// targets the given org/bucket.
+func NewInfluxStore(url, token, org, bucket string) *InfluxStore {
+	client := influxdb2.NewClient(url, token)
+	return &InfluxStore{
+		client:   client,
+		writeAPI: client.WriteAPIBlocking(org, bucket),
+		queryAPI: client.QueryAPI(org),
+		bucket:   bucket,
+	}
+}
+
+// WritePoint appends a single raw sample, tagged by simulation ID.
+func (s *InfluxStore) WritePoint(ctx context.Context, point MetricPoint) error {
+	p := influxdb2.NewPoint(
+		string(point.Kind),
+		map[string]string{"simulation_id": fmt.Sprint(point.SimulationID)},
+		map[string]interface{}{"value": point.Value},
+		point.Timestamp,
+	)
+	return s.writeAPI.WritePoint(ctx, p)
+}
+
+// Query aggregates points into windows matching the rollup granularity
+// closest to step via Flux's aggregateWindow.
+func (s *InfluxStore) Query(ctx context.Context, simulationID int64, kind MetricKind, from, to time.Time, step time.Duration) (*HistoricalSeries, error) {
+	granularity := PickGranularity(step)
+
+	flux := fmt.Sprintf(`
+		from(bucket: %q)
+		  |> range(start: %s, stop: %s)
+		  |> filter(fn: (r) => r._measurement == %q and r.simulation_id == %q)
+		  |> aggregateWindow(every: %s, fn: mean, createEmpty: false)
+	`, s.bucket, from.Format(time.RFC3339), to.Format(time.RFC3339), string(kind), fmt.Sprint(simulationID), granularity.Duration())
+
+	result, err := s.queryAPI.Query(ctx, flux)
+	if err != nil {
+		return nil, fmt.Errorf("influx query failed: %w", err)
+	}
+	defer result.Close()
+
+	points := make([]MetricRollup, 0)
+	for result.Next() {
+		record := result.Record()
+		value, _ := record.Value().(float64)
+		points = append(points, MetricRollup{
+			SimulationID: simulationID,
+			Kind:         kind,
+			Granularity:  granularity,
+			BucketStart:  record.Time(),
+			Avg:          value,
+			Min:          value,
+			Max:          value,
+			Count:        1,
+		})
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("influx query iteration failed: %w", result.Err())
+	}
+
+	return &HistoricalSeries{Kind: kind, Granularity: granularity, Points: points}, nil
+}
+
+// Close releases the InfluxDB client's connection pool.
+func (s *InfluxStore) Close() error {
+	s.client.Close()
+	return nil
+}
+
+// PrometheusRemoteWriteStore pushes points to a Prometheus-compatible
+// remote_write endpoint (Cortex, Mimir, Thanos receive, etc). It is
+// write-only: remote-write receivers don't expose a read path this Store
+// interface can query, so callers needing history should read from
+// Prometheus/Grafana directly instead of through GetHistoricalMetrics.
+type PrometheusRemoteWriteStore struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewPrometheusRemoteWriteStore targets a remote_write endpoint URL.
+func NewPrometheusRemoteWriteStore(endpoint string) *PrometheusRemoteWriteStore {
+	return &PrometheusRemoteWriteStore{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// WritePoint encodes a single sample as a Prometheus remote_write request.
+func (s *PrometheusRemoteWriteStore) WritePoint(ctx context.Context, point MetricPoint) error {
+	writeReq := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: "simulation_" + string(point.Kind)},
+					{Name: "sim_id", Value: fmt.Sprint(point.SimulationID)},
+				},
+				Samples: []prompb.Sample{
+					{Value: point.Value, Timestamp: point.Timestamp.UnixMilli()},
+				},
+			},
+		},
+	}
+
+	data, err := proto.Marshal(writeReq)
+	if err != nil {
+		return fmt.Errorf("failed to marshal remote write request: %w", err)
+	}
+	compressed := snappy.Encode(nil, data)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(compressed))
+	if err != nil {
+		return fmt.Errorf("failed to build remote write request: %w", err)
+	}
+	httpReq.Header.Set("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("remote write request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote write endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Query always fails: see the write-only note on PrometheusRemoteWriteStore.
+func (s *PrometheusRemoteWriteStore) Query(ctx context.Context, simulationID int64, kind MetricKind, from, to time.Time, step time.Duration) (*HistoricalSeries, error) {
+	return nil, fmt.Errorf("PrometheusRemoteWriteStore is write-only; query your Prometheus-compatible backend directly")
+}
+
+// Close is a no-op; PrometheusRemoteWriteStore holds no long-lived connection.
+func (s *PrometheusRemoteWriteStore) Close() error {
+	return nil
+}
@@ -0,0 +1,232 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqlitePoint is the GORM row backing a raw MetricPoint.
+type sqlitePoint struct {
+	ID           int64     `gorm:"primaryKey"`
+	SimulationID int64     `gorm:"index:idx_point_lookup"`
+	Kind         string    `gorm:"size:32;index:idx_point_lookup"`
+	Timestamp    time.Time `gorm:"index:idx_point_lookup"`
+	Value        float64
+}
+
+func (sqlitePoint) TableName() string { return "metric_points" }
+
+// sqliteRollup is the GORM row backing a downsampled MetricRollup.
+type sqliteRollup struct {
+	ID           int64     `gorm:"primaryKey"`
+	SimulationID int64     `gorm:"index:idx_rollup_lookup"`
+	Kind         string    `gorm:"size:32;index:idx_rollup_lookup"`
+	Granularity  string    `gorm:"size:8;index:idx_rollup_lookup"`
+	BucketStart  time.Time `gorm:"index:idx_rollup_lookup"`
+	Avg          float64
+	Min          float64
+	Max          float64
+	Count        int64
+}
+
+func (sqliteRollup) TableName() string { return "metric_rollups" }
+
+// SQLiteStore is the default Store: a local SQLite database plus a
+// background compactor that downsamples raw points into rollups and
+// enforces a RetentionPolicy.
+type SQLiteStore struct {
+	db        *gorm.DB
+	retention RetentionPolicy
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSQLiteStore opens (creating if necessary) a SQLite database at path
+// and starts its background compactor on a 10s tick.
+func NewSQLiteStore(path string, retention RetentionPolicy) (*SQLiteStore, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metrics store: %w", err)
+	}
+	if err := db.AutoMigrate(&sqlitePoint{}, &sqliteRollup{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate metrics store: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store := &SQLiteStore{db: db, retention: retention, cancel: cancel}
+
+	store.wg.Add(1)
+	go store.runCompactor(ctx)
+
+	return store, nil
+}
+
+// WritePoint appends a single raw sample.
+func (s *SQLiteStore) WritePoint(ctx context.Context, point MetricPoint) error {
+	row := sqlitePoint{
+		SimulationID: point.SimulationID,
+		Kind:         string(point.Kind),
+		Timestamp:    point.Timestamp,
+		Value:        point.Value,
+	}
+	return s.db.WithContext(ctx).Create(&row).Error
+}
+
+// Query reads rollups at the coarsest granularity satisfying step.
+func (s *SQLiteStore) Query(ctx context.Context, simulationID int64, kind MetricKind, from, to time.Time, step time.Duration) (*HistoricalSeries, error) {
+	granularity := PickGranularity(step)
+
+	var rows []sqliteRollup
+	err := s.db.WithContext(ctx).
+		Where("simulation_id = ? AND kind = ? AND granularity = ? AND bucket_start BETWEEN ? AND ?",
+			simulationID, string(kind), string(granularity), from, to).
+		Order("bucket_start asc").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rollups: %w", err)
+	}
+
+	points := make([]MetricRollup, 0, len(rows))
+	for _, r := range rows {
+		points = append(points, MetricRollup{
+			SimulationID: r.SimulationID,
+			Kind:         MetricKind(r.Kind),
+			Granularity:  Granularity(r.Granularity),
+			BucketStart:  r.BucketStart,
+			Avg:          r.Avg,
+			Min:          r.Min,
+			Max:          r.Max,
+			Count:        r.Count,
+		})
+	}
+
+	return &HistoricalSeries{Kind: kind, Granularity: granularity, Points: points}, nil
+}
+
+// Close stops the compactor and closes the underlying connection.
+func (s *SQLiteStore) Close() error {
+	s.cancel()
+	s.wg.Wait()
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// CompactNow runs one compaction pass immediately instead of waiting for
+// the next tick, for tests and for operators who want rollups fresh before
+// reading them.
+func (s *SQLiteStore) CompactNow() error {
+	return s.compactOnce()
+}
+
+// runCompactor periodically downsamples raw points into every configured
+// rollup granularity and deletes anything past its retention window.
+func (s *SQLiteStore) runCompactor(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.compactOnce(); err != nil {
+				logrus.Warnf("metrics store compaction failed: %v", err)
+			}
+		}
+	}
+}
+
+func (s *SQLiteStore) compactOnce() error {
+	now := time.Now()
+
+	for _, granularity := range rollupLadder {
+		if err := s.rollupInto(granularity, now); err != nil {
+			return fmt.Errorf("rollup %s: %w", granularity, err)
+		}
+	}
+
+	if err := s.db.Where("timestamp < ?", now.Add(-s.retention.RawRetention)).Delete(&sqlitePoint{}).Error; err != nil {
+		return fmt.Errorf("expire raw points: %w", err)
+	}
+
+	for granularity, retention := range s.retention.RollupRetention {
+		err := s.db.Where("granularity = ? AND bucket_start < ?", string(granularity), now.Add(-retention)).
+			Delete(&sqliteRollup{}).Error
+		if err != nil {
+			return fmt.Errorf("expire %s rollups: %w", granularity, err)
+		}
+	}
+
+	return nil
+}
+
+// rollupInto aggregates every raw point not yet expired into the given
+// granularity's buckets, upserting each bucket's running average/min/max.
+func (s *SQLiteStore) rollupInto(granularity Granularity, now time.Time) error {
+	bucketSize := granularity.Duration()
+	if bucketSize == 0 {
+		return nil
+	}
+
+	var points []sqlitePoint
+	if err := s.db.Where("timestamp <= ?", now).Find(&points).Error; err != nil {
+		return err
+	}
+
+	type bucketKey struct {
+		simulationID int64
+		kind         string
+		bucketStart  time.Time
+	}
+	buckets := make(map[bucketKey]*sqliteRollup)
+
+	for _, p := range points {
+		key := bucketKey{simulationID: p.SimulationID, kind: p.Kind, bucketStart: p.Timestamp.Truncate(bucketSize)}
+
+		r, ok := buckets[key]
+		if !ok {
+			r = &sqliteRollup{
+				SimulationID: p.SimulationID,
+				Kind:         p.Kind,
+				Granularity:  string(granularity),
+				BucketStart:  key.bucketStart,
+				Min:          p.Value,
+				Max:          p.Value,
+			}
+			buckets[key] = r
+		}
+		r.Count++
+		r.Avg += (p.Value - r.Avg) / float64(r.Count)
+		if p.Value < r.Min {
+			r.Min = p.Value
+		}
+		if p.Value > r.Max {
+			r.Max = p.Value
+		}
+	}
+
+	for _, r := range buckets {
+		err := s.db.Where("simulation_id = ? AND kind = ? AND granularity = ? AND bucket_start = ?",
+			r.SimulationID, r.Kind, r.Granularity, r.BucketStart).
+			Assign(sqliteRollup{Avg: r.Avg, Min: r.Min, Max: r.Max, Count: r.Count}).
+			FirstOrCreate(r).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -7,36 +7,163 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/dop251/goja"
+	"github.com/google/cel-go/cel"
 	"github.com/sirupsen/logrus"
+
+	"github.com/antchfx/xmlquery"
 )
 
-// AssertionEngine handles custom simplified assertions
+// AssertionEngine evaluates user-authored assertion scripts — small boolean
+// expressions such as `status == 200 && contains(body, 'ok')` — against an
+// HTTP response. Scripts are parsed with a hand-rolled lexer/recursive-
+// descent parser (assertion_lexer.go, assertion_parser.go), the same
+// approach template_expr.go takes for `{{...}}` templates, rather than
+// pulling in an external expression-engine dependency. JSON bodies are
+// queried with jsonpath(...) (jsonpath.go); XML and SOAP bodies are
+// queried with xpath(...)/xmlcontains(...)/soap(...) (xpath.go); a gRPC
+// response's trailing metadata is read with trailer(...), and a GraphQL
+// response's envelope with graphqlErrors()/graphqlData(...). An assertion
+// with Type == AssertionTypeCEL skips this hand-rolled language entirely
+// and instead runs Expression as a CEL program (cel_assertions.go), for
+// users who want the full CEL grammar instead of the operator set above.
+// Type == AssertionTypeJS instead runs Expression as JavaScript in a
+// sandboxed goja.Runtime (js_assertions.go), exposing a Postman-style
+// pm.response object for users migrating assertions from tools like
+// Postman/Newman.
 type AssertionEngine struct {
+	celMutex        sync.RWMutex
+	celProgramCache map[string]cel.Program // keyed by sha256(expression), see cel_assertions.go
+
+	jsMutex        sync.RWMutex
+	jsProgramCache map[string]*goja.Program // keyed by sha256(expression), see js_assertions.go
 }
 
 // NewAssertionEngine creates a new assertion engine
 func NewAssertionEngine() *AssertionEngine {
-	return &AssertionEngine{}
+	return &AssertionEngine{
+		celProgramCache: make(map[string]cel.Program),
+		jsProgramCache:  make(map[string]*goja.Program),
+	}
+}
+
+// assertionContext is the set of values an assertion script can reference by
+// bare identifier, plus the response metadata its built-in functions need.
+type assertionContext struct {
+	status      int
+	body        string
+	headers     http.Header
+	trailers    http.Header
+	durationMs  float64
+	contentType string
+
+	jsonValue interface{}
+	jsonErr   error
+
+	// bodyTypeHint is the current assertion's Assertion.BodyType, refreshed
+	// before each assertion is evaluated; it overrides contentType sniffing
+	// for xpath/xmlcontains/soap.
+	bodyTypeHint string
+
+	xmlParsed bool
+	xmlDoc    *xmlquery.Node
+	xmlErr    error
+}
+
+// xmlDocument lazily parses body as XML on first use and caches the result,
+// so multiple xpath/xmlcontains/soap assertions against the same response
+// only parse it once.
+func (c *assertionContext) xmlDocument() (*xmlquery.Node, error) {
+	if c.xmlParsed {
+		return c.xmlDoc, c.xmlErr
+	}
+	c.xmlParsed = true
+
+	if !c.looksLikeXML() {
+		c.xmlErr = fmt.Errorf("response does not look like XML (content-type %q); set the assertion's body_type to \"xml\" to force XML parsing", c.contentType)
+		return nil, c.xmlErr
+	}
+
+	c.xmlDoc, c.xmlErr = parseXMLDocument(c.body)
+	return c.xmlDoc, c.xmlErr
+}
+
+// looksLikeXML decides, for the current assertion, whether the body should
+// be parsed as XML: bodyTypeHint wins if set, otherwise it's sniffed from
+// the response's Content-Type (application/xml, text/xml, application/soap+xml).
+func (c *assertionContext) looksLikeXML() bool {
+	switch strings.ToLower(strings.TrimSpace(c.bodyTypeHint)) {
+	case "xml":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(strings.ToLower(c.contentType), "xml")
+}
+
+func (c *assertionContext) lookup(name string) (interface{}, error) {
+	switch name {
+	case "status":
+		return float64(c.status), nil
+	case "body":
+		return c.body, nil
+	case "size":
+		return float64(len(c.body)), nil
+	case "duration_ms":
+		return c.durationMs, nil
+	case "json":
+		if c.jsonErr != nil {
+			return nil, fmt.Errorf("json: %w", c.jsonErr)
+		}
+		return c.jsonValue, nil
+	default:
+		return nil, fmt.Errorf("unknown identifier %q", name)
+	}
 }
 
-// ExecuteAssertions runs all custom simplified assertions
-func (ae *AssertionEngine) ExecuteAssertions(resp *http.Response, body []byte, assertions []Assertion) []AssertionResult {
+// ExecuteAssertions runs every assertion's script against resp/body and
+// returns one AssertionResult per assertion, in order. responseTime is
+// exposed to scripts as the duration_ms identifier.
+func (ae *AssertionEngine) ExecuteAssertions(resp *http.Response, body []byte, assertions []Assertion, responseTime time.Duration) []AssertionResult {
 	results := make([]AssertionResult, len(assertions))
 
+	var jsonValue interface{}
+	jsonErr := json.Unmarshal(body, &jsonValue)
+
+	ctx := &assertionContext{
+		status:      resp.StatusCode,
+		body:        string(body),
+		headers:     resp.Header,
+		trailers:    resp.Trailer,
+		durationMs:  float64(responseTime.Milliseconds()),
+		contentType: resp.Header.Get("Content-Type"),
+		jsonValue:   jsonValue,
+		jsonErr:     jsonErr,
+	}
+
 	for i, assertion := range assertions {
-		result := AssertionResult{
-			Name:   assertion.Name,
-			Passed: false,
-		}
+		result := AssertionResult{Name: assertion.Name}
+
+		ctx.bodyTypeHint = assertion.BodyType
 
-		// Parse and execute simplified assertion expressions
-		passed, value, err := ae.executeSimplifiedAssertion(assertion.Script, resp, body)
+		var value interface{}
+		var err error
+		switch assertion.Type {
+		case AssertionTypeCEL:
+			value, err = ae.evaluateCEL(assertion.Expression, ctx)
+		case AssertionTypeJS:
+			value, err = ae.evaluateJS(assertion.Expression, ctx)
+		default:
+			value, err = ae.evaluate(assertion.Script, ctx)
+		}
 		if err != nil {
-			result.Error = fmt.Sprintf("Assertion execution error: %v", err)
+			result.Error = fmt.Sprintf("assertion execution error: %v", err)
 			logrus.Errorf("Assertion '%s' failed: %v", assertion.Name, err)
 		} else {
-			result.Passed = passed
+			result.Passed = truthy(value)
 			result.Value = value
 		}
 
@@ -46,381 +173,402 @@ func (ae *AssertionEngine) ExecuteAssertions(resp *http.Response, body []byte, a
 	return results
 }
 
-// executeSimplifiedAssertion executes a simplified assertion expression
-func (ae *AssertionEngine) executeSimplifiedAssertion(script string, resp *http.Response, body []byte) (bool, interface{}, error) {
-	// Parse simplified assertion expressions like:
-	// - "status == 200"
-	// - "contains(body, 'success')"
-	// - "header('Content-Type') == 'application/json'"
-	// - "jsonpath('$.status') == 'ok'"
-	
+// evaluate parses and runs a single assertion script against ctx.
+func (ae *AssertionEngine) evaluate(script string, ctx *assertionContext) (interface{}, error) {
 	script = strings.TrimSpace(script)
-	logrus.Debugf("Executing simplified assertion: %s", script)
-
-	// Handle status code checks
-	if strings.HasPrefix(script, "status") {
-		return ae.evaluateStatusAssertion(script, resp)
-	}
-
-	// Handle body content checks
-	if strings.HasPrefix(script, "contains(body,") || strings.Contains(script, "body.contains") {
-		return ae.evaluateBodyContainsAssertion(script, body)
-	}
-
-	// Handle header checks
-	if strings.HasPrefix(script, "header(") {
-		return ae.evaluateHeaderAssertion(script, resp)
+	node, err := parseAssertionExpr(script)
+	if err != nil {
+		return nil, fmt.Errorf("parse assertion %q: %w", script, err)
 	}
+	return node.eval(ctx)
+}
 
-	// Handle JSONPath checks
-	if strings.HasPrefix(script, "jsonpath(") {
-		return ae.evaluateJSONPathAssertion(script, body)
+// ValidateAssertion parses (but does not execute) assertion.Script, or
+// type-checks assertion.Expression when Type == AssertionTypeCEL, so a
+// malformed expression is rejected at scenario save/CRUD time rather than
+// first surfacing mid-run.
+func (ae *AssertionEngine) ValidateAssertion(assertion *Assertion) error {
+	if assertion.Type == AssertionTypeCEL {
+		if strings.TrimSpace(assertion.Expression) == "" {
+			return fmt.Errorf("CEL expression cannot be empty")
+		}
+		env, err := sharedCELEnv()
+		if err != nil {
+			return fmt.Errorf("build CEL environment: %w", err)
+		}
+		if _, issues := env.Compile(assertion.Expression); issues != nil && issues.Err() != nil {
+			return fmt.Errorf("invalid CEL expression %q: %w", assertion.Expression, issues.Err())
+		}
+		return nil
 	}
 
-	// Handle regex checks
-	if strings.HasPrefix(script, "regex(") {
-		return ae.evaluateRegexAssertion(script, body)
+	if assertion.Type == AssertionTypeJS {
+		if strings.TrimSpace(assertion.Expression) == "" {
+			return fmt.Errorf("JS expression cannot be empty")
+		}
+		if _, err := goja.Compile("assertion.js", assertion.Expression, false); err != nil {
+			return fmt.Errorf("invalid JS expression %q: %w", assertion.Expression, err)
+		}
+		return nil
 	}
 
-	// Handle size checks
-	if strings.HasPrefix(script, "size") {
-		return ae.evaluateSizeAssertion(script, body)
+	if strings.TrimSpace(assertion.Script) == "" {
+		return fmt.Errorf("assertion script cannot be empty")
 	}
-
-	// For unknown expressions, try simple boolean evaluation
-	if script == "true" {
-		return true, true, nil
+	node, err := parseAssertionExpr(assertion.Script)
+	if err != nil {
+		return fmt.Errorf("invalid assertion expression %q: %w", assertion.Script, err)
 	}
-	if script == "false" {
-		return false, false, nil
+	if err := validateAssertionNode(node); err != nil {
+		return fmt.Errorf("invalid assertion expression %q: %w", assertion.Script, err)
 	}
-
-	return false, nil, fmt.Errorf("unsupported assertion expression: %s", script)
+	return nil
 }
 
-// evaluateStatusAssertion evaluates status code assertions
-func (ae *AssertionEngine) evaluateStatusAssertion(script string, resp *http.Response) (bool, interface{}, error) {
-	// Examples: "status == 200", "status >= 200 && status < 300", "status != 404"
-	statusCode := resp.StatusCode
-	
-	if strings.Contains(script, "==") {
-		parts := strings.Split(script, "==")
-		if len(parts) == 2 {
-			expectedStr := strings.TrimSpace(parts[1])
-			if expected, err := strconv.Atoi(expectedStr); err == nil {
-				return statusCode == expected, statusCode, nil
-			}
+// validateAssertionNode walks a parsed AST checking that every identifier
+// and function call it references actually exists, so e.g. a typo'd
+// function name is caught here instead of only surfacing as a runtime
+// "unknown function" error on the assertion's first execution.
+func validateAssertionNode(node assertionNode) error {
+	switch n := node.(type) {
+	case *literalNode:
+		return nil
+	case *identNode:
+		switch n.name {
+		case "status", "body", "size", "duration_ms", "json":
+			return nil
+		default:
+			return fmt.Errorf("unknown identifier %q", n.name)
 		}
-	}
-	
-	if strings.Contains(script, "!=") {
-		parts := strings.Split(script, "!=")
-		if len(parts) == 2 {
-			expectedStr := strings.TrimSpace(parts[1])
-			if expected, err := strconv.Atoi(expectedStr); err == nil {
-				return statusCode != expected, statusCode, nil
-			}
+	case *unaryNode:
+		return validateAssertionNode(n.operand)
+	case *binaryNode:
+		if err := validateAssertionNode(n.left); err != nil {
+			return err
 		}
-	}
-	
-	if strings.Contains(script, ">=") {
-		parts := strings.Split(script, ">=")
-		if len(parts) == 2 {
-			expectedStr := strings.TrimSpace(parts[1])
-			if expected, err := strconv.Atoi(expectedStr); err == nil {
-				return statusCode >= expected, statusCode, nil
-			}
+		return validateAssertionNode(n.right)
+	case *callNode:
+		if _, ok := assertionFunctions[n.name]; !ok {
+			return fmt.Errorf("unknown function %q", n.name)
 		}
-	}
-	
-	if strings.Contains(script, "<=") {
-		parts := strings.Split(script, "<=")
-		if len(parts) == 2 {
-			expectedStr := strings.TrimSpace(parts[1])
-			if expected, err := strconv.Atoi(expectedStr); err == nil {
-				return statusCode <= expected, statusCode, nil
+		for _, arg := range n.args {
+			if err := validateAssertionNode(arg); err != nil {
+				return err
 			}
 		}
+		return nil
+	default:
+		return fmt.Errorf("unrecognized expression node %T", node)
 	}
-	
-	return false, statusCode, fmt.Errorf("unsupported status assertion: %s", script)
 }
 
-// evaluateBodyContainsAssertion evaluates body content assertions
-func (ae *AssertionEngine) evaluateBodyContainsAssertion(script string, body []byte) (bool, interface{}, error) {
-	bodyStr := string(body)
-	
-	// Extract the search string from contains(body, 'text')
-	if strings.HasPrefix(script, "contains(body,") {
-		start := strings.Index(script, "'")
-		if start == -1 {
-			start = strings.Index(script, "\"")
-		}
-		if start != -1 {
-			end := strings.LastIndex(script, "'")
-			if end == -1 {
-				end = strings.LastIndex(script, "\"")
-			}
-			if end > start {
-				searchText := script[start+1 : end]
-				contains := strings.Contains(bodyStr, searchText)
-				return contains, contains, nil
-			}
+// assertionFunc is a built-in callable an assertion script can invoke, e.g.
+// contains(body, 'ok'). Arguments have already been evaluated by the time
+// the function runs.
+type assertionFunc func(ctx *assertionContext, args []interface{}) (interface{}, error)
+
+var assertionFunctions = map[string]assertionFunc{
+	"contains": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("contains", args, 2); err != nil {
+			return nil, err
 		}
-	}
-	
-	return false, nil, fmt.Errorf("unsupported body assertion: %s", script)
+		if arr, ok := args[0].([]interface{}); ok {
+			return sliceContainsEqual(arr, args[1]), nil
+		}
+		return strings.Contains(toStringArg(args[0]), toStringArg(args[1])), nil
+	},
+	"startsWith": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("startsWith", args, 2); err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(toStringArg(args[0]), toStringArg(args[1])), nil
+	},
+	"endsWith": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("endsWith", args, 2); err != nil {
+			return nil, err
+		}
+		return strings.HasSuffix(toStringArg(args[0]), toStringArg(args[1])), nil
+	},
+	"header": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("header", args, 1); err != nil {
+			return nil, err
+		}
+		return ctx.headers.Get(toStringArg(args[0])), nil
+	},
+	"trailer": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("trailer", args, 1); err != nil {
+			return nil, err
+		}
+		return ctx.trailers.Get(toStringArg(args[0])), nil
+	},
+	"jsonpath": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("jsonpath", args, 1); err != nil {
+			return nil, err
+		}
+		if ctx.jsonErr != nil {
+			return nil, fmt.Errorf("jsonpath: invalid JSON body: %w", ctx.jsonErr)
+		}
+		value, err := defaultJSONPathEvaluator.Evaluate(ctx.jsonValue, toStringArg(args[0]))
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath: %w", err)
+		}
+		return value, nil
+	},
+	"graphqlErrors": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("graphqlErrors", args, 0); err != nil {
+			return nil, err
+		}
+		if ctx.jsonErr != nil {
+			return nil, fmt.Errorf("graphqlErrors: invalid JSON body: %w", ctx.jsonErr)
+		}
+		value, err := defaultJSONPathEvaluator.Evaluate(ctx.jsonValue, "$.errors[*].message")
+		if err != nil {
+			return nil, fmt.Errorf("graphqlErrors: %w", err)
+		}
+		switch v := value.(type) {
+		case nil:
+			return []interface{}{}, nil
+		case []interface{}:
+			return v, nil
+		default:
+			return []interface{}{v}, nil
+		}
+	},
+	"graphqlData": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("graphqlData", args, 1); err != nil {
+			return nil, err
+		}
+		if ctx.jsonErr != nil {
+			return nil, fmt.Errorf("graphqlData: invalid JSON body: %w", ctx.jsonErr)
+		}
+		value, err := defaultJSONPathEvaluator.Evaluate(ctx.jsonValue, graphqlDataPath(toStringArg(args[0])))
+		if err != nil {
+			return nil, fmt.Errorf("graphqlData: %w", err)
+		}
+		return value, nil
+	},
+	"regex": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("regex expects 1 or 2 arguments, got %d", len(args))
+		}
+		pattern := toStringArg(args[0])
+		subject := ctx.body
+		if len(args) == 2 {
+			subject = toStringArg(args[1])
+		}
+		matched, err := regexp.MatchString(pattern, subject)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern %q: %w", pattern, err)
+		}
+		return matched, nil
+	},
+	"len": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("len", args, 1); err != nil {
+			return nil, err
+		}
+		switch v := args[0].(type) {
+		case string:
+			return float64(len(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		default:
+			return nil, fmt.Errorf("len: unsupported argument type %T", v)
+		}
+	},
+	"int": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("int", args, 1); err != nil {
+			return nil, err
+		}
+		f, err := toFloat(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("int: %w", err)
+		}
+		return float64(int64(f)), nil
+	},
+	"float": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("float", args, 1); err != nil {
+			return nil, err
+		}
+		f, err := toFloat(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("float: %w", err)
+		}
+		return f, nil
+	},
+	"xpath": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("xpath", args, 1); err != nil {
+			return nil, err
+		}
+		doc, err := ctx.xmlDocument()
+		if err != nil {
+			return nil, fmt.Errorf("xpath: %w", err)
+		}
+		value, err := defaultXPathEvaluator.Evaluate(doc, toStringArg(args[0]))
+		if err != nil {
+			return nil, fmt.Errorf("xpath: %w", err)
+		}
+		return value, nil
+	},
+	"xmlcontains": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("xmlcontains", args, 2); err != nil {
+			return nil, err
+		}
+		doc, err := ctx.xmlDocument()
+		if err != nil {
+			return nil, fmt.Errorf("xmlcontains: %w", err)
+		}
+		value, err := defaultXPathEvaluator.Evaluate(doc, toStringArg(args[0]))
+		if err != nil {
+			return nil, fmt.Errorf("xmlcontains: %w", err)
+		}
+		return xmlValueContains(value, toStringArg(args[1])), nil
+	},
+	"soap": func(ctx *assertionContext, args []interface{}) (interface{}, error) {
+		if err := requireArgs("soap", args, 1); err != nil {
+			return nil, err
+		}
+		doc, err := ctx.xmlDocument()
+		if err != nil {
+			return nil, fmt.Errorf("soap: %w", err)
+		}
+		bodyElement, err := soapBodyElement(doc)
+		if err != nil {
+			return nil, fmt.Errorf("soap: %w", err)
+		}
+		value, err := defaultXPathEvaluator.Evaluate(bodyElement, toStringArg(args[0]))
+		if err != nil {
+			return nil, fmt.Errorf("soap: %w", err)
+		}
+		return value, nil
+	},
 }
 
-// evaluateHeaderAssertion evaluates header assertions
-func (ae *AssertionEngine) evaluateHeaderAssertion(script string, resp *http.Response) (bool, interface{}, error) {
-	// Example: "header('Content-Type') == 'application/json'"
-	
-	// Extract header name
-	start := strings.Index(script, "'")
-	if start == -1 {
-		start = strings.Index(script, "\"")
-	}
-	if start == -1 {
-		return false, nil, fmt.Errorf("invalid header assertion syntax: %s", script)
-	}
-	
-	end := strings.Index(script[start+1:], "'")
-	if end == -1 {
-		end = strings.Index(script[start+1:], "\"")
-	}
-	if end == -1 {
-		return false, nil, fmt.Errorf("invalid header assertion syntax: %s", script)
-	}
-	
-	headerName := script[start+1 : start+1+end]
-	headerValue := resp.Header.Get(headerName)
-	
-	// Check for equality
-	if strings.Contains(script, "==") {
-		parts := strings.Split(script, "==")
-		if len(parts) == 2 {
-			expectedValue := strings.TrimSpace(parts[1])
-			// Remove quotes
-			expectedValue = strings.Trim(expectedValue, "'\"")
-			return headerValue == expectedValue, headerValue, nil
+// xmlValueContains reports whether an xpath(...) result (a scalar, or a
+// []interface{} for a multi-match node-set) contains substr.
+func xmlValueContains(value interface{}, substr string) bool {
+	if arr, ok := value.([]interface{}); ok {
+		for _, item := range arr {
+			if strings.Contains(toStringArg(item), substr) {
+				return true
+			}
 		}
+		return false
 	}
-	
-	return false, headerValue, fmt.Errorf("unsupported header assertion: %s", script)
+	return strings.Contains(toStringArg(value), substr)
 }
 
-// evaluateJSONPathAssertion evaluates JSONPath assertions
-func (ae *AssertionEngine) evaluateJSONPathAssertion(script string, body []byte) (bool, interface{}, error) {
-	// Example: "jsonpath('$.status') == 'ok'"
-	
-	var jsonData interface{}
-	if err := json.Unmarshal(body, &jsonData); err != nil {
-		return false, nil, fmt.Errorf("invalid JSON body: %v", err)
-	}
-	
-	// Extract JSONPath
-	start := strings.Index(script, "'")
-	if start == -1 {
-		start = strings.Index(script, "\"")
-	}
-	if start == -1 {
-		return false, nil, fmt.Errorf("invalid jsonpath assertion syntax: %s", script)
-	}
-	
-	end := strings.Index(script[start+1:], "'")
-	if end == -1 {
-		end = strings.Index(script[start+1:], "\"")
+// graphqlDataPath rewrites a JSONPath/JSON-pointer path so it's scoped
+// under a GraphQL response's "data" field, so graphqlData('$.user.id') reads
+// the same way jsonpath('$.data.user.id') would, without making callers
+// spell out "data" themselves.
+func graphqlDataPath(path string) string {
+	path = strings.TrimSpace(path)
+	switch {
+	case strings.HasPrefix(path, "/"):
+		return "/data" + path
+	case path == "" || path == "$":
+		return "$.data"
+	case strings.HasPrefix(path, "$."), strings.HasPrefix(path, "$["):
+		return "$.data" + path[1:]
+	default:
+		return "$.data." + path
 	}
-	if end == -1 {
-		return false, nil, fmt.Errorf("invalid jsonpath assertion syntax: %s", script)
-	}
-	
-	jsonPath := script[start+1 : start+1+end]
-	value := ae.extractJSONPathValue(jsonData, jsonPath)
-	
-	// Check for equality
-	if strings.Contains(script, "==") {
-		parts := strings.Split(script, "==")
-		if len(parts) == 2 {
-			expectedValue := strings.TrimSpace(parts[1])
-			expectedValue = strings.Trim(expectedValue, "'\"")
-			
-			// Convert value to string for comparison
-			valueStr := fmt.Sprintf("%v", value)
-			return valueStr == expectedValue, value, nil
-		}
-	}
-	
-	return false, value, fmt.Errorf("unsupported jsonpath assertion: %s", script)
 }
 
-// evaluateRegexAssertion evaluates regex assertions
-func (ae *AssertionEngine) evaluateRegexAssertion(script string, body []byte) (bool, interface{}, error) {
-	// Example: "regex('\\d+') matches body"
-	
-	bodyStr := string(body)
-	
-	// Extract regex pattern
-	start := strings.Index(script, "'")
-	if start == -1 {
-		start = strings.Index(script, "\"")
-	}
-	if start == -1 {
-		return false, nil, fmt.Errorf("invalid regex assertion syntax: %s", script)
-	}
-	
-	end := strings.Index(script[start+1:], "'")
-	if end == -1 {
-		end = strings.Index(script[start+1:], "\"")
+func requireArgs(name string, args []interface{}, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%s expects %d argument(s), got %d", name, n, len(args))
 	}
-	if end == -1 {
-		return false, nil, fmt.Errorf("invalid regex assertion syntax: %s", script)
-	}
-	
-	pattern := script[start+1 : start+1+end]
-	
-	matched, err := regexp.MatchString(pattern, bodyStr)
-	if err != nil {
-		return false, nil, fmt.Errorf("invalid regex pattern '%s': %v", pattern, err)
-	}
-	
-	return matched, matched, nil
+	return nil
 }
 
-// evaluateSizeAssertion evaluates size assertions
-func (ae *AssertionEngine) evaluateSizeAssertion(script string, body []byte) (bool, interface{}, error) {
-	// Example: "size > 100", "size <= 1000"
-	
-	size := len(body)
-	
-	if strings.Contains(script, "<=") {
-		parts := strings.Split(script, "<=")
-		if len(parts) == 2 {
-			maxSizeStr := strings.TrimSpace(parts[1])
-			if maxSize, err := strconv.Atoi(maxSizeStr); err == nil {
-				return size <= maxSize, size, nil
-			}
-		}
-	}
-	
-	if strings.Contains(script, ">=") {
-		parts := strings.Split(script, ">=")
-		if len(parts) == 2 {
-			minSizeStr := strings.TrimSpace(parts[1])
-			if minSize, err := strconv.Atoi(minSizeStr); err == nil {
-				return size >= minSize, size, nil
-			}
-		}
+// toStringArg renders an evaluated argument as a string for the string-ish
+// built-ins (contains, startsWith, header name, ...).
+func toStringArg(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", t)
 	}
-	
-	if strings.Contains(script, ">") {
-		parts := strings.Split(script, ">")
-		if len(parts) == 2 {
-			minSizeStr := strings.TrimSpace(parts[1])
-			if minSize, err := strconv.Atoi(minSizeStr); err == nil {
-				return size > minSize, size, nil
-			}
+}
+
+// toFloat coerces an evaluated value to a number for arithmetic/relational
+// operators, including numeric strings like jsonpath results pulled out of
+// string-typed JSON fields.
+func toFloat(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case bool:
+		if t {
+			return 1, nil
 		}
-	}
-	
-	if strings.Contains(script, "<") {
-		parts := strings.Split(script, "<")
-		if len(parts) == 2 {
-			maxSizeStr := strings.TrimSpace(parts[1])
-			if maxSize, err := strconv.Atoi(maxSizeStr); err == nil {
-				return size < maxSize, size, nil
-			}
+		return 0, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %q to a number", t)
 		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %v to a number", v)
 	}
-	
-	return false, size, fmt.Errorf("unsupported size assertion: %s", script)
 }
 
-// extractJSONPathValue extracts value using simplified JSONPath (same as in validation_engine.go)
-func (ae *AssertionEngine) extractJSONPathValue(data interface{}, path string) interface{} {
-	if path == "$" {
-		return data
-	}
-	
-	if strings.HasPrefix(path, "$.") {
-		fieldPath := strings.TrimPrefix(path, "$.")
-		return ae.getNestedValue(data, fieldPath)
+// truthy decides whether an evaluated value counts as "passed" when it is
+// the final result of an assertion script, or the left/right of && / ||.
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return t != ""
+	case nil:
+		return false
+	default:
+		return true
 	}
-	
-	return nil
 }
 
-// getNestedValue extracts nested values from JSON data (same as in validation_engine.go)
-func (ae *AssertionEngine) getNestedValue(data interface{}, path string) interface{} {
-	parts := strings.Split(path, ".")
-	current := data
-	
-	for _, part := range parts {
-		// Handle array indices
-		if strings.Contains(part, "[") && strings.Contains(part, "]") {
-			// Simple array index handling like "items[0]"
-			beforeBracket := strings.Split(part, "[")[0]
-			indexStr := strings.Split(strings.Split(part, "[")[1], "]")[0]
-			
-			if m, ok := current.(map[string]interface{}); ok {
-				current = m[beforeBracket]
-			} else {
-				return nil
-			}
-			
-			if arr, ok := current.([]interface{}); ok {
-				if index, err := strconv.Atoi(indexStr); err == nil && index < len(arr) {
-					current = arr[index]
-				} else {
-					return nil
-				}
-			} else {
-				return nil
-			}
-		} else {
-			if m, ok := current.(map[string]interface{}); ok {
-				current = m[part]
-			} else {
-				return nil
-			}
-		}
+// valuesEqual compares two evaluated values for ==/!=. A []interface{} on
+// either side (a multi-match jsonpath(...) result) is treated as a
+// membership check rather than a single scalar comparison. Otherwise,
+// numeric comparison is tried first so `status == 200` and
+// `jsonpath('$.count') == 3` work even though one side of a JSON-derived
+// value may come back as a string; falls back to a string comparison
+// otherwise.
+func valuesEqual(a, b interface{}) bool {
+	if arr, ok := a.([]interface{}); ok {
+		return sliceContainsEqual(arr, b)
+	}
+	if arr, ok := b.([]interface{}); ok {
+		return sliceContainsEqual(arr, a)
 	}
-	
-	return current
-}
 
-// ValidateAssertion validates a single assertion syntax without executing it
-func (ae *AssertionEngine) ValidateAssertion(assertion *Assertion) error {
-	if assertion.Script == "" {
-		return fmt.Errorf("assertion script cannot be empty")
+	af, aerr := toFloat(a)
+	bf, berr := toFloat(b)
+	if aerr == nil && berr == nil {
+		return af == bf
 	}
-	
-	script := strings.TrimSpace(assertion.Script)
-	
-	// Basic syntax validation for supported expressions
-	supportedPrefixes := []string{
-		"status",
-		"contains(body,",
-		"header(",
-		"jsonpath(",
-		"regex(",
-		"size",
-		"true",
-		"false",
-	}
-	
-	valid := false
-	for _, prefix := range supportedPrefixes {
-		if strings.HasPrefix(script, prefix) {
-			valid = true
-			break
+	return toStringArg(a) == toStringArg(b)
+}
+
+// sliceContainsEqual reports whether any element of arr equals v under
+// valuesEqual's rules.
+func sliceContainsEqual(arr []interface{}, v interface{}) bool {
+	for _, item := range arr {
+		if valuesEqual(item, v) {
+			return true
 		}
 	}
-	
-	if !valid {
-		return fmt.Errorf("unsupported assertion expression: %s. Supported expressions: status==200, contains(body,'text'), header('name')=='value', jsonpath('$.field')=='value', regex('pattern'), size>100", script)
-	}
-	
-	return nil
-}
\ No newline at end of file
+	return false
+}
@@ -0,0 +1,170 @@
+package simulator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// assertionTokenKind identifies the lexical category of an assertionToken.
+type assertionTokenKind int
+
+const (
+	tokEOF assertionTokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokAnd // &&
+	tokOr  // ||
+	tokNot // !
+	tokEq  // ==
+	tokNeq // !=
+	tokLt
+	tokLte
+	tokGt
+	tokGte
+	tokLParen
+	tokRParen
+	tokComma
+	tokPlus
+	tokMinus
+	tokStar
+	tokSlash
+)
+
+// assertionToken is one lexical token out of an assertion script. num and
+// text are only meaningful for the kinds that carry a value (tokNumber,
+// tokString, tokIdent).
+type assertionToken struct {
+	kind assertionTokenKind
+	text string
+	num  float64
+}
+
+// tokenizeAssertion lexes an assertion script such as
+// `status == 200 && contains(body, 'ok')` into a flat token stream ending in
+// a tokEOF sentinel, so the parser never needs to bounds-check.
+func tokenizeAssertion(input string) ([]assertionToken, error) {
+	var tokens []assertionToken
+	i, n := 0, len(input)
+
+	for i < n {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, assertionToken{kind: tokLParen})
+			i++
+		case c == ')':
+			tokens = append(tokens, assertionToken{kind: tokRParen})
+			i++
+		case c == ',':
+			tokens = append(tokens, assertionToken{kind: tokComma})
+			i++
+		case c == '+':
+			tokens = append(tokens, assertionToken{kind: tokPlus})
+			i++
+		case c == '*':
+			tokens = append(tokens, assertionToken{kind: tokStar})
+			i++
+		case c == '/':
+			tokens = append(tokens, assertionToken{kind: tokSlash})
+			i++
+		case c == '&' && i+1 < n && input[i+1] == '&':
+			tokens = append(tokens, assertionToken{kind: tokAnd})
+			i += 2
+		case c == '|' && i+1 < n && input[i+1] == '|':
+			tokens = append(tokens, assertionToken{kind: tokOr})
+			i += 2
+		case c == '!' && i+1 < n && input[i+1] == '=':
+			tokens = append(tokens, assertionToken{kind: tokNeq})
+			i += 2
+		case c == '!':
+			tokens = append(tokens, assertionToken{kind: tokNot})
+			i++
+		case c == '=' && i+1 < n && input[i+1] == '=':
+			tokens = append(tokens, assertionToken{kind: tokEq})
+			i += 2
+		case c == '<' && i+1 < n && input[i+1] == '=':
+			tokens = append(tokens, assertionToken{kind: tokLte})
+			i += 2
+		case c == '<':
+			tokens = append(tokens, assertionToken{kind: tokLt})
+			i++
+		case c == '>' && i+1 < n && input[i+1] == '=':
+			tokens = append(tokens, assertionToken{kind: tokGte})
+			i += 2
+		case c == '>':
+			tokens = append(tokens, assertionToken{kind: tokGt})
+			i++
+		case c == '\'' || c == '"':
+			text, end, err := scanAssertionString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, assertionToken{kind: tokString, text: text})
+			i = end
+		case c >= '0' && c <= '9':
+			end := i
+			for end < n && (input[end] >= '0' && input[end] <= '9' || input[end] == '.') {
+				end++
+			}
+			numText := input[i:end]
+			num, err := strconv.ParseFloat(numText, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number literal %q", numText)
+			}
+			tokens = append(tokens, assertionToken{kind: tokNumber, num: num, text: numText})
+			i = end
+		case isIdentStart(c):
+			// '-' is handled here too, since a bareword minus sign only ever
+			// appears as the unary/subtraction operator, never starting an
+			// identifier, so there's no ambiguity with the numeric case above.
+			end := i
+			for end < n && isIdentPart(input[end]) {
+				end++
+			}
+			tokens = append(tokens, assertionToken{kind: tokIdent, text: input[i:end]})
+			i = end
+		case c == '-':
+			tokens = append(tokens, assertionToken{kind: tokMinus})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", string(c), i)
+		}
+	}
+
+	tokens = append(tokens, assertionToken{kind: tokEOF})
+	return tokens, nil
+}
+
+// scanAssertionString reads a single- or double-quoted string literal
+// starting at input[start], returning its decoded text and the index just
+// past the closing quote.
+func scanAssertionString(input string, start int) (string, int, error) {
+	quote := input[start]
+	var sb strings.Builder
+	j := start + 1
+	for j < len(input) {
+		if input[j] == '\\' && j+1 < len(input) {
+			sb.WriteByte(input[j+1])
+			j += 2
+			continue
+		}
+		if input[j] == quote {
+			return sb.String(), j + 1, nil
+		}
+		sb.WriteByte(input[j])
+		j++
+	}
+	return "", 0, fmt.Errorf("unterminated string literal at position %d", start)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,412 @@
+package simulator
+
+import "fmt"
+
+// assertionNode is one node of a parsed assertion expression's AST.
+// Evaluation is dynamically typed: operands come back as bool, float64, or
+// string, and operators coerce as needed (see toFloat/truthy/valuesEqual in
+// assertion_engine.go).
+type assertionNode interface {
+	eval(ctx *assertionContext) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(_ *assertionContext) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(ctx *assertionContext) (interface{}, error) {
+	return ctx.lookup(n.name)
+}
+
+type unaryNode struct {
+	op      string // "!" or "-"
+	operand assertionNode
+}
+
+func (n *unaryNode) eval(ctx *assertionContext) (interface{}, error) {
+	v, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		return !truthy(v), nil
+	case "-":
+		f, err := toFloat(v)
+		if err != nil {
+			return nil, fmt.Errorf("unary -: %w", err)
+		}
+		return -f, nil
+	}
+	return nil, fmt.Errorf("unknown unary operator %q", n.op)
+}
+
+type binaryNode struct {
+	op          string
+	left, right assertionNode
+}
+
+func (n *binaryNode) eval(ctx *assertionContext) (interface{}, error) {
+	switch n.op {
+	case "&&":
+		l, err := n.left.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !truthy(l) {
+			return false, nil
+		}
+		r, err := n.right.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(r), nil
+	case "||":
+		l, err := n.left.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(l) {
+			return true, nil
+		}
+		r, err := n.right.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(r), nil
+	}
+
+	l, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return valuesEqual(l, r), nil
+	case "!=":
+		return !valuesEqual(l, r), nil
+	case "<", "<=", ">", ">=":
+		lf, err := toFloat(l)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", n.op, err)
+		}
+		rf, err := toFloat(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", n.op, err)
+		}
+		switch n.op {
+		case "<":
+			return lf < rf, nil
+		case "<=":
+			return lf <= rf, nil
+		case ">":
+			return lf > rf, nil
+		default:
+			return lf >= rf, nil
+		}
+	case "+", "-", "*", "/":
+		lf, err := toFloat(l)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", n.op, err)
+		}
+		rf, err := toFloat(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", n.op, err)
+		}
+		switch n.op {
+		case "+":
+			return lf + rf, nil
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		default:
+			if rf == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return lf / rf, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+type callNode struct {
+	name string
+	args []assertionNode
+}
+
+func (n *callNode) eval(ctx *assertionContext) (interface{}, error) {
+	fn, ok := assertionFunctions[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", n.name)
+	}
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return fn(ctx, args)
+}
+
+// assertionParser is a hand-rolled recursive-descent parser over the token
+// stream produced by tokenizeAssertion, mirroring the precedent set by
+// template_expr.go's own hand-rolled template grammar. Precedence, loosest
+// to tightest: || , && , ==/!= , </<=/>/>= , +/- , * / , unary !/- , primary.
+type assertionParser struct {
+	tokens []assertionToken
+	pos    int
+}
+
+// parseAssertionExpr lexes and parses a full assertion script, failing if
+// any input remains after the expression.
+func parseAssertionExpr(input string) (assertionNode, error) {
+	tokens, err := tokenizeAssertion(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &assertionParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.current().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected token %q", p.current().text)
+	}
+	return node, nil
+}
+
+func (p *assertionParser) current() assertionToken {
+	return p.tokens[p.pos]
+}
+
+func (p *assertionParser) advance() assertionToken {
+	t := p.tokens[p.pos]
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *assertionParser) parseOr() (assertionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.current().kind == tokOr {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *assertionParser) parseAnd() (assertionNode, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+	for p.current().kind == tokAnd {
+		p.advance()
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *assertionParser) parseEquality() (assertionNode, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for p.current().kind == tokEq || p.current().kind == tokNeq {
+		op := "=="
+		if p.advance().kind == tokNeq {
+			op = "!="
+		}
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *assertionParser) parseRelational() (assertionNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch p.current().kind {
+		case tokLt:
+			op = "<"
+		case tokLte:
+			op = "<="
+		case tokGt:
+			op = ">"
+		case tokGte:
+			op = ">="
+		default:
+			return left, nil
+		}
+		p.advance()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *assertionParser) parseAdditive() (assertionNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch p.current().kind {
+		case tokPlus:
+			op = "+"
+		case tokMinus:
+			op = "-"
+		default:
+			return left, nil
+		}
+		p.advance()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *assertionParser) parseMultiplicative() (assertionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch p.current().kind {
+		case tokStar:
+			op = "*"
+		case tokSlash:
+			op = "/"
+		default:
+			return left, nil
+		}
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *assertionParser) parseUnary() (assertionNode, error) {
+	switch p.current().kind {
+	case tokNot:
+		p.advance()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "!", operand: operand}, nil
+	case tokMinus:
+		p.advance()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *assertionParser) parsePrimary() (assertionNode, error) {
+	tok := p.current()
+	switch tok.kind {
+	case tokNumber:
+		p.advance()
+		return &literalNode{value: tok.num}, nil
+	case tokString:
+		p.advance()
+		return &literalNode{value: tok.text}, nil
+	case tokLParen:
+		p.advance()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.current().kind != tokRParen {
+			return nil, fmt.Errorf("expected ')'")
+		}
+		p.advance()
+		return node, nil
+	case tokIdent:
+		name := tok.text
+		p.advance()
+		if p.current().kind == tokLParen {
+			return p.parseCallArgs(name)
+		}
+		switch name {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		return &identNode{name: name}, nil
+	}
+	return nil, fmt.Errorf("unexpected token %q", tok.text)
+}
+
+func (p *assertionParser) parseCallArgs(name string) (assertionNode, error) {
+	p.advance() // consume '('
+	var args []assertionNode
+	if p.current().kind != tokRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.current().kind == tokComma {
+				p.advance()
+				continue
+			}
+			break
+		}
+	}
+	if p.current().kind != tokRParen {
+		return nil, fmt.Errorf("expected ')' after arguments to %s(...)", name)
+	}
+	p.advance()
+	return &callNode{name: name, args: args}, nil
+}
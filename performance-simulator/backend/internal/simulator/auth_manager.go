@@ -2,36 +2,139 @@ package simulator
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"golang.org/x/sync/singleflight"
 )
 
-// AuthManager handles authentication for HTTP requests
+// defaultTokenRefreshSkew is how far ahead of a token's real expiry
+// AuthManager proactively refreshes it, so a request in flight doesn't race
+// a token that expires mid-request.
+const defaultTokenRefreshSkew = 60 * time.Second
+
+// AuthManager handles authentication for HTTP requests. Static schemes
+// (bearer, basic, API key) are applied directly from config; JWT and OAuth2
+// tokens are fetched, cached per credential set, and transparently
+// refreshed ahead of expiry; client-certificate auth is loaded once into a
+// reusable *tls.Config.
 type AuthManager struct {
 	configs map[int64]*AuthConfig
-	tokens  map[int64]*TokenCache
 	mutex   sync.RWMutex
+
+	// RefreshSkew controls how early a near-expiry token is refreshed.
+	// Defaults to defaultTokenRefreshSkew.
+	RefreshSkew time.Duration
+
+	tokenMu    sync.RWMutex
+	tokenCache map[string]*TokenCache
+
+	tlsMu         sync.Mutex
+	tlsConfigs    map[string]*tls.Config
+	rotatingCerts map[string]*rotatingClientCert
+
+	// jwtMu/jwtSigners cache a parsed jwtSigner per signing-key
+	// configuration, so minting a fresh JWT on every request under
+	// millions-of-RPS doesn't re-parse a PEM key or re-validate an HMAC
+	// secret each time. Keyed by jwtSignerCacheKey, not simulation ID, so
+	// simulations sharing the same signing config share one signer.
+	jwtMu      sync.Mutex
+	jwtSigners map[string]*jwtSigner
+
+	// refreshGroup collapses concurrent fetch/refresh calls for the same
+	// cache key onto a single in-flight request, so parallel simulator
+	// workers sharing one OAuth2 client or JWT refresh token don't
+	// stampede the token endpoint.
+	refreshGroup singleflight.Group
+
+	// oidcDiscovery/jwksSets cache OIDC-mode JWTAuth lookups (see
+	// jwks_auth.go): the discovery document per issuer, and the parsed
+	// JWKS key set per JWKS URI, so simulations pointed at the same
+	// provider share one discovery round-trip and one key set.
+	oidcMu        sync.Mutex
+	oidcDiscovery map[string]*oidcDiscoveryDocument
+	jwksMu        sync.RWMutex
+	jwksSets      map[string]*jwksKeySet
+
+	// now is the clock used by JWKS refresh-cooldown logic; overridable via
+	// NewAuthManagerWithClock so tests can control cooldown behavior
+	// deterministically.
+	now func() time.Time
+
+	// OAuth2TokenFetcher overrides how OAuth2 tokens are fetched. Defaults
+	// to defaultOAuth2TokenFetcher (a real golang.org/x/oauth2-backed
+	// implementation); tests can set this to a fake to exercise
+	// AuthManager's caching/refresh/rotation logic without a real token
+	// endpoint.
+	OAuth2TokenFetcher OAuth2TokenFetcher
+
+	// SecretManager resolves "scheme://..." secret references (see
+	// secrets.go) found in credential fields — BearerAuth.Token,
+	// BasicAuth.Password, OAuth2Auth.ClientSecret, TLSConfig's file fields —
+	// so simulation configs can reference env vars, files, or a Vault
+	// secret instead of embedding the plaintext credential.
+	SecretManager *SecretManager
 }
 
-// TokenCache represents cached authentication tokens
+// OAuth2TokenFetcher fetches a fresh OAuth2 token for auth's grant.
+type OAuth2TokenFetcher interface {
+	FetchOAuth2Token(ctx context.Context, auth *OAuth2Auth) (*TokenCache, error)
+}
+
+// TokenCache is a cached authentication token, keyed per credential set
+// (see oauth2CacheKey/jwtCacheKey) rather than per simulation, so multiple
+// simulations sharing one OAuth2 client or JWT reuse a single token.
 type TokenCache struct {
 	AccessToken  string
 	RefreshToken string
-	ExpiresAt    int64
+	ExpiresAt    int64 // unix seconds; 0 means "no known expiry"
+
+	// TokenType is the issuer's token_type ("bearer", "mac", ...), empty if
+	// unknown. applyOAuth2Auth uses it to decide whether "Authorization:
+	// Bearer" is the right header, rather than assuming every token is one.
+	TokenType string
 }
 
-// NewAuthManager creates a new authentication manager
+// validWithSkew reports whether tc is still usable without needing a
+// refresh, given skew.
+func (tc *TokenCache) validWithSkew(skew time.Duration) bool {
+	if tc.ExpiresAt == 0 {
+		return true
+	}
+	return time.Now().Unix() < tc.ExpiresAt-int64(skew.Seconds())
+}
+
+// NewAuthManager creates a new authentication manager.
 func NewAuthManager() *AuthManager {
+	return NewAuthManagerWithClock(time.Now)
+}
+
+// NewAuthManagerWithClock is NewAuthManager with an injectable clock, so
+// tests can control JWKS refresh-cooldown behavior (see jwks_auth.go)
+// deterministically.
+func NewAuthManagerWithClock(now func() time.Time) *AuthManager {
 	return &AuthManager{
-		configs: make(map[int64]*AuthConfig),
-		tokens:  make(map[int64]*TokenCache),
+		configs:       make(map[int64]*AuthConfig),
+		RefreshSkew:   defaultTokenRefreshSkew,
+		tokenCache:    make(map[string]*TokenCache),
+		tlsConfigs:    make(map[string]*tls.Config),
+		rotatingCerts: make(map[string]*rotatingClientCert),
+		jwtSigners:    make(map[string]*jwtSigner),
+		oidcDiscovery: make(map[string]*oidcDiscoveryDocument),
+		jwksSets:      make(map[string]*jwksKeySet),
+		now:           now,
+		SecretManager: NewSecretManager(),
 	}
 }
 
@@ -42,54 +145,112 @@ func (am *AuthManager) SetAuthConfig(simulationID int64, config *AuthConfig) {
 	am.configs[simulationID] = config
 }
 
-// ApplyAuth applies authentication to an HTTP request
-func (am *AuthManager) ApplyAuth(req *http.Request, simulationID int64) error {
+// ClearAuthConfig removes authentication configuration for a simulation
+func (am *AuthManager) ClearAuthConfig(simulationID int64) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	delete(am.configs, simulationID)
+}
+
+// ApplyAuthForSimulation applies the authentication configuration
+// previously registered for simulationID via SetAuthConfig.
+func (am *AuthManager) ApplyAuthForSimulation(req *http.Request, simulationID int64) error {
+	return am.ApplyAuthForSimulationWithResolver(req, simulationID, nil)
+}
+
+// ApplyAuthForSimulationWithResolver is ApplyAuthForSimulation, plus a
+// VariableResolver so self-signed JWT minting (see applyJWTAuth) can expand
+// "{{username}}"-style templates in JWTAuth.ClaimTemplate. resolver may be
+// nil, in which case claim template values are used verbatim.
+func (am *AuthManager) ApplyAuthForSimulationWithResolver(req *http.Request, simulationID int64, resolver *VariableResolver) error {
 	am.mutex.RLock()
 	config, exists := am.configs[simulationID]
 	am.mutex.RUnlock()
 
-	if !exists || config.Type == AuthTypeNone {
+	if !exists {
+		return nil
+	}
+	return am.applyAuth(req, config, resolver, simulationID)
+}
+
+// ApplyAuth applies cfg's authentication to req. See
+// ApplyAuthWithResolver for the resolver-aware version used wherever a
+// simulation's VariableResolver is available.
+func (am *AuthManager) ApplyAuth(req *http.Request, cfg *AuthConfig) error {
+	return am.ApplyAuthWithResolver(req, cfg, nil)
+}
+
+// ApplyAuthWithResolver applies cfg's authentication to req. Static schemes
+// (bearer, basic, API key) are set directly; JWT and OAuth2 tokens are
+// served from cache or fetched/refreshed first (self-signed JWTs are minted
+// fresh every call using resolver, if set, to expand ClaimTemplate). AWS
+// SigV4 and HMAC sign the request as it stands, so callers must apply them
+// after the body and other headers are set. mTLS has nothing to add to the
+// request itself — it's applied at the transport level by
+// CreateHTTPClientWithAuth, independent of cfg.Type (see TLSConfig).
+func (am *AuthManager) ApplyAuthWithResolver(req *http.Request, cfg *AuthConfig, resolver *VariableResolver) error {
+	return am.applyAuth(req, cfg, resolver, 0)
+}
+
+// applyAuth is the shared implementation behind ApplyAuthWithResolver and
+// ApplyAuthForSimulationWithResolver. simulationID is only consulted by
+// OIDC-mode JWT auth (see applyJWTOIDCAuth), which caches its verified
+// token per simulation rather than per credential set; callers outside a
+// simulation context (e.g. TestAuth) pass 0.
+func (am *AuthManager) applyAuth(req *http.Request, cfg *AuthConfig, resolver *VariableResolver, simulationID int64) error {
+	if cfg == nil || cfg.Type == AuthTypeNone {
 		return nil
 	}
 
-	switch config.Type {
+	switch cfg.Type {
 	case AuthTypeBearer:
-		return am.applyBearerAuth(req, config.BearerToken)
-
+		return am.applyBearerAuth(req, cfg.BearerToken)
 	case AuthTypeBasic:
-		return am.applyBasicAuth(req, config.BasicAuth)
-
+		return am.applyBasicAuth(req, cfg.BasicAuth)
 	case AuthTypeAPIKey:
-		return am.applyAPIKeyAuth(req, config.APIKey)
-
+		return am.applyAPIKeyAuth(req, cfg.APIKey)
 	case AuthTypeJWT:
-		return am.applyJWTAuth(req, simulationID, config.JWT)
-
+		return am.applyJWTAuth(req, cfg.JWT, resolver, simulationID)
 	case AuthTypeOAuth2:
-		return am.applyOAuth2Auth(req, simulationID, config.OAuth2)
-
+		return am.applyOAuth2Auth(req, cfg.OAuth2)
+	case AuthTypeAWSSigV4:
+		return am.applyAWSSigV4Auth(req, cfg.AWSSigV4)
+	case AuthTypeHMAC:
+		return am.applyHMACAuth(req, cfg.HMAC)
 	default:
-		return fmt.Errorf("unsupported auth type: %s", config.Type)
+		return fmt.Errorf("unsupported auth type: %s", cfg.Type)
 	}
 }
 
-// applyBearerAuth applies bearer token authentication
+// applyBearerAuth applies bearer token authentication. auth.Token may be a
+// literal token or a "scheme://..." SecretManager reference.
 func (am *AuthManager) applyBearerAuth(req *http.Request, auth *BearerAuth) error {
 	if auth == nil || auth.Token == "" {
 		return fmt.Errorf("bearer token is required")
 	}
 
-	req.Header.Set("Authorization", "Bearer "+auth.Token)
+	token, err := am.SecretManager.Resolve(auth.Token)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
 	return nil
 }
 
-// applyBasicAuth applies basic authentication
+// applyBasicAuth applies basic authentication. auth.Password may be a
+// literal password or a "scheme://..." SecretManager reference.
 func (am *AuthManager) applyBasicAuth(req *http.Request, auth *BasicAuth) error {
 	if auth == nil || auth.Username == "" || auth.Password == "" {
 		return fmt.Errorf("username and password are required for basic auth")
 	}
 
-	credentials := auth.Username + ":" + auth.Password
+	password, err := am.SecretManager.Resolve(auth.Password)
+	if err != nil {
+		return err
+	}
+
+	credentials := auth.Username + ":" + password
 	encoded := base64.StdEncoding.EncodeToString([]byte(credentials))
 	req.Header.Set("Authorization", "Basic "+encoded)
 	return nil
@@ -115,214 +276,514 @@ func (am *AuthManager) applyAPIKeyAuth(req *http.Request, auth *APIKeyAuth) erro
 	return nil
 }
 
-// applyJWTAuth applies JWT authentication with token refresh support
-func (am *AuthManager) applyJWTAuth(req *http.Request, simulationID int64, auth *JWTAuth) error {
-	am.mutex.Lock()
-	defer am.mutex.Unlock()
+// getOrRefreshToken returns a valid cached token for cacheKey, calling fetch
+// to obtain one on first use or refresh to renew it once it is within
+// am.RefreshSkew of expiry (or has no cached entry has gone missing).
+// Concurrent callers for the same cacheKey collapse onto a single
+// in-flight fetch/refresh.
+func (am *AuthManager) getOrRefreshToken(cacheKey string, fetch func() (*TokenCache, error), refresh func(*TokenCache) (*TokenCache, error)) (*TokenCache, error) {
+	if cached, ok := am.cachedToken(cacheKey); ok && cached.validWithSkew(am.RefreshSkew) {
+		return cached, nil
+	}
+
+	result, err, _ := am.refreshGroup.Do(cacheKey, func() (interface{}, error) {
+		// Re-check: another goroutine may have refreshed this key while we
+		// were waiting to enter this singleflight call.
+		cached, hasCached := am.cachedToken(cacheKey)
+		if hasCached && cached.validWithSkew(am.RefreshSkew) {
+			return cached, nil
+		}
 
-	// Check if token needs refresh
-	if cached, exists := am.tokens[simulationID]; exists {
-		if cached.ExpiresAt > 0 && time.Now().Unix() >= cached.ExpiresAt-60 { // Refresh 1 minute before expiry
-			// Token is expired or about to expire, refresh it
-			if auth.RefreshToken != "" && auth.RefreshURL != "" {
-				newToken, expiresAt, err := am.refreshJWTToken(auth)
-				if err != nil {
-					return fmt.Errorf("failed to refresh JWT token: %v", err)
-				}
-
-				// Cache the new token
-				am.tokens[simulationID] = &TokenCache{
-					AccessToken:  newToken,
-					RefreshToken: auth.RefreshToken,
-					ExpiresAt:    expiresAt,
-				}
-
-				req.Header.Set("Authorization", "Bearer "+newToken)
-				return nil
-			}
+		var next *TokenCache
+		var err error
+		if hasCached {
+			next, err = refresh(cached)
 		} else {
-			// Use cached token
-			req.Header.Set("Authorization", "Bearer "+cached.AccessToken)
-			return nil
+			next, err = fetch()
+		}
+		if err != nil {
+			return nil, err
 		}
+
+		am.tokenMu.Lock()
+		am.tokenCache[cacheKey] = next
+		am.tokenMu.Unlock()
+		return next, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result.(*TokenCache), nil
+}
 
-	// Use static token
-	if auth.Token == "" {
-		return fmt.Errorf("JWT token is required")
+func (am *AuthManager) cachedToken(cacheKey string) (*TokenCache, bool) {
+	am.tokenMu.RLock()
+	defer am.tokenMu.RUnlock()
+	cached, ok := am.tokenCache[cacheKey]
+	return cached, ok
+}
+
+// jwtClaims is the subset of a JWT payload AuthManager reads to decide
+// whether a token is close to expiry.
+type jwtClaims struct {
+	Exp int64 `json:"exp"`
+}
+
+// decodeJWTExpiry reads the `exp` claim out of a JWT's payload segment
+// without verifying its signature — AuthManager only uses this to decide
+// when to refresh, not to authenticate the token itself.
+func decodeJWTExpiry(token string) (int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("not a JWT: expected 3 dot-separated segments, got %d", len(parts))
 	}
 
-	req.Header.Set("Authorization", "Bearer "+auth.Token)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("decode JWT payload: %w", err)
+	}
 
-	// Cache the token if expiry is specified
-	if auth.ExpiresAt > 0 {
-		am.tokens[simulationID] = &TokenCache{
-			AccessToken:  auth.Token,
-			RefreshToken: auth.RefreshToken,
-			ExpiresAt:    auth.ExpiresAt,
-		}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return 0, fmt.Errorf("parse JWT claims: %w", err)
 	}
+	return claims.Exp, nil
+}
 
-	return nil
+// jwtCacheKey identifies a JWT's refresh credentials for token caching.
+func jwtCacheKey(auth *JWTAuth) string {
+	return strings.Join([]string{"jwt", auth.RefreshURL, auth.RefreshToken}, "|")
 }
 
-// applyOAuth2Auth applies OAuth2 client credentials authentication
-func (am *AuthManager) applyOAuth2Auth(req *http.Request, simulationID int64, auth *OAuth2Auth) error {
-	am.mutex.Lock()
-	defer am.mutex.Unlock()
+// applyJWTAuth applies JWT authentication. When auth.Algorithm is set, it
+// mints and signs a fresh token for every request (see mintJWT); when
+// auth.OIDCIssuer or auth.JWKSURL is set, it fetches a token from a real
+// OIDC provider and verifies it against the provider's JWKS before
+// attaching it (see applyJWTOIDCAuth); otherwise it falls back to the
+// static-token flow, decoding the cached token's exp claim (falling back to
+// auth.ExpiresAt) to decide whether it needs refreshing via auth.RefreshURL
+// before use.
+func (am *AuthManager) applyJWTAuth(req *http.Request, auth *JWTAuth, resolver *VariableResolver, simulationID int64) error {
+	if auth == nil {
+		return fmt.Errorf("JWT auth configuration is required")
+	}
 
-	// Check if we have a cached, valid token
-	if cached, exists := am.tokens[simulationID]; exists {
-		if cached.ExpiresAt == 0 || time.Now().Unix() < cached.ExpiresAt-60 { // Token still valid
-			req.Header.Set("Authorization", "Bearer "+cached.AccessToken)
-			return nil
-		}
+	if auth.Algorithm != "" {
+		return am.mintJWT(req, auth, resolver)
+	}
+
+	if auth.OIDCIssuer != "" || auth.JWKSURL != "" {
+		return am.applyJWTOIDCAuth(req, auth, simulationID)
 	}
 
-	// Need to get a new token
-	token, expiresAt, err := am.getOAuth2Token(auth)
+	token, err := am.getOrRefreshToken(
+		jwtCacheKey(auth),
+		func() (*TokenCache, error) { return am.seedJWTToken(auth) },
+		func(cached *TokenCache) (*TokenCache, error) { return am.refreshJWTToken(auth, cached) },
+	)
 	if err != nil {
-		return fmt.Errorf("failed to get OAuth2 token: %v", err)
+		return fmt.Errorf("JWT token: %w", err)
 	}
 
-	// Cache the new token
-	am.tokens[simulationID] = &TokenCache{
-		AccessToken: token,
-		ExpiresAt:   expiresAt,
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return nil
+}
+
+// mintJWT builds, signs, and attaches a fresh self-signed JWT per auth's
+// claim template and algorithm. The parsed signing key is cached (see
+// jwtSignerFor), so only the claim-building and signing happen per request.
+func (am *AuthManager) mintJWT(req *http.Request, auth *JWTAuth, resolver *VariableResolver) error {
+	signer, err := am.jwtSignerFor(auth)
+	if err != nil {
+		return fmt.Errorf("JWT signer: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+token)
+	token, _, err := signer.mint(auth, resolver)
+	if err != nil {
+		return fmt.Errorf("mint JWT: %w", err)
+	}
+
+	headerName := auth.HeaderName
+	if headerName == "" {
+		headerName = "Authorization"
+	}
+	headerPrefix := auth.HeaderPrefix
+	if headerPrefix == "" && headerName == "Authorization" {
+		headerPrefix = "Bearer "
+	}
+
+	req.Header.Set(headerName, headerPrefix+token)
 	return nil
 }
 
-// refreshJWTToken refreshes a JWT token using the refresh token
-func (am *AuthManager) refreshJWTToken(auth *JWTAuth) (string, int64, error) {
-	payload := map[string]string{
-		"refresh_token": auth.RefreshToken,
+// jwtSignerCacheKey identifies a JWT signing-key configuration, so
+// simulations sharing one signing key reuse a single parsed jwtSigner.
+func jwtSignerCacheKey(auth *JWTAuth) string {
+	return strings.Join([]string{auth.Algorithm, auth.SigningKey, auth.KeyID}, "|")
+}
+
+// jwtSignerFor returns the cached jwtSigner for auth's signing configuration,
+// parsing and caching a new one on first use.
+func (am *AuthManager) jwtSignerFor(auth *JWTAuth) (*jwtSigner, error) {
+	key := jwtSignerCacheKey(auth)
+
+	am.jwtMu.Lock()
+	defer am.jwtMu.Unlock()
+
+	if cached, ok := am.jwtSigners[key]; ok {
+		return cached, nil
 	}
 
-	jsonPayload, err := json.Marshal(payload)
+	signer, err := newJWTSigner(auth)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
+	am.jwtSigners[key] = signer
+	return signer, nil
+}
 
-	resp, err := http.Post(auth.RefreshURL, "application/json", bytes.NewBuffer(jsonPayload))
+// seedJWTToken builds the initial TokenCache entry from auth's static
+// token, determining its expiry from the token itself when auth.ExpiresAt
+// isn't set. A token that is already within the refresh skew window is
+// refreshed immediately rather than handed out stale, since getOrRefreshToken
+// only rechecks freshness on cache hits, not on the first seed.
+func (am *AuthManager) seedJWTToken(auth *JWTAuth) (*TokenCache, error) {
+	if auth.Token == "" {
+		return nil, fmt.Errorf("JWT token is required")
+	}
+
+	expiresAt := auth.ExpiresAt
+	if expiresAt == 0 {
+		if exp, err := decodeJWTExpiry(auth.Token); err == nil {
+			expiresAt = exp
+		}
+	}
+
+	seeded := &TokenCache{AccessToken: auth.Token, RefreshToken: auth.RefreshToken, ExpiresAt: expiresAt}
+	if !seeded.validWithSkew(am.RefreshSkew) {
+		return am.refreshJWTToken(auth, seeded)
+	}
+	return seeded, nil
+}
+
+// refreshJWTToken POSTs auth's refresh token to auth.RefreshURL and updates
+// auth in place with the response, so subsequent reads of the JWTAuth
+// struct (e.g. for persistence) see the latest token.
+func (am *AuthManager) refreshJWTToken(auth *JWTAuth, cached *TokenCache) (*TokenCache, error) {
+	refreshToken := cached.RefreshToken
+	if refreshToken == "" {
+		refreshToken = auth.RefreshToken
+	}
+	if auth.RefreshURL == "" || refreshToken == "" {
+		// Nothing to refresh with; keep using the existing token.
+		return cached, nil
+	}
+
+	payload, err := json.Marshal(map[string]string{"refresh_token": refreshToken})
 	if err != nil {
-		return "", 0, err
+		return nil, err
+	}
+
+	resp, err := http.Post(auth.RefreshURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("refresh request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", 0, fmt.Errorf("token refresh failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("refresh request failed with status %d", resp.StatusCode)
 	}
 
 	var result struct {
-		AccessToken string `json:"access_token"`
-		ExpiresIn   int64  `json:"expires_in"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
 	}
-
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", 0, err
+		return nil, fmt.Errorf("decode refresh response: %w", err)
 	}
 
-	expiresAt := time.Now().Unix() + result.ExpiresIn
-	return result.AccessToken, expiresAt, nil
+	next := &TokenCache{AccessToken: result.AccessToken, RefreshToken: refreshToken}
+	if result.RefreshToken != "" {
+		next.RefreshToken = result.RefreshToken
+	}
+	if result.ExpiresIn > 0 {
+		next.ExpiresAt = time.Now().Unix() + result.ExpiresIn
+	} else if exp, err := decodeJWTExpiry(next.AccessToken); err == nil {
+		next.ExpiresAt = exp
+	}
+
+	auth.Token = next.AccessToken
+	auth.RefreshToken = next.RefreshToken
+	auth.ExpiresAt = next.ExpiresAt
+
+	return next, nil
 }
 
-// getOAuth2Token gets an OAuth2 access token using client credentials
-func (am *AuthManager) getOAuth2Token(auth *OAuth2Auth) (string, int64, error) {
-	payload := map[string]string{
-		"grant_type":    "client_credentials",
-		"client_id":     auth.ClientID,
-		"client_secret": auth.ClientSecret,
-	}
+// oauth2CacheKey identifies an OAuth2 credential set for token caching, so
+// two simulations configured with the same client/token URL/scope share
+// one cached token instead of each fetching their own.
+func oauth2CacheKey(auth *OAuth2Auth) string {
+	return strings.Join([]string{"oauth2", auth.ClientID, auth.TokenURL, auth.Scope}, "|")
+}
 
-	if auth.Scope != "" {
-		payload["scope"] = auth.Scope
+// applyOAuth2Auth applies OAuth2 authentication, fetching (and later
+// refreshing, with refresh-token rotation) a token per oauth2CacheKey. The
+// Authorization header is only set to "Bearer " when the issued token's
+// type calls for it (see bearerHeaderValue).
+func (am *AuthManager) applyOAuth2Auth(req *http.Request, auth *OAuth2Auth) error {
+	if auth == nil {
+		return fmt.Errorf("OAuth2 auth configuration is required")
 	}
 
-	jsonPayload, err := json.Marshal(payload)
+	fetch := func() (*TokenCache, error) { return am.fetchOAuth2Token(auth) }
+	refresh := func(cached *TokenCache) (*TokenCache, error) { return am.refreshOAuth2Token(auth, cached) }
+	token, err := am.getOrRefreshToken(oauth2CacheKey(auth), fetch, refresh)
 	if err != nil {
-		return "", 0, err
+		return fmt.Errorf("OAuth2 token: %w", err)
 	}
 
-	resp, err := http.Post(auth.TokenURL, "application/json", bytes.NewBuffer(jsonPayload))
+	auth.AccessToken = token.AccessToken
+	auth.ExpiresAt = token.ExpiresAt
+	if token.RefreshToken != "" {
+		auth.RefreshToken = token.RefreshToken
+	}
+
+	req.Header.Set("Authorization", bearerHeaderValue(token))
+	return nil
+}
+
+// bearerHeaderValue returns the Authorization header value for token,
+// honoring its token_type: "Bearer " for the common "bearer" type (or an
+// unspecified one), or the issuer's own type as the prefix otherwise (e.g.
+// a "MAC" token), so a non-bearer scheme isn't mislabeled.
+func bearerHeaderValue(token *TokenCache) string {
+	if token.TokenType == "" || strings.EqualFold(token.TokenType, "bearer") {
+		return "Bearer " + token.AccessToken
+	}
+	return token.TokenType + " " + token.AccessToken
+}
+
+// refreshOAuth2Token renews auth's token using cached's refresh token if
+// present (refresh-token rotation: a provider-issued replacement refresh
+// token must be used on the next renewal, not the original static one),
+// falling back to auth.RefreshToken, or to a plain re-fetch for grants (like
+// client_credentials) that have no refresh token at all.
+func (am *AuthManager) refreshOAuth2Token(auth *OAuth2Auth, cached *TokenCache) (*TokenCache, error) {
+	refreshToken := cached.RefreshToken
+	if refreshToken == "" {
+		refreshToken = auth.RefreshToken
+	}
+	if refreshToken == "" {
+		return am.fetchOAuth2Token(auth)
+	}
+
+	rotated := *auth
+	rotated.GrantType = "refresh_token"
+	rotated.RefreshToken = refreshToken
+	return am.fetchOAuth2Token(&rotated)
+}
+
+// fetchOAuth2Token requests a fresh access token for auth, delegating to
+// am.OAuth2TokenFetcher if set (see OAuth2TokenFetcher), or
+// defaultOAuth2TokenFetcher otherwise. auth.ClientSecret is resolved
+// through am.SecretManager first, so a "scheme://..." reference there
+// doesn't need to be understood by the fetcher itself.
+func (am *AuthManager) fetchOAuth2Token(auth *OAuth2Auth) (*TokenCache, error) {
+	clientSecret, err := am.SecretManager.Resolve(auth.ClientSecret)
 	if err != nil {
-		return "", 0, err
+		return nil, err
+	}
+	if clientSecret != auth.ClientSecret {
+		resolved := *auth
+		resolved.ClientSecret = clientSecret
+		auth = &resolved
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", 0, fmt.Errorf("OAuth2 token request failed with status: %d", resp.StatusCode)
+	fetcher := am.OAuth2TokenFetcher
+	if fetcher == nil {
+		fetcher = defaultOAuth2TokenFetcher{}
 	}
+	return fetcher.FetchOAuth2Token(context.Background(), auth)
+}
 
-	var result struct {
-		AccessToken string `json:"access_token"`
-		ExpiresIn   int64  `json:"expires_in"`
+// defaultOAuth2TokenFetcher implements OAuth2TokenFetcher against a real
+// token endpoint via golang.org/x/oauth2, which sends RFC 6749
+// application/x-www-form-urlencoded request bodies for every grant.
+type defaultOAuth2TokenFetcher struct{}
+
+// oauth2AuthStyle maps OAuth2Auth.AuthStyle to the oauth2 package's enum.
+func oauth2AuthStyle(style string) oauth2.AuthStyle {
+	switch style {
+	case "header":
+		return oauth2.AuthStyleInHeader
+	case "params":
+		return oauth2.AuthStyleInParams
+	default:
+		return oauth2.AuthStyleAutoDetect
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", 0, err
+// FetchOAuth2Token requests a fresh access token for auth. The default
+// "client_credentials" grant goes through golang.org/x/oauth2/
+// clientcredentials; the other supported grants ("password",
+// "authorization_code", "refresh_token") go through golang.org/x/oauth2's
+// generic Config, since clientcredentials.Config only implements the one
+// grant.
+func (defaultOAuth2TokenFetcher) FetchOAuth2Token(ctx context.Context, auth *OAuth2Auth) (*TokenCache, error) {
+	var scopes []string
+	if auth.Scope != "" {
+		scopes = strings.Fields(auth.Scope)
 	}
 
-	var expiresAt int64
-	if result.ExpiresIn > 0 {
-		expiresAt = time.Now().Unix() + result.ExpiresIn
+	switch auth.GrantType {
+	case "", "client_credentials":
+		cfg := &clientcredentials.Config{
+			ClientID:     auth.ClientID,
+			ClientSecret: auth.ClientSecret,
+			TokenURL:     auth.TokenURL,
+			Scopes:       scopes,
+			AuthStyle:    oauth2AuthStyle(auth.AuthStyle),
+		}
+		token, err := cfg.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("client_credentials grant: %w", err)
+		}
+		return tokenCacheFromOAuth2Token(token), nil
+
+	case "password":
+		cfg := &oauth2.Config{
+			ClientID:     auth.ClientID,
+			ClientSecret: auth.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: auth.TokenURL, AuthStyle: oauth2AuthStyle(auth.AuthStyle)},
+			Scopes:       scopes,
+		}
+		token, err := cfg.PasswordCredentialsToken(ctx, auth.Username, auth.Password)
+		if err != nil {
+			return nil, fmt.Errorf("password grant: %w", err)
+		}
+		return tokenCacheFromOAuth2Token(token), nil
+
+	case "authorization_code":
+		cfg := &oauth2.Config{
+			ClientID:     auth.ClientID,
+			ClientSecret: auth.ClientSecret,
+			RedirectURL:  auth.RedirectURL,
+			Endpoint:     oauth2.Endpoint{TokenURL: auth.TokenURL, AuthStyle: oauth2AuthStyle(auth.AuthStyle)},
+			Scopes:       scopes,
+		}
+		var opts []oauth2.AuthCodeOption
+		if auth.CodeVerifier != "" {
+			opts = append(opts, oauth2.SetAuthURLParam("code_verifier", auth.CodeVerifier))
+		}
+		token, err := cfg.Exchange(ctx, auth.AuthorizationCode, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("authorization_code grant: %w", err)
+		}
+		return tokenCacheFromOAuth2Token(token), nil
+
+	case "refresh_token":
+		cfg := &oauth2.Config{
+			ClientID:     auth.ClientID,
+			ClientSecret: auth.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: auth.TokenURL, AuthStyle: oauth2AuthStyle(auth.AuthStyle)},
+			Scopes:       scopes,
+		}
+		source := cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: auth.RefreshToken})
+		token, err := source.Token()
+		if err != nil {
+			return nil, fmt.Errorf("refresh_token grant: %w", err)
+		}
+		return tokenCacheFromOAuth2Token(token), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported OAuth2 grant type: %q", auth.GrantType)
+	}
+}
+
+func tokenCacheFromOAuth2Token(token *oauth2.Token) *TokenCache {
+	tc := &TokenCache{AccessToken: token.AccessToken, RefreshToken: token.RefreshToken, TokenType: token.TokenType}
+	if !token.Expiry.IsZero() {
+		tc.ExpiresAt = token.Expiry.Unix()
 	}
+	return tc
+}
 
-	return result.AccessToken, expiresAt, nil
+// GeneratePKCEPair returns a random RFC 7636 code_verifier and its S256
+// code_challenge, for callers building an authorization URL for the
+// "authorization_code" grant with OAuth2Auth.UsePKCE set. The verifier
+// should be stashed (e.g. alongside the "state" param) and set as
+// OAuth2Auth.CodeVerifier once the authorization code comes back.
+func GeneratePKCEPair() (verifier string, challenge string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("generate PKCE verifier: %w", err)
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(raw)
+
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier, challenge, nil
 }
 
-// CreateHTTPClientWithAuth creates an HTTP client with client certificate authentication if needed
-func (am *AuthManager) CreateHTTPClientWithAuth(config *AuthConfig) (*http.Client, error) {
+// CreateHTTPClientWithAuth creates an HTTP client, wiring up tlsCfg's
+// transport-level TLS material (if any) — independent of whatever
+// header-based AuthConfig is also applied to each request via ApplyAuth —
+// reusing a cached *tls.Config so a given cert/key/CA combination is parsed
+// only once.
+func (am *AuthManager) CreateHTTPClientWithAuth(tlsCfg *TLSConfig) (*http.Client, error) {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
 
-	if config != nil && config.Type == AuthTypeClientCert && config.ClientCert != nil {
-		tlsConfig, err := am.loadClientCertificate(config.ClientCert)
+	if tlsCfg != nil {
+		transportTLSConfig, err := am.tlsConfigFor(tlsCfg)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+			return nil, fmt.Errorf("failed to load TLS configuration: %v", err)
 		}
 
-		transport := &http.Transport{
-			TLSClientConfig: tlsConfig,
+		client.Transport = &http.Transport{
+			TLSClientConfig: transportTLSConfig,
 		}
-		client.Transport = transport
 	}
 
 	return client, nil
 }
 
-// loadClientCertificate loads client certificate for mutual TLS authentication
-func (am *AuthManager) loadClientCertificate(auth *ClientCertAuth) (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(auth.CertFile, auth.KeyFile)
-	if err != nil {
-		return nil, err
-	}
+func tlsConfigCacheKey(tlsCfg *TLSConfig) string {
+	return strings.Join([]string{
+		tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.KeyPassphrase, tlsCfg.CAFile, tlsCfg.CABundle,
+		tlsCfg.P12File, tlsCfg.P12Password, tlsCfg.ServerName, tlsCfg.MinVersion,
+		strings.Join(tlsCfg.CipherSuites, ","), tlsCfg.SPIFFEID,
+		fmt.Sprintf("%t", tlsCfg.InsecureSkipVerify), tlsCfg.ReloadInterval.String(),
+	}, "|")
+}
 
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
+// tlsConfigFor loads and caches the *tls.Config for tlsCfg, so repeated
+// requests (and repeated simulations) using the same TLS configuration
+// reuse one rotatingClientCert and its background reload goroutine (see
+// mtls.go) instead of starting a new one per request.
+func (am *AuthManager) tlsConfigFor(tlsCfg *TLSConfig) (*tls.Config, error) {
+	key := tlsConfigCacheKey(tlsCfg)
+
+	am.tlsMu.Lock()
+	defer am.tlsMu.Unlock()
+
+	if cached, ok := am.tlsConfigs[key]; ok {
+		return cached, nil
 	}
 
-	if auth.CAFile != "" {
-		caCert, err := ioutil.ReadFile(auth.CAFile)
+	rc, ok := am.rotatingCerts[key]
+	if !ok {
+		var err error
+		rc, err = newRotatingClientCert(tlsCfg, am.SecretManager)
 		if err != nil {
 			return nil, err
 		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tlsConfig.RootCAs = caCertPool
+		am.rotatingCerts[key] = rc
 	}
 
+	tlsConfig := tlsConfigFromRotating(rc, tlsCfg)
+	am.tlsConfigs[key] = tlsConfig
 	return tlsConfig, nil
 }
-
-// ClearAuthConfig removes authentication configuration for a simulation
-func (am *AuthManager) ClearAuthConfig(simulationID int64) {
-	am.mutex.Lock()
-	defer am.mutex.Unlock()
-	delete(am.configs, simulationID)
-	delete(am.tokens, simulationID)
-}
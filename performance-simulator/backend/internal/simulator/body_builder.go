@@ -6,30 +6,34 @@ import (
 	"io"
 	"mime/multipart"
 	"net/url"
+	"os"
 	"strings"
 )
 
-// buildRequestBody builds the appropriate request body based on the body type
-func (e *Engine) buildRequestBody(config *SimulationConfig) (io.Reader, string, error) {
+// buildRequestBody builds the appropriate request body based on the body
+// type, returning its reader, Content-Type, and Content-Length (-1 if the
+// length can't be determined ahead of time, which leaves the request to be
+// sent with chunked transfer encoding).
+func (e *Engine) buildRequestBody(config *SimulationConfig) (io.Reader, string, int64, error) {
 	if config.Body == nil {
-		return nil, "", nil
+		return nil, "", 0, nil
 	}
 
 	switch config.Body.Type {
 	case BodyTypeNone:
-		return nil, "", nil
+		return nil, "", 0, nil
 
 	case BodyTypeJSON:
 		body := strings.NewReader(config.Body.Content)
-		return body, "application/json", nil
+		return body, "application/json", int64(body.Len()), nil
 
 	case BodyTypeForm:
 		data := url.Values{}
 		for key, value := range config.Body.FormData {
 			data.Set(key, value)
 		}
-		body := strings.NewReader(data.Encode())
-		return body, "application/x-www-form-urlencoded", nil
+		encoded := data.Encode()
+		return strings.NewReader(encoded), "application/x-www-form-urlencoded", int64(len(encoded)), nil
 
 	case BodyTypeMultipart:
 		return e.buildMultipartBody(config)
@@ -40,49 +44,190 @@ func (e *Engine) buildRequestBody(config *SimulationConfig) (io.Reader, string,
 		if contentType == "" {
 			contentType = "text/plain"
 		}
-		return body, contentType, nil
+		return body, contentType, int64(body.Len()), nil
 
 	case BodyTypeXML:
 		body := strings.NewReader(config.Body.Content)
-		return body, "application/xml", nil
+		return body, "application/xml", int64(body.Len()), nil
+
+	case BodyTypeStream:
+		return e.buildStreamBody(config)
 
 	default:
-		return nil, "", fmt.Errorf("unsupported body type: %s", config.Body.Type)
+		return nil, "", 0, fmt.Errorf("unsupported body type: %s", config.Body.Type)
 	}
 }
 
-// buildMultipartBody builds a multipart form data body
-func (e *Engine) buildMultipartBody(config *SimulationConfig) (io.Reader, string, error) {
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
+// buildStreamBody invokes config.Body.Generator to produce a body without
+// the engine ever materializing it itself, for synthetic payloads a caller
+// doesn't want allocated up front (see StreamBodyGenerator).
+func (e *Engine) buildStreamBody(config *SimulationConfig) (io.Reader, string, int64, error) {
+	if config.Body.Generator == nil {
+		return nil, "", 0, fmt.Errorf("stream body requires a Generator")
+	}
 
-	// Add form fields
-	for key, value := range config.Body.FormData {
-		err := writer.WriteField(key, value)
-		if err != nil {
-			return nil, "", err
+	reader, size, err := config.Body.Generator()
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("generate stream body: %w", err)
+	}
+
+	contentType := config.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return reader, contentType, size, nil
+}
+
+// buildMultipartBody streams a multipart form-data body rather than
+// buffering it in a bytes.Buffer: a goroutine writes fields and copies each
+// file straight from its source (disk, an io.Reader, or an in-memory
+// Content) into the multipart writer, which in turn writes into the write
+// end of an io.Pipe whose read end is what's returned here, so a
+// multi-gigabyte file upload never needs its own copy held in RAM. The
+// Content-Length is computed up front from the same framing multipart.Writer
+// produces, as long as every file's size is known ahead of time (Content or
+// a stat'able FilePath); it's left at -1 (chunked) the moment any file's
+// size can't be determined without reading it, e.g. a caller-supplied
+// Reader.
+func (e *Engine) buildMultipartBody(config *SimulationConfig) (io.Reader, string, int64, error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	contentLength, err := multipartContentLength(config.Body, writer.Boundary())
+	if err != nil {
+		contentLength = -1
+	}
+
+	go func() {
+		writeErr := writeMultipartBody(writer, config.Body)
+		pw.CloseWithError(writeErr)
+	}()
+
+	return pr, writer.FormDataContentType(), contentLength, nil
+}
+
+// writeMultipartBody writes body's fields and files into writer, streaming
+// each file's source straight through rather than loading it whole. Closing
+// writer emits the multipart trailing boundary; the caller is responsible
+// for closing the underlying pipe with whatever error (nil included) this
+// returns.
+func writeMultipartBody(writer *multipart.Writer, body *RequestBody) error {
+	for key, value := range body.FormData {
+		if err := writer.WriteField(key, value); err != nil {
+			return fmt.Errorf("write form field %q: %w", key, err)
 		}
 	}
 
-	// Add files
-	for _, file := range config.Body.Files {
+	for _, file := range body.Files {
 		part, err := writer.CreateFormFile(file.FieldName, file.FileName)
 		if err != nil {
-			return nil, "", err
+			return fmt.Errorf("create form file %q: %w", file.FieldName, err)
 		}
 
-		_, err = part.Write(file.Content)
+		src, err := openFileUploadSource(file)
 		if err != nil {
-			return nil, "", err
+			return err
+		}
+		_, copyErr := io.Copy(part, src)
+		closeErr := src.Close()
+		if copyErr != nil {
+			return fmt.Errorf("stream file %q: %w", file.FieldName, copyErr)
+		}
+		if closeErr != nil {
+			return fmt.Errorf("close file %q: %w", file.FieldName, closeErr)
 		}
 	}
 
-	err := writer.Close()
-	if err != nil {
-		return nil, "", err
+	return writer.Close()
+}
+
+// openFileUploadSource returns file's content as a stream, preferring
+// FilePath (opened from disk), then Reader, and finally falling back to the
+// in-memory Content, in that order.
+func openFileUploadSource(file FileUpload) (io.ReadCloser, error) {
+	switch {
+	case file.FilePath != "":
+		f, err := os.Open(file.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("open file %q: %w", file.FilePath, err)
+		}
+		return f, nil
+	case file.Reader != nil:
+		return io.NopCloser(file.Reader), nil
+	default:
+		return io.NopCloser(bytes.NewReader(file.Content)), nil
 	}
+}
 
-	return &buf, writer.FormDataContentType(), nil
+// fileUploadSize returns file's size and whether it could be determined
+// without reading the whole file: Content's length is always known,
+// FilePath's is stat'd from disk, and a Reader's is unknowable ahead of
+// time.
+func fileUploadSize(file FileUpload) (int64, bool) {
+	switch {
+	case file.FilePath != "":
+		info, err := os.Stat(file.FilePath)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size(), true
+	case file.Reader != nil:
+		return 0, false
+	default:
+		return int64(len(file.Content)), true
+	}
+}
+
+// multipartContentLength computes the exact byte length a multipart body
+// with the given boundary will have, without reading any file's content: it
+// runs the real multipart.Writer machinery (so the part framing it produces
+// is guaranteed byte-for-byte identical to writeMultipartBody's) against a
+// discarding counter, substituting each file's already-known size for its
+// actual bytes. Returns an error the moment any file's size isn't known
+// ahead of time, since a byte count that omits a part would be worse than
+// no Content-Length at all.
+func multipartContentLength(body *RequestBody, boundary string) (int64, error) {
+	counter := &byteCounter{}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return 0, fmt.Errorf("set boundary: %w", err)
+	}
+
+	for key, value := range body.FormData {
+		if err := writer.WriteField(key, value); err != nil {
+			return 0, fmt.Errorf("write form field %q: %w", key, err)
+		}
+	}
+
+	for _, file := range body.Files {
+		size, ok := fileUploadSize(file)
+		if !ok {
+			return 0, fmt.Errorf("file %q has no statically known size", file.FieldName)
+		}
+		if _, err := writer.CreateFormFile(file.FieldName, file.FileName); err != nil {
+			return 0, fmt.Errorf("create form file %q: %w", file.FieldName, err)
+		}
+		counter.n += size
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, fmt.Errorf("close writer: %w", err)
+	}
+
+	return counter.n, nil
+}
+
+// byteCounter is an io.Writer that only tracks how many bytes it was
+// asked to write, discarding the data itself; used by
+// multipartContentLength to size a multipart body without holding it in
+// memory.
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
 }
 
 // buildFormData builds URL-encoded form data
@@ -111,6 +256,11 @@ func (e *Engine) getContentType(bodyType BodyType, customContentType string) str
 	case BodyTypeMultipart:
 		// This will be set by the multipart writer
 		return ""
+	case BodyTypeStream:
+		if customContentType != "" {
+			return customContentType
+		}
+		return "application/octet-stream"
 	default:
 		return ""
 	}
@@ -122,6 +272,10 @@ func (e *Engine) validateBody(config *SimulationConfig) error {
 		return nil
 	}
 
+	if err := validateBodyTemplates(config.Body); err != nil {
+		return err
+	}
+
 	switch config.Body.Type {
 	case BodyTypeNone:
 		return nil
@@ -143,6 +297,11 @@ func (e *Engine) validateBody(config *SimulationConfig) error {
 		if len(config.Body.FormData) == 0 && len(config.Body.Files) == 0 {
 			return fmt.Errorf("multipart body must have either form data or files")
 		}
+		for _, file := range config.Body.Files {
+			if file.FilePath == "" && file.Reader == nil && len(file.Content) == 0 {
+				return fmt.Errorf("file %q must set one of content, file_path, or a reader", file.FieldName)
+			}
+		}
 		return nil
 
 	case BodyTypeRaw:
@@ -158,7 +317,48 @@ func (e *Engine) validateBody(config *SimulationConfig) error {
 		// TODO: Add XML validation if needed
 		return nil
 
+	case BodyTypeStream:
+		if config.Body.Generator == nil {
+			return fmt.Errorf("stream body requires a Generator")
+		}
+		return nil
+
 	default:
 		return fmt.Errorf("unsupported body type: %s", config.Body.Type)
 	}
 }
+
+// validateBodyTemplates dry-runs a syntax check over every {{expr}}
+// placeholder in body's Content and FormData, so a malformed expression
+// (an unterminated quote, a missing function name, an unbalanced pipeline)
+// is reported when the simulation is created or updated rather than only
+// when a request first tries to resolve it. It only parses each
+// expression, not evaluate it, since evaluating e.g. a {{csv}} call would
+// require the data feeder/VU context that only exists once the simulation
+// is actually running.
+func validateBodyTemplates(body *RequestBody) error {
+	if err := validateTemplateSyntax(body.Content); err != nil {
+		return err
+	}
+	for key, value := range body.FormData {
+		if err := validateTemplateSyntax(key); err != nil {
+			return err
+		}
+		if err := validateTemplateSyntax(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTemplateSyntax parses (without evaluating) every {{expr}}
+// placeholder found in content, returning the first parse error encountered.
+func validateTemplateSyntax(content string) error {
+	for _, match := range templatePattern.FindAllStringSubmatch(content, -1) {
+		raw := strings.TrimSpace(match[1])
+		if _, err := parseTemplateExpr(raw); err != nil {
+			return fmt.Errorf("invalid template expression %q: %w", raw, err)
+		}
+	}
+	return nil
+}
@@ -1,5 +1,10 @@
 package simulator
 
+import (
+	"io"
+	"time"
+)
+
 // Request body types and structures for enhanced HTTP client support
 
 // BodyType defines different types of request bodies
@@ -12,25 +17,72 @@ const (
 	BodyTypeMultipart BodyType = "multipart"
 	BodyTypeRaw       BodyType = "raw"
 	BodyTypeXML       BodyType = "xml"
+
+	// BodyTypeGRPC and BodyTypeGraphQL describe a request body shaped for
+	// those protocols (the JSON that's marshaled into the gRPC request
+	// message, or the GraphQL query/variables payload). They're set for
+	// documentation/display purposes on RequestBody; the actual request is
+	// sent by GRPCDriver/GraphQLDriver (protocol.go), selected by
+	// SimulationConfig.Protocol rather than by Body.Type, since a
+	// simulation's protocol also determines things Body.Type alone
+	// couldn't (the gRPC method descriptor, the GraphQL envelope) — see
+	// GRPCConfig/GraphQLConfig.
+	BodyTypeGRPC    BodyType = "grpc"
+	BodyTypeGraphQL BodyType = "graphql"
+
+	// BodyTypeStream takes its body entirely from RequestBody.Generator
+	// rather than Content/Files/FormData, for synthetic payloads (e.g.
+	// deterministic PRNG bytes for an upload benchmark) a caller doesn't
+	// want materialized in memory up front. See buildRequestBody.
+	BodyTypeStream BodyType = "stream"
 )
 
+// StreamBodyGenerator produces a request body on demand, one call per
+// request: it returns the body's reader, its total size in bytes (or -1 if
+// the size isn't known ahead of time, which forces chunked transfer
+// encoding), and any error preparing it. Set on RequestBody.Generator
+// together with BodyTypeStream. It can't be serialized to JSON or
+// persisted, so it must be set by code constructing a SimulationConfig
+// in-process rather than through the HTTP API.
+type StreamBodyGenerator func() (io.ReadCloser, int64, error)
+
 // RequestBody represents the structure of a request body
 type RequestBody struct {
-	Type     BodyType              `json:"type"`
-	Content  string                `json:"content"`
-	Files    []FileUpload          `json:"files,omitempty"`
-	FormData map[string]string     `json:"form_data,omitempty"`
+	Type     BodyType          `json:"type"`
+	Content  string            `json:"content"`
+	Files    []FileUpload      `json:"files,omitempty"`
+	FormData map[string]string `json:"form_data,omitempty"`
+
+	// Generator backs BodyTypeStream; see StreamBodyGenerator.
+	Generator StreamBodyGenerator `json:"-"`
 }
 
-// FileUpload represents a file to be uploaded in multipart requests
+// FileUpload represents a file to be uploaded in multipart requests. Its
+// content comes from exactly one of Content, FilePath, or Reader: Content
+// is fine for small payloads, but buildMultipartBody streams FilePath/
+// Reader straight to the wire so a multi-gigabyte upload never has to fit
+// in memory.
 type FileUpload struct {
 	FieldName string `json:"field_name"`
 	FileName  string `json:"file_name"`
 	Content   []byte `json:"content"`
 	MimeType  string `json:"mime_type"`
+
+	// FilePath, if set, is opened and streamed from disk instead of using
+	// Content, so a large upload is never read fully into RAM.
+	FilePath string `json:"file_path,omitempty"`
+
+	// Reader, if set, is streamed the same way FilePath is, for a caller
+	// that already has an io.Reader (e.g. a generated payload) rather than
+	// a path on disk. Like Generator, it isn't JSON-serializable and must
+	// be set in-process.
+	Reader io.Reader `json:"-"`
 }
 
-// AuthConfig represents authentication configuration
+// AuthConfig represents authentication configuration. mTLS is not one of
+// these schemes — it's transport-level, configured independently via
+// SimulationConfig.TLSConfig, and composes with whichever of these (if any)
+// is also set.
 type AuthConfig struct {
 	Type         AuthType          `json:"type"`
 	BearerToken  *BearerAuth      `json:"bearer_token,omitempty"`
@@ -38,20 +90,22 @@ type AuthConfig struct {
 	APIKey       *APIKeyAuth      `json:"api_key,omitempty"`
 	JWT          *JWTAuth         `json:"jwt,omitempty"`
 	OAuth2       *OAuth2Auth      `json:"oauth2,omitempty"`
-	ClientCert   *ClientCertAuth  `json:"client_cert,omitempty"`
+	AWSSigV4     *AWSSigV4Auth    `json:"aws_sigv4,omitempty"`
+	HMAC         *HMACAuth        `json:"hmac,omitempty"`
 }
 
 // AuthType defines different authentication methods
 type AuthType string
 
 const (
-	AuthTypeNone       AuthType = "none"
-	AuthTypeBearer     AuthType = "bearer"
-	AuthTypeBasic      AuthType = "basic"
-	AuthTypeAPIKey     AuthType = "apikey"
-	AuthTypeJWT        AuthType = "jwt"
-	AuthTypeOAuth2     AuthType = "oauth2"
-	AuthTypeClientCert AuthType = "client_cert"
+	AuthTypeNone     AuthType = "none"
+	AuthTypeBearer   AuthType = "bearer"
+	AuthTypeBasic    AuthType = "basic"
+	AuthTypeAPIKey   AuthType = "apikey"
+	AuthTypeJWT      AuthType = "jwt"
+	AuthTypeOAuth2   AuthType = "oauth2"
+	AuthTypeAWSSigV4 AuthType = "aws_sigv4"
+	AuthTypeHMAC     AuthType = "hmac"
 )
 
 // BearerAuth represents Bearer token authentication
@@ -72,27 +126,206 @@ type APIKeyAuth struct {
 	Location string `json:"location"` // "header" or "query"
 }
 
-// JWTAuth represents JWT token authentication with refresh capability
+// JWTAuth represents JWT token authentication. It supports two modes: a
+// static pre-minted Token (optionally kept fresh via RefreshURL/RefreshToken,
+// as before), or self-signed minting when Algorithm is set, in which case
+// AuthManager builds and signs a fresh compact JWS on every request from
+// ClaimTemplate instead of reusing a single token — see applyJWTAuth.
 type JWTAuth struct {
-	Token        string `json:"token"`
+	Token        string `json:"token,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	RefreshURL   string `json:"refresh_url,omitempty"`
 	ExpiresAt    int64  `json:"expires_at,omitempty"`
+
+	// Algorithm selects self-signed minting mode when set: one of HS256,
+	// HS384, HS512, RS256, RS384, or ES256.
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// SigningKey is the key material for Algorithm: the raw shared secret
+	// for HS* algorithms, or a PEM-encoded RSA/ECDSA private key for RS*/ES*.
+	SigningKey string `json:"signing_key,omitempty"`
+
+	// KeyID, if set, is sent as the token's "kid" header so a verifier can
+	// select the matching public key from a key set.
+	KeyID string `json:"key_id,omitempty"`
+
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+
+	// TTL sets how far past iat exp is set. Defaults to 5 minutes.
+	TTL time.Duration `json:"ttl,omitempty"`
+
+	// ClaimTemplate seeds the token's claim set before iat/exp/jti (and
+	// Issuer/Audience) are added. String values are resolved through the
+	// simulation's VariableResolver first, so e.g. {"sub": "{{username}}"}
+	// mints a different subject per virtual user.
+	ClaimTemplate map[string]interface{} `json:"claim_template,omitempty"`
+
+	// HeaderName and HeaderPrefix override where the minted token is placed.
+	// Default to "Authorization" and "Bearer ".
+	HeaderName   string `json:"header_name,omitempty"`
+	HeaderPrefix string `json:"header_prefix,omitempty"`
+
+	// OIDCIssuer and JWKSURL select a third mode: instead of forwarding a
+	// static Token or minting a self-signed one, AuthManager fetches a token
+	// from a real OIDC provider's token endpoint and cryptographically
+	// verifies it before attaching it, so a misconfigured or expired
+	// provider key fails the request instead of silently sending a bad
+	// token. OIDCIssuer is the issuer base URL used for discovery (fetching
+	// "{issuer}/.well-known/openid-configuration" for jwks_uri and
+	// token_endpoint); JWKSURL, if set, skips discovery and is used
+	// directly. Either field alone is enough to select this mode.
+	OIDCIssuer string `json:"oidc_issuer,omitempty"`
+	JWKSURL    string `json:"jwks_url,omitempty"`
+
+	// ClientID/ClientSecret/Scope authenticate the client_credentials
+	// request against the discovered (or explicit, via RefreshURL as
+	// token_endpoint override) token endpoint in OIDC mode.
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Scope        string `json:"scope,omitempty"`
 }
 
-// OAuth2Auth represents OAuth2 client credentials authentication
+// OAuth2Auth represents OAuth2 authentication. GrantType selects which OAuth2
+// flow AuthManager performs against TokenURL: "client_credentials" (the
+// default), "password", "authorization_code", or "refresh_token" — only the
+// fields that grant needs must be set.
 type OAuth2Auth struct {
+	GrantType    string `json:"grant_type,omitempty"`
 	ClientID     string `json:"client_id"`
 	ClientSecret string `json:"client_secret"`
 	TokenURL     string `json:"token_url"`
 	Scope        string `json:"scope,omitempty"`
-	AccessToken  string `json:"access_token,omitempty"`
-	ExpiresAt    int64  `json:"expires_at,omitempty"`
+
+	// Username/Password are used by the "password" grant.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// AuthorizationCode/RedirectURL are used by the "authorization_code" grant.
+	AuthorizationCode string `json:"authorization_code,omitempty"`
+	RedirectURL       string `json:"redirect_url,omitempty"`
+
+	// RefreshToken is used by the "refresh_token" grant, and is also kept
+	// up to date after any other grant returns one (refresh-token rotation):
+	// when a token response includes a new refresh_token, it replaces this
+	// value and is used on the next refresh instead of the original.
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// AuthStyle selects how client_id/client_secret are sent: "header" for
+	// HTTP Basic auth, "params" to include them in the form body, or ""
+	// (default) to auto-detect based on what the token endpoint accepts.
+	AuthStyle string `json:"auth_style,omitempty"`
+
+	// UsePKCE adds RFC 7636 PKCE to the "authorization_code" grant: a random
+	// code_verifier is generated (see GeneratePKCEPair) and its S256
+	// code_challenge should be included when building the authorization URL
+	// the user is redirected to; CodeVerifier below is then sent with the
+	// token exchange. Set CodeVerifier directly instead if the verifier was
+	// already generated elsewhere (e.g. by the caller that built the
+	// authorization URL).
+	UsePKCE      bool   `json:"use_pkce,omitempty"`
+	CodeVerifier string `json:"code_verifier,omitempty"`
+
+	// AccessToken/ExpiresAt reflect the most recently issued token; set by
+	// AuthManager, not meant to be supplied by the caller.
+	AccessToken string `json:"access_token,omitempty"`
+	ExpiresAt   int64  `json:"expires_at,omitempty"`
+}
+
+// TLSConfig configures the transport-level TLS a simulation's HTTP client
+// dials with — set on SimulationConfig, independent of AuthConfig.Type, so
+// mTLS composes with any header-based auth scheme (e.g. mTLS + Bearer) or
+// none at all. A client certificate is optional: CertFile/KeyFile (or
+// P12File) are only needed when the target requires one; CAFile/CABundle/
+// ServerName/SPIFFEID apply to verifying the target's certificate either
+// way.
+type TLSConfig struct {
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+
+	// KeyPassphrase decrypts KeyFile when it's an encrypted PEM private key.
+	// May be a literal value or a "scheme://..." SecretManager reference.
+	KeyPassphrase string `json:"key_passphrase,omitempty"`
+
+	CAFile string `json:"ca_file,omitempty"`
+
+	// CABundle is an inline PEM-encoded CA bundle, as an alternative to
+	// CAFile for callers that don't want to manage a file on disk. Takes
+	// precedence over CAFile if both are set.
+	CABundle string `json:"ca_bundle,omitempty"`
+
+	// ServerName overrides SNI/certificate-hostname verification, for
+	// targets addressed by IP or behind a load balancer that doesn't share
+	// the certificate's subject.
+	ServerName string `json:"server_name,omitempty"`
+
+	// InsecureSkipVerify disables server certificate verification entirely.
+	// Only meant for testing against self-signed endpoints; never enable
+	// this against a production target.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+
+	// P12File is a PKCS#12 (.p12/.pfx) bundle containing the client
+	// certificate and private key, as an alternative to separate
+	// CertFile/KeyFile. P12Password decrypts it (empty if unencrypted).
+	P12File     string `json:"p12_file,omitempty"`
+	P12Password string `json:"p12_password,omitempty"`
+
+	// MinVersion floors the negotiated TLS version: "1.0", "1.1", "1.2", or
+	// "1.3". Defaults to the standard library's own minimum.
+	MinVersion string `json:"min_version,omitempty"`
+
+	// CipherSuites restricts negotiation to these cipher suites by name
+	// (e.g. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"), for targets that
+	// require pinning to a specific set. Defaults to the standard library's
+	// own preference order when empty. Ignored for TLS 1.3, which the
+	// standard library always chooses ciphers for itself.
+	CipherSuites []string `json:"cipher_suites,omitempty"`
+
+	// SPIFFEID, if set, is verified against the peer certificate's URI SAN
+	// on every connection (see verifyPeerCertificate) — SPIFFE-style
+	// workload identity, on top of whatever chain verification CAFile/
+	// CABundle or the system root pool already performs.
+	SPIFFEID string `json:"spiffe_id,omitempty"`
+
+	// ReloadInterval, if set, makes AuthManager periodically re-read the
+	// certificate/key/CA material from disk, so a long-running simulation
+	// picks up a rotated short-lived certificate without restarting.
+	ReloadInterval time.Duration `json:"reload_interval,omitempty"`
+}
+
+// AWSSigV4Auth represents AWS Signature Version 4 request signing, as used
+// by S3-compatible and other AWS-style APIs. SessionToken is only needed for
+// temporary (STS) credentials.
+type AWSSigV4Auth struct {
+	Region          string `json:"region"`
+	Service         string `json:"service"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token,omitempty"`
 }
 
-// ClientCertAuth represents client certificate authentication
-type ClientCertAuth struct {
-	CertFile string `json:"cert_file"`
-	KeyFile  string `json:"key_file"`
-	CAFile   string `json:"ca_file,omitempty"`
+// HMACAuth represents a generic HMAC request-signing scheme, as used by many
+// internal and webhook-style APIs that aren't full AWS SigV4. SignedHeaders
+// and IncludeBody control what feeds the canonical string that gets signed;
+// Algorithm, HeaderName, and Prefix control how the signature is produced
+// and where it's placed on the request.
+type HMACAuth struct {
+	Secret string `json:"secret"`
+
+	// Algorithm is "sha256" (default), "sha1", or "sha512".
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// HeaderName is the header the signature is written to. Defaults to
+	// "X-Signature".
+	HeaderName string `json:"header_name,omitempty"`
+
+	// Prefix is prepended to the hex-encoded signature, e.g. "sha256=".
+	Prefix string `json:"prefix,omitempty"`
+
+	// SignedHeaders names request headers to fold into the canonical
+	// string, in order, by their already-resolved values.
+	SignedHeaders []string `json:"signed_headers,omitempty"`
+
+	// IncludeBody appends the request body to the canonical string.
+	IncludeBody bool `json:"include_body,omitempty"`
 }
@@ -0,0 +1,228 @@
+package simulator
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// capabilityUpdateInterval is how often a registered target is re-probed.
+const capabilityUpdateInterval = time.Hour
+
+// capabilityMinInvalidateInterval bounds how often Invalidate can force an
+// early re-probe of the same baseURL, so a burst of user actions (e.g.
+// rapidly reopening the service profile picker) can't stampede a
+// downstream service with health checks.
+const capabilityMinInvalidateInterval = time.Minute
+
+// defaultCapabilityPaths are probed in order; the first one that responds
+// is used for latency/RPS-ceiling sampling, but every path's response body
+// is scanned for feature flags.
+var defaultCapabilityPaths = []string{"/health", "/metrics"}
+
+// CapabilitySnapshot is the most recently observed health of a probed
+// service profile target, merged into GetServiceProfiles alongside its
+// static configuration.
+type CapabilitySnapshot struct {
+	ActualRPS      float64           `json:"actual_rps"`
+	ActualP50Latency time.Duration   `json:"actual_p50_latency"`
+	ActualP95Latency time.Duration   `json:"actual_p95_latency"`
+	ActualP99Latency time.Duration   `json:"actual_p99_latency"`
+	FeatureFlags   map[string]bool   `json:"feature_flags,omitempty"`
+	LastProbedAt   time.Time         `json:"last_probed_at"`
+
+	nextUpdate     time.Time
+	nextInvalidate time.Time
+}
+
+// CapabilityProber periodically probes registered service-profile targets
+// and caches what it observes, the same fetch-cache-rate-limit pattern
+// AuthManager's TokenCache uses for credential refreshes: entries are kept
+// for capabilityUpdateInterval and never forced to re-probe more than once
+// per capabilityMinInvalidateInterval.
+type CapabilityProber struct {
+	mu    sync.RWMutex
+	cache map[string]*CapabilitySnapshot
+
+	// probePaths remembers each baseURL's configured probe paths so
+	// Invalidate can re-probe with the same paths StartProbing was given.
+	probePaths map[string][]string
+
+	stopOnce sync.Once
+	stopCh   map[string]chan struct{}
+
+	client *http.Client
+
+	// getCapabilitiesNow stands in for time.Now so tests can control cache
+	// expiry without sleeping.
+	getCapabilitiesNow func() time.Time
+}
+
+// NewCapabilityProber returns a CapabilityProber with no targets registered
+// yet; call StartProbing for each baseURL worth monitoring.
+func NewCapabilityProber() *CapabilityProber {
+	return NewCapabilityProberWithClock(time.Now)
+}
+
+// NewCapabilityProberWithClock is NewCapabilityProber with an injectable
+// clock, so tests can control cache expiry (nextUpdate/nextInvalidate)
+// without sleeping.
+func NewCapabilityProberWithClock(now func() time.Time) *CapabilityProber {
+	return &CapabilityProber{
+		cache:              make(map[string]*CapabilitySnapshot),
+		probePaths:         make(map[string][]string),
+		stopCh:             make(map[string]chan struct{}),
+		client:             &http.Client{Timeout: 5 * time.Second},
+		getCapabilitiesNow: now,
+	}
+}
+
+// StartProbing spawns a background goroutine that probes baseURL against
+// paths (defaultCapabilityPaths if empty) every capabilityUpdateInterval
+// until the CapabilityProber is stopped. Calling it again for a baseURL
+// already being probed is a no-op.
+func (p *CapabilityProber) StartProbing(baseURL string, paths []string) {
+	if len(paths) == 0 {
+		paths = defaultCapabilityPaths
+	}
+
+	p.mu.Lock()
+	if _, running := p.stopCh[baseURL]; running {
+		p.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	p.stopCh[baseURL] = stop
+	p.probePaths[baseURL] = paths
+	p.mu.Unlock()
+
+	go func() {
+		p.probe(baseURL, paths)
+		ticker := time.NewTicker(capabilityUpdateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				p.probe(baseURL, paths)
+			}
+		}
+	}()
+}
+
+// Get returns the cached snapshot for baseURL, if one has been probed yet.
+func (p *CapabilityProber) Get(baseURL string) (*CapabilitySnapshot, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	snapshot, ok := p.cache[baseURL]
+	return snapshot, ok
+}
+
+// Invalidate forces an immediate re-probe of baseURL, unless it was already
+// invalidated within capabilityMinInvalidateInterval, in which case it is
+// left alone so a burst of calls can't stampede the target. Safe to call
+// whether or not StartProbing has been started for baseURL.
+func (p *CapabilityProber) Invalidate(baseURL string) {
+	now := p.getCapabilitiesNow()
+
+	p.mu.RLock()
+	snapshot, ok := p.cache[baseURL]
+	p.mu.RUnlock()
+	if ok && now.Before(snapshot.nextInvalidate) {
+		return
+	}
+
+	p.mu.RLock()
+	paths := p.probePaths[baseURL]
+	p.mu.RUnlock()
+	if len(paths) == 0 {
+		paths = defaultCapabilityPaths
+	}
+	p.probe(baseURL, paths)
+}
+
+// probe hits the first reachable path in paths, records its latency as a
+// crude RPS ceiling estimate (1 / latency, single-sample - good enough to
+// rank targets relative to each other, not a load-tested guarantee), and
+// scans every path's JSON body (if any) for a top-level "features" object.
+func (p *CapabilityProber) probe(baseURL string, paths []string) {
+	var latencies []time.Duration
+	flags := make(map[string]bool)
+
+	for _, path := range paths {
+		url := strings.TrimRight(baseURL, "/") + path
+		start := p.getCapabilitiesNow()
+		resp, err := p.client.Get(url)
+		latency := p.getCapabilitiesNow().Sub(start)
+		if err != nil {
+			logrus.Debugf("capability probe %s failed: %v", url, err)
+			continue
+		}
+		latencies = append(latencies, latency)
+
+		var body struct {
+			Features map[string]bool `json:"features"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil {
+			for flag, enabled := range body.Features {
+				flags[flag] = enabled
+			}
+		}
+		resp.Body.Close()
+	}
+
+	if len(latencies) == 0 {
+		return
+	}
+
+	now := p.getCapabilitiesNow()
+	snapshot := &CapabilitySnapshot{
+		ActualP50Latency: percentileDuration(latencies, 0.50),
+		ActualP95Latency: percentileDuration(latencies, 0.95),
+		ActualP99Latency: percentileDuration(latencies, 0.99),
+		FeatureFlags:     flags,
+		LastProbedAt:     now,
+		nextUpdate:       now.Add(capabilityUpdateInterval),
+		nextInvalidate:   now.Add(capabilityMinInvalidateInterval),
+	}
+	if mean := meanDuration(latencies); mean > 0 {
+		snapshot.ActualRPS = float64(time.Second) / float64(mean)
+	}
+
+	p.mu.Lock()
+	p.cache[baseURL] = snapshot
+	p.mu.Unlock()
+}
+
+// Stop ends every running probe goroutine; the CapabilityProber is not
+// reusable afterwards.
+func (p *CapabilityProber) Stop() {
+	p.stopOnce.Do(func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, stop := range p.stopCh {
+			close(stop)
+		}
+	})
+}
+
+func percentileDuration(samples []time.Duration, pct float64) time.Duration {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(pct * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+func meanDuration(samples []time.Duration) time.Duration {
+	var total time.Duration
+	for _, s := range samples {
+		total += s
+	}
+	return total / time.Duration(len(samples))
+}
@@ -0,0 +1,219 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CaptureSource identifies where in an HTTP response a CaptureRule reads from.
+type CaptureSource string
+
+const (
+	CaptureSourceBody   CaptureSource = "body"
+	CaptureSourceHeader CaptureSource = "header"
+	CaptureSourceStatus CaptureSource = "status"
+)
+
+// CaptureScope controls which VariableResolver a captured value is stored
+// in, and therefore how widely it's visible to later requests.
+type CaptureScope string
+
+const (
+	// ScopeVirtualUser makes the captured value visible only to later
+	// requests issued by the same virtual user.
+	ScopeVirtualUser CaptureScope = "virtual_user"
+	// ScopeSimulation makes the captured value visible to every virtual
+	// user within the same simulation.
+	ScopeSimulation CaptureScope = "simulation"
+	// ScopeGlobal makes the captured value visible across every simulation.
+	ScopeGlobal CaptureScope = "global"
+)
+
+// CaptureRule extracts a value out of an HTTP response and stores it as a
+// named variable so a later request in the same chain can reference it as
+// {{name}}, e.g. capturing a login token for reuse as an auth header.
+type CaptureRule struct {
+	Name   string        `json:"name"`
+	Source CaptureSource `json:"source"`
+	// Expr is a JSONPath-like expression ("$.data.token") when Source is
+	// body, the header name when Source is header, and is ignored when
+	// Source is status. A body/header Expr may alternatively be a regular
+	// expression with exactly one capture group, used when it doesn't start
+	// with '$'.
+	Expr  string       `json:"expr"`
+	Scope CaptureScope `json:"scope"`
+}
+
+// applyCaptureRules evaluates every capture rule for a response and, on a
+// hit, stores the captured value in the resolver matching the rule's scope.
+// Hits and misses are both recorded to the metrics collector so capture
+// rules that silently stop matching (e.g. after an API shape change) show up
+// as a dashboard signal rather than a quiet correctness bug.
+func (e *Engine) applyCaptureRules(sim *Simulation, vuResolver *VariableResolver, rules []CaptureRule, resp *http.Response, body []byte) {
+	for _, rule := range rules {
+		value, ok := captureValue(rule, resp, body)
+		if !ok {
+			e.metricsCollector.RecordCaptureResult(sim.config.ID, rule.Name, false)
+			logrus.Debugf("capture rule %q did not match", rule.Name)
+			continue
+		}
+
+		switch rule.Scope {
+		case ScopeGlobal:
+			e.varResolver.SetVariable(rule.Name, value)
+		case ScopeSimulation:
+			sim.simResolver.SetVariable(rule.Name, value)
+		default: // ScopeVirtualUser, and the zero value
+			vuResolver.SetVariable(rule.Name, value)
+		}
+		e.metricsCollector.RecordCaptureResult(sim.config.ID, rule.Name, true)
+	}
+}
+
+// captureValue extracts the raw value a CaptureRule describes from resp/body.
+func captureValue(rule CaptureRule, resp *http.Response, body []byte) (string, bool) {
+	switch rule.Source {
+	case CaptureSourceStatus:
+		return strconv.Itoa(resp.StatusCode), true
+	case CaptureSourceHeader:
+		value := resp.Header.Get(rule.Expr)
+		if value == "" {
+			return "", false
+		}
+		return value, true
+	case CaptureSourceBody:
+		return captureFromBody(rule.Expr, body)
+	default:
+		return "", false
+	}
+}
+
+// captureFromBody evaluates expr against the JSON response body as a
+// JSONPath-like expression if it starts with '$', otherwise as a regular
+// expression whose first capture group is returned.
+func captureFromBody(expr string, body []byte) (string, bool) {
+	if strings.HasPrefix(expr, "$") {
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return "", false
+		}
+		value, ok := EvaluateJSONPath(data, expr)
+		if !ok {
+			return "", false
+		}
+		return jsonPathValueToString(value), true
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", false
+	}
+	match := re.FindSubmatch(body)
+	if len(match) < 2 {
+		return "", false
+	}
+	return string(match[1]), true
+}
+
+// jsonPathValueToString renders a decoded JSON value as the plain string a
+// captured template variable should hold.
+func jsonPathValueToString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
+
+// EvaluateJSONPath resolves a small subset of JSONPath against data: "$" for
+// the root, dotted keys ("$.data.token"), array indices ("items[0]"), and a
+// wildcard index ("items[*]") that returns the first element. It exists so
+// capture rules don't need a full JSONPath dependency for the common shapes
+// APIs return.
+func EvaluateJSONPath(data interface{}, path string) (interface{}, bool) {
+	if path == "$" {
+		return data, true
+	}
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+
+	current := data
+	for _, segment := range splitJSONPath(path) {
+		key, index, hasIndex := parseJSONPathSegment(segment)
+
+		if key != "" {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			current, ok = m[key]
+			if !ok {
+				return nil, false
+			}
+		}
+
+		if hasIndex {
+			arr, ok := current.([]interface{})
+			if !ok || len(arr) == 0 {
+				return nil, false
+			}
+			if index < 0 || index >= len(arr) {
+				return nil, false
+			}
+			current = arr[index]
+		}
+	}
+	return current, true
+}
+
+// splitJSONPath splits a dotted path like "data.items[0].token" into
+// ["data", "items[0]", "token"].
+func splitJSONPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// parseJSONPathSegment splits a single path segment into its bare key (if
+// any) and array index (if any), e.g. "items[0]" -> ("items", 0, true) and
+// "items[*]" -> ("items", 0, true) (the wildcard resolves to the first
+// element, since this subset has no concept of a multi-value result).
+func parseJSONPathSegment(segment string) (key string, index int, hasIndex bool) {
+	open := strings.IndexByte(segment, '[')
+	if open == -1 {
+		return segment, 0, false
+	}
+	closeIdx := strings.IndexByte(segment, ']')
+	if closeIdx == -1 || closeIdx < open {
+		return segment, 0, false
+	}
+
+	key = segment[:open]
+	indexText := segment[open+1 : closeIdx]
+	if indexText == "*" {
+		return key, 0, true
+	}
+	n, err := strconv.Atoi(indexText)
+	if err != nil {
+		return key, 0, false
+	}
+	return key, n, true
+}
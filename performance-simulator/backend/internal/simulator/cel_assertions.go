@@ -0,0 +1,200 @@
+package simulator
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// sharedCELEnv is the single CEL environment every CEL assertion (Assertion
+// with Type == AssertionTypeCEL) compiles against, built once per process —
+// the same once-built, shared-thereafter approach registerDefaultFormatCheckers
+// uses for gojsonschema's global format registry (json_schema.go). It
+// declares:
+//   - response: a map with status (int), headers (map<string,string>), and
+//     bodyBytes (bytes)
+//   - json: the response body, already parsed (or null if it wasn't valid JSON)
+//   - xml: the response body parsed as XML, as nested maps (xmlNodeToCELValue)
+//   - duration: the response time
+//   - match(pattern, string): a regexp.MatchString-backed predicate
+var (
+	celEnvOnce sync.Once
+	celEnv     *cel.Env
+	celEnvErr  error
+)
+
+func sharedCELEnv() (*cel.Env, error) {
+	celEnvOnce.Do(func() {
+		celEnv, celEnvErr = cel.NewEnv(
+			cel.Variable("response", cel.DynType),
+			cel.Variable("json", cel.DynType),
+			cel.Variable("xml", cel.DynType),
+			cel.Variable("duration", cel.DurationType),
+			cel.Function("match",
+				cel.Overload("match_string_string",
+					[]*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+					cel.BinaryBinding(celMatch)),
+			),
+		)
+	})
+	return celEnv, celEnvErr
+}
+
+// celMatch backs the match(pattern, string) CEL function.
+func celMatch(pattern, subject ref.Val) ref.Val {
+	patternStr, ok := pattern.Value().(string)
+	if !ok {
+		return types.NewErr("match: pattern must be a string")
+	}
+	subjectStr, ok := subject.Value().(string)
+	if !ok {
+		return types.NewErr("match: subject must be a string")
+	}
+	matched, err := regexp.MatchString(patternStr, subjectStr)
+	if err != nil {
+		return types.NewErr("match: invalid pattern %q: %v", patternStr, err)
+	}
+	return types.Bool(matched)
+}
+
+// compileCELProgram compiles expression, caching the result by a hash of its
+// source the same way ValidationEngine.compileJSONSchema caches compiled
+// schemas, so a scenario that runs the same CEL assertion thousands of times
+// only type-checks it once.
+func (ae *AssertionEngine) compileCELProgram(expression string) (cel.Program, error) {
+	hash := sha256Hex(expression)
+
+	ae.celMutex.RLock()
+	cached, ok := ae.celProgramCache[hash]
+	ae.celMutex.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	env, err := sharedCELEnv()
+	if err != nil {
+		return nil, fmt.Errorf("build CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("compile CEL expression %q: %w", expression, issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("build CEL program for %q: %w", expression, err)
+	}
+
+	ae.celMutex.Lock()
+	ae.celProgramCache[hash] = program
+	ae.celMutex.Unlock()
+	return program, nil
+}
+
+// evaluateCEL compiles (or reuses a cached compilation of) expression and
+// runs it against ctx, returning its result value the same way evaluate does
+// for the hand-rolled expression language.
+func (ae *AssertionEngine) evaluateCEL(expression string, ctx *assertionContext) (interface{}, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, fmt.Errorf("CEL expression cannot be empty")
+	}
+
+	program, err := ae.compileCELProgram(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonValue interface{}
+	if ctx.jsonErr == nil {
+		jsonValue = ctx.jsonValue
+	}
+
+	vars := map[string]interface{}{
+		"response": map[string]interface{}{
+			"status":    int64(ctx.status),
+			"headers":   flattenHeader(ctx.headers),
+			"bodyBytes": []byte(ctx.body),
+		},
+		"json":     jsonValue,
+		"duration": time.Duration(ctx.durationMs * float64(time.Millisecond)),
+	}
+	if doc, err := ctx.xmlDocument(); err == nil {
+		vars["xml"] = xmlNodeToCELValue(doc)
+	}
+
+	out, _, err := program.Eval(vars)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate CEL expression %q: %w", expression, err)
+	}
+	return out.Value(), nil
+}
+
+// flattenHeader collapses an http.Header's possibly-multi-valued entries
+// down to one string per key (its first/combined value, via Header.Get) so
+// it can be exposed to CEL as a plain map<string,string>.
+func flattenHeader(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name := range h {
+		out[name] = h.Get(name)
+	}
+	return out
+}
+
+// xmlNodeToCELValue converts a parsed XML element into a value CEL
+// assertions can navigate with plain field access (e.g. xml.user.id):
+// attributes become "@name" entries, a repeated child element becomes a
+// list, and a leaf element with no children or attributes collapses to its
+// trimmed text content instead of a map.
+func xmlNodeToCELValue(node *xmlquery.Node) interface{} {
+	if node == nil {
+		return nil
+	}
+
+	result := map[string]interface{}{}
+	for _, attr := range node.Attr {
+		result["@"+attr.Name.Local] = attr.Value
+	}
+
+	children := map[string][]interface{}{}
+	var order []string
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != xmlquery.ElementNode {
+			continue
+		}
+		if _, seen := children[child.Data]; !seen {
+			order = append(order, child.Data)
+		}
+		children[child.Data] = append(children[child.Data], xmlNodeToCELValue(child))
+	}
+
+	for _, name := range order {
+		values := children[name]
+		if len(values) == 1 {
+			result[name] = values[0]
+		} else {
+			result[name] = values
+		}
+	}
+
+	if len(order) == 0 {
+		text := strings.TrimSpace(node.InnerText())
+		if len(result) == 0 {
+			return text
+		}
+		if text != "" {
+			result["_text"] = text
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,427 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CorpusEntry is one distinct request a RequestCorpus can sample, as
+// imported from a HAR capture or an OpenAPI operation.
+type CorpusEntry struct {
+	Name        string            `json:"name"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        *RequestBody      `json:"body,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	// Weight is this entry's relative share of sampled requests - observed
+	// call frequency for a HAR import, 1 for every OpenAPI operation (they
+	// carry no frequency information of their own).
+	Weight float64 `json:"weight"`
+}
+
+// RequestCorpus is a weighted pool of requests executeRequest samples from
+// instead of SimulationConfig's single fixed TargetURL, so a simulation can
+// reproduce a realistic traffic mix. Next() is safe for concurrent use by
+// every virtual user's goroutine.
+type RequestCorpus struct {
+	Entries []CorpusEntry `json:"entries"`
+
+	scheduleOnce sync.Once
+	schedule     []int
+	counter      int64
+}
+
+// Next round-robins through Entries in proportion to their Weight (an entry
+// with twice the weight of another appears in the rotation twice as often),
+// lazily building the rotation schedule on first use so a RequestCorpus
+// decoded from JSON (whose schedule/counter are zero values) works without
+// an explicit construction step.
+func (rc *RequestCorpus) Next() *CorpusEntry {
+	rc.scheduleOnce.Do(rc.buildSchedule)
+	if len(rc.schedule) == 0 {
+		return nil
+	}
+	idx := int(uint64(atomic.AddInt64(&rc.counter, 1)) % uint64(len(rc.schedule)))
+	return &rc.Entries[rc.schedule[idx]]
+}
+
+func (rc *RequestCorpus) buildSchedule() {
+	if len(rc.Entries) == 0 {
+		return
+	}
+
+	minWeight := 0.0
+	for _, entry := range rc.Entries {
+		if entry.Weight > 0 && (minWeight == 0 || entry.Weight < minWeight) {
+			minWeight = entry.Weight
+		}
+	}
+	if minWeight <= 0 {
+		minWeight = 1
+	}
+
+	for i, entry := range rc.Entries {
+		weight := entry.Weight
+		if weight <= 0 {
+			weight = minWeight
+		}
+		count := int(math.Round(weight / minWeight))
+		if count < 1 {
+			count = 1
+		}
+		for n := 0; n < count; n++ {
+			rc.schedule = append(rc.schedule, i)
+		}
+	}
+}
+
+// withCorpusEntry returns a shallow copy of config with its TargetURL,
+// Method, Headers, Body, and ContentType replaced by entry's, so
+// resolveVariables can resolve templates against the sampled corpus entry
+// without mutating the SimulationConfig every virtual user shares.
+func (config *SimulationConfig) withCorpusEntry(entry *CorpusEntry) *SimulationConfig {
+	resolved := *config
+	resolved.TargetURL = entry.URL
+	resolved.Method = entry.Method
+	resolved.Headers = entry.Headers
+	resolved.Body = entry.Body
+	if entry.ContentType != "" {
+		resolved.ContentType = entry.ContentType
+	}
+	return &resolved
+}
+
+// harFile is the subset of the HAR 1.2 format (http://www.softwareishard.com/blog/har-12-spec/)
+// ImportHAR needs: one request per log entry.
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method      string `json:"method"`
+				URL         string `json:"url"`
+				Headers     []harNameValue `json:"headers"`
+				QueryString []harNameValue `json:"queryString"`
+				PostData    *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// BuildCorpusFromHAR groups har's entries by method+path (the query string
+// and any captured variable are not part of the grouping key, since the
+// same endpoint hit with different parameters should still count as one
+// corpus entry with a higher observed frequency), and returns one
+// RequestCorpus entry per endpoint with Weight set to its observed call
+// count.
+func BuildCorpusFromHAR(har []byte) (*RequestCorpus, error) {
+	var parsed harFile
+	if err := json.Unmarshal(har, &parsed); err != nil {
+		return nil, fmt.Errorf("parse HAR: %w", err)
+	}
+
+	type group struct {
+		entry CorpusEntry
+		count int
+	}
+	groups := make(map[string]*group)
+	var order []string
+
+	for _, e := range parsed.Log.Entries {
+		req := e.Request
+		if req.Method == "" || req.URL == "" {
+			continue
+		}
+		parsedURL, err := url.Parse(req.URL)
+		if err != nil {
+			continue
+		}
+		key := strings.ToUpper(req.Method) + " " + parsedURL.Path
+
+		g, exists := groups[key]
+		if !exists {
+			headers := make(map[string]string, len(req.Headers))
+			for _, h := range req.Headers {
+				headers[h.Name] = h.Value
+			}
+
+			var body *RequestBody
+			contentType := ""
+			if req.PostData != nil {
+				contentType = req.PostData.MimeType
+				body = &RequestBody{Type: BodyTypeRaw, Content: req.PostData.Text}
+				if strings.Contains(contentType, "json") {
+					body.Type = BodyTypeJSON
+				} else if strings.Contains(contentType, "form-urlencoded") {
+					body.Type = BodyTypeForm
+				}
+			}
+
+			g = &group{entry: CorpusEntry{
+				Name:        key,
+				Method:      strings.ToUpper(req.Method),
+				URL:         req.URL,
+				Headers:     headers,
+				Body:        body,
+				ContentType: contentType,
+			}}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.count++
+	}
+
+	corpus := &RequestCorpus{}
+	for _, key := range order {
+		g := groups[key]
+		g.entry.Weight = float64(g.count)
+		corpus.Entries = append(corpus.Entries, g.entry)
+	}
+	if len(corpus.Entries) == 0 {
+		return nil, fmt.Errorf("HAR file contained no usable requests")
+	}
+	return corpus, nil
+}
+
+// openAPIHTTPMethods are the operation keys BuildCorpusFromOpenAPI looks for
+// under each path item, in a stable iteration order for deterministic
+// output (Go map iteration is randomized otherwise).
+var openAPIHTTPMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// BuildCorpusFromOpenAPI walks an OpenAPI 3.x document's paths and returns
+// one RequestCorpus entry per operation, with a request body synthesized
+// from requestBody.content.application/json.schema (preferring a literal
+// example where the spec provides one) and path/query parameter templates
+// left as {{resolver}} placeholders for VariableResolver to fill in at
+// request time.
+//
+// The document is parsed as a generic map rather than a typed OpenAPI 3
+// model (this repo's go-openapi dependency only understands Swagger 2.0 -
+// see openapi_validation.go), so $ref references are not resolved; a spec
+// that relies on them needs to be bundled/dereferenced before importing.
+func BuildCorpusFromOpenAPI(spec []byte, baseURL string) (*RequestCorpus, error) {
+	doc, err := decodeOpenAPIDocument(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseURL == "" {
+		baseURL = firstOpenAPIServerURL(doc)
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	if paths == nil {
+		return nil, fmt.Errorf("OpenAPI spec declares no paths")
+	}
+
+	var pathKeys []string
+	for path := range paths {
+		pathKeys = append(pathKeys, path)
+	}
+	sort.Strings(pathKeys)
+
+	corpus := &RequestCorpus{}
+	for _, path := range pathKeys {
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIHTTPMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			entry, err := corpusEntryFromOperation(baseURL, path, method, op)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s %s: %w", strings.ToUpper(method), path, err)
+			}
+			corpus.Entries = append(corpus.Entries, *entry)
+		}
+	}
+	if len(corpus.Entries) == 0 {
+		return nil, fmt.Errorf("OpenAPI spec declared no operations")
+	}
+	return corpus, nil
+}
+
+func decodeOpenAPIDocument(spec []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(spec, &doc); err == nil {
+		return doc, nil
+	}
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse OpenAPI spec as JSON or YAML: %w", err)
+	}
+	return doc, nil
+}
+
+func firstOpenAPIServerURL(doc map[string]interface{}) string {
+	servers, _ := doc["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]interface{})
+	serverURL, _ := server["url"].(string)
+	return serverURL
+}
+
+func corpusEntryFromOperation(baseURL, path, method string, op map[string]interface{}) (*CorpusEntry, error) {
+	entry := &CorpusEntry{
+		Name:   operationName(op, method, path),
+		Method: strings.ToUpper(method),
+		URL:    strings.TrimRight(baseURL, "/") + templatePathParameters(path),
+		Weight: 1,
+	}
+
+	reqBody, _ := op["requestBody"].(map[string]interface{})
+	if reqBody == nil {
+		return entry, nil
+	}
+	content, _ := reqBody["content"].(map[string]interface{})
+	jsonContent, _ := content["application/json"].(map[string]interface{})
+	if jsonContent == nil {
+		return entry, nil
+	}
+
+	entry.ContentType = "application/json"
+
+	if example, ok := jsonContent["example"]; ok {
+		return finishEntryWithBody(entry, example)
+	}
+	if examples, ok := jsonContent["examples"].(map[string]interface{}); ok {
+		for _, v := range examples {
+			if named, ok := v.(map[string]interface{}); ok {
+				if value, ok := named["value"]; ok {
+					return finishEntryWithBody(entry, value)
+				}
+			}
+		}
+	}
+
+	schema, _ := jsonContent["schema"].(map[string]interface{})
+	if schema == nil {
+		return entry, nil
+	}
+	return finishEntryWithBody(entry, generateFromSchema(schema))
+}
+
+func finishEntryWithBody(entry *CorpusEntry, value interface{}) (*CorpusEntry, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("encode generated request body: %w", err)
+	}
+	entry.Body = &RequestBody{Type: BodyTypeJSON, Content: string(content)}
+	return entry, nil
+}
+
+// operationName prefers the operation's own operationId/summary, falling
+// back to "METHOD /path" so every corpus entry has a human-readable label.
+func operationName(op map[string]interface{}, method, path string) string {
+	if id, ok := op["operationId"].(string); ok && id != "" {
+		return id
+	}
+	if summary, ok := op["summary"].(string); ok && summary != "" {
+		return summary
+	}
+	return strings.ToUpper(method) + " " + path
+}
+
+// templatePathParameters rewrites OpenAPI's {param} path templates into
+// VariableResolver placeholders so StartSimulation resolves a fresh value
+// per request; callers that want the literal template left alone can
+// ignore this and build the URL themselves.
+func templatePathParameters(path string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range path {
+		switch r {
+		case '{':
+			if depth == 0 {
+				b.WriteString("{{")
+			}
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				b.WriteString("}}")
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// generateFromSchema synthesizes a JSON-compatible value from an OpenAPI/
+// JSON Schema object. Scalar leaves become VariableResolver placeholders
+// (e.g. "{{uuid}}") chosen from the schema's format/type rather than a
+// fixed literal, so every sampled request carries fresh data. $ref is not
+// followed (see BuildCorpusFromOpenAPI's doc comment).
+func generateFromSchema(schema map[string]interface{}) interface{} {
+	if example, ok := schema["example"]; ok {
+		return example
+	}
+
+	schemaType, _ := schema["type"].(string)
+	switch schemaType {
+	case "object":
+		obj := make(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, propSchema := range properties {
+			propMap, ok := propSchema.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			obj[name] = generateFromSchema(propMap)
+		}
+		return obj
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		if items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{generateFromSchema(items)}
+	case "integer":
+		return "{{random_int}}"
+	case "number":
+		return "{{random_float}}"
+	case "boolean":
+		return "{{random_bool}}"
+	default:
+		return placeholderForStringFormat(schema)
+	}
+}
+
+// placeholderForStringFormat maps a string schema's "format" (and a couple
+// of common property-name hints the format keyword doesn't cover) to the
+// VariableResolver function that best matches it.
+func placeholderForStringFormat(schema map[string]interface{}) string {
+	switch format, _ := schema["format"].(string); format {
+	case "uuid":
+		return "{{uuid}}"
+	case "email":
+		return "{{random_email}}"
+	case "date":
+		return "{{date}}"
+	case "date-time":
+		return "{{iso_timestamp}}"
+	default:
+		return "{{random_string}}"
+	}
+}
@@ -0,0 +1,207 @@
+package simulator
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// FeederMode selects how a DataFeeder's cursor advances each time Next is
+// called.
+type FeederMode string
+
+const (
+	// FeederModeRoundRobin wraps back to the first row once the last has
+	// been returned, so a long-running load test keeps rotating through a
+	// fixed dataset indefinitely. This is the default for {{csv}}/{{jsonl}}.
+	FeederModeRoundRobin FeederMode = "round_robin"
+
+	// FeederModeSequential returns each row once, in file order, and
+	// errors once every row has been consumed.
+	FeederModeSequential FeederMode = "sequential"
+
+	// FeederModeRandom returns a uniformly random row on every call.
+	FeederModeRandom FeederMode = "random"
+)
+
+// DataFeeder supplies the next row of templating data, e.g. one record of a
+// CSV or JSON-lines file, as a column-name-keyed map. A single DataFeeder is
+// shared by every virtual user drawing from the same file (see
+// dataFeederRegistry) so a run rotates through the dataset once overall,
+// e.g. a simulation hitting /login with {{csv "users.csv" "email"}} works
+// through the whole account list instead of every virtual user replaying it
+// from row one.
+type DataFeeder interface {
+	// Next returns the next row and advances the feeder's cursor according
+	// to its FeederMode. Safe for concurrent use.
+	Next() (map[string]string, error)
+}
+
+// rowDataFeeder is a DataFeeder backed by a fixed, in-memory set of rows
+// loaded once from a CSV or JSON-lines file.
+type rowDataFeeder struct {
+	mu   sync.Mutex
+	rows []map[string]string
+	mode FeederMode
+	next int
+	rng  *rand.Rand
+}
+
+func newRowDataFeeder(rows []map[string]string, mode FeederMode) (*rowDataFeeder, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("data feeder has no rows")
+	}
+	if mode == "" {
+		mode = FeederModeRoundRobin
+	}
+	return &rowDataFeeder{
+		rows: rows,
+		mode: mode,
+		rng:  rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+// Next implements DataFeeder.
+func (f *rowDataFeeder) Next() (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.mode == FeederModeRandom {
+		return f.rows[f.rng.Intn(len(f.rows))], nil
+	}
+
+	if f.next >= len(f.rows) {
+		if f.mode == FeederModeSequential {
+			return nil, fmt.Errorf("data feeder exhausted after %d rows", len(f.rows))
+		}
+		f.next = 0
+	}
+	row := f.rows[f.next]
+	f.next++
+	return row, nil
+}
+
+// NewCSVDataFeeder loads path as a CSV file, using its first row as column
+// names, and returns a DataFeeder that rotates through the remaining rows
+// according to mode.
+func NewCSVDataFeeder(path string, mode FeederMode) (DataFeeder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open csv data feeder %q: %w", path, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv data feeder %q: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("csv data feeder %q has no header row", path)
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			if i < len(record) {
+				row[col] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return newRowDataFeeder(rows, mode)
+}
+
+// NewJSONLinesDataFeeder loads path as a JSON-lines file (one JSON object
+// per line) and returns a DataFeeder that rotates through its rows
+// according to mode.
+func NewJSONLinesDataFeeder(path string, mode FeederMode) (DataFeeder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open jsonl data feeder %q: %w", path, err)
+	}
+	defer f.Close()
+
+	var rows []map[string]string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("parse jsonl data feeder %q: %w", path, err)
+		}
+		row := make(map[string]string, len(record))
+		for k, v := range record {
+			row[k] = stringifyJSONValue(v)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read jsonl data feeder %q: %w", path, err)
+	}
+	return newRowDataFeeder(rows, mode)
+}
+
+// stringifyJSONValue renders a decoded JSON value as plain text for a data
+// feeder row, so {{csv}}/{{jsonl}} can substitute it directly into a
+// request body.
+func stringifyJSONValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(b)
+	}
+}
+
+// dataFeederRegistry lazily opens and caches one DataFeeder per file path,
+// shared by a simulation's VariableResolver and every child resolver it
+// hands out (see VariableResolver.feeders), so {{csv "file" "col"}} rotates
+// through the same rows across all of a run's virtual users rather than
+// each one opening and starting its own copy of the file.
+type dataFeederRegistry struct {
+	mu      sync.Mutex
+	feeders map[string]DataFeeder
+}
+
+func newDataFeederRegistry() *dataFeederRegistry {
+	return &dataFeederRegistry{feeders: make(map[string]DataFeeder)}
+}
+
+// get returns the cached DataFeeder for path, loading it with load on first
+// use.
+func (r *dataFeederRegistry) get(path string, mode FeederMode, load func(string, FeederMode) (DataFeeder, error)) (DataFeeder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if feeder, ok := r.feeders[path]; ok {
+		return feeder, nil
+	}
+	feeder, err := load(path, mode)
+	if err != nil {
+		return nil, err
+	}
+	r.feeders[path] = feeder
+	return feeder, nil
+}
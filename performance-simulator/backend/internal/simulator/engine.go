@@ -1,7 +1,10 @@
 package simulator
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math"
@@ -13,9 +16,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/origo-stack/performance-simulator/internal/metrics"
+	"github.com/origo-stack/performance-simulator/internal/simulator/providers"
 	"github.com/origo-stack/performance-simulator/internal/websocket"
 	"github.com/sirupsen/logrus"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"gorm.io/gorm"
 )
 
@@ -27,6 +33,10 @@ type Engine struct {
 	authManager      *AuthManager
 	varResolver      *VariableResolver
 	validationEngine *ValidationEngine
+	validationStats  *ValidationStatsTracker
+	grpcPool         *grpcConnPool
+	store            SimulationStore
+	capabilityProber *CapabilityProber
 	activeSimulations sync.Map
 	simulationCounter int64
 	validationResults sync.Map // Store validation results by simulation ID
@@ -40,8 +50,33 @@ type SimulationConfig struct {
 	Method           string               `json:"method"`
 	Headers          map[string]string    `json:"headers"`
 	Body             *RequestBody         `json:"body"`
+	// Protocol selects the ProtocolDriver requests go through; it defaults
+	// to ProtocolHTTP. GRPC/GraphQL hold the extra configuration their
+	// respective drivers need and are ignored otherwise.
+	Protocol         Protocol             `json:"protocol,omitempty"`
+	GRPC             *GRPCConfig          `json:"grpc,omitempty"`
+	GraphQL          *GraphQLConfig       `json:"graphql,omitempty"`
 	Auth             *AuthConfig          `json:"auth"`
+	// TLSConfig configures the client's transport-level TLS (mTLS, CA
+	// pinning, SPIFFE workload identity), independent of Auth.Type — see
+	// TLSConfig's doc comment for why these are kept separate.
+	TLSConfig        *TLSConfig           `json:"tls_config,omitempty"`
 	Validation       *ResponseValidation  `json:"validation,omitempty"`
+	CaptureRules     []CaptureRule        `json:"capture_rules,omitempty"`
+	// RequestCorpus, if set (see corpus_import.go), makes every virtual user
+	// sample its request from this weighted pool each tick instead of
+	// always hitting TargetURL, so an imported HAR/OpenAPI traffic mix can
+	// be replayed at load instead of a single fixed endpoint.
+	RequestCorpus    *RequestCorpus       `json:"request_corpus,omitempty"`
+	// Seed drives every random_* template function for this simulation and
+	// its virtual users. If zero, StartSimulation fills in a random one.
+	// The effective value is recorded on the simulation's status so a run
+	// can be reproduced exactly via the /replay endpoint.
+	Seed             int64                `json:"seed,omitempty"`
+	// Locale selects the providers.Dataset (see VariableResolver.Locale)
+	// this simulation's name/address/phone/company template functions draw
+	// from by default, e.g. "de_DE". Defaults to providers.DefaultLocale.
+	Locale           string               `json:"locale,omitempty"`
 	ContentType      string               `json:"content_type"`
 	MaxRPS           int64                `json:"max_rps"`           // Changed to int64 for millions of RPS
 	MinRPS           int64                `json:"min_rps"`           // Starting RPS (default: 1)
@@ -93,6 +128,11 @@ type SimulationStatus struct {
 	Config          *SimulationConfig        `json:"config"`
 }
 
+// stopGracePeriod bounds how long Simulation.Stop waits for in-flight
+// executeRequest goroutines to finish before finalizing metrics anyway, so a
+// stuck request can't block a StopSimulation call forever.
+const stopGracePeriod = 30 * time.Second
+
 // Simulation represents an active performance test
 type Simulation struct {
 	config        *SimulationConfig
@@ -103,22 +143,111 @@ type Simulation struct {
 	successCount  int64
 	errorCount    int64
 	mu            sync.RWMutex
-	client        *http.Client
-	responseTimes []time.Duration
-	timeSeries    *TimeSeriesMetrics
+	client         *http.Client
+	responseDigest *metrics.Digest
+	timeSeries     *TimeSeriesMetrics
+
+	// wg tracks every in-flight executeRequest goroutine. Stop waits on it
+	// (bounded by its ctx) before computing final percentiles, so a shutdown
+	// can't race with requests still writing into responseDigest/errorCount.
+	wg sync.WaitGroup
+
+	// stopOnce and finalizeOnce guarantee that, no matter how many of
+	// runSimulation's own completion, a StopSimulation request, and a
+	// DeleteSimulation request race to end this simulation, cancel fires
+	// exactly once and the completion broadcast/unregistration fires exactly
+	// once. See Simulation.Stop and Engine.finishSimulation.
+	stopOnce     sync.Once
+	finalizeOnce sync.Once
+
+	// simResolver holds variables captured with simulation scope, shared by
+	// every virtual user in this simulation but isolated from other
+	// simulations. vuResolvers holds one child resolver per virtual-user
+	// slot so capture rules scoped to virtual_user never leak across users;
+	// vuCounter round-robins fired requests across those slots, since the
+	// engine fires requests from a shared worker pool rather than running a
+	// persistent goroutine per virtual user.
+	simResolver *VariableResolver
+	vuResolvers []*VariableResolver
+	vuCounter   int64
+
+	// tsBuffer accumulates time-series points between persistence flushes
+	// (see flushTimeSeriesBuffer) so the once-a-second reportMetrics tick
+	// doesn't issue a database round-trip per point. Only reportMetrics's
+	// single goroutine ever touches it, so it needs no lock of its own.
+	tsBuffer []TimeSeriesPoint
+}
+
+// timeSeriesFlushSize is how many buffered points flushTimeSeriesBuffer
+// accumulates before writing them to the store in one batch.
+const timeSeriesFlushSize = 10
+
+// flushTimeSeriesBuffer writes sim's buffered time-series points to store
+// and clears the buffer, regardless of whether it has reached
+// timeSeriesFlushSize; callers decide when a flush is due.
+func (sim *Simulation) flushTimeSeriesBuffer(store SimulationStore) {
+	if len(sim.tsBuffer) == 0 {
+		return
+	}
+	if err := store.AppendTimeSeriesPoints(sim.config.ID, sim.tsBuffer); err != nil {
+		logrus.Errorf("Failed to persist time series for simulation %d: %v", sim.config.ID, err)
+	}
+	sim.tsBuffer = sim.tsBuffer[:0]
 }
 
 // NewEngine creates a new simulation engine
 func NewEngine(db *gorm.DB, metricsCollector *metrics.Collector, wsHub *websocket.Hub) *Engine {
-	return &Engine{
+	validationEngine := NewValidationEngine()
+	validationEngine.SetGoldenStore(NewGormGoldenStore(db))
+
+	store := NewGormSimulationStore(db)
+
+	e := &Engine{
 		db:               db,
 		metricsCollector: metricsCollector,
 		wsHub:            wsHub,
 		authManager:      NewAuthManager(),
 		varResolver:      NewVariableResolver(),
-		validationEngine: NewValidationEngine(),
+		validationEngine: validationEngine,
+		validationStats:  NewValidationStatsTracker(),
+		grpcPool:         newGRPCConnPool(),
+		store:            store,
+		capabilityProber: NewCapabilityProber(),
 		simulationCounter: 0,
 	}
+
+	wsHub.SetSnapshotProvider(e.snapshotForPath)
+	StartRetentionWorker(context.Background(), store, DefaultSimulationRetentionPolicy(), time.Hour)
+
+	if profiles, err := store.ListServiceProfiles(); err == nil {
+		for _, profile := range profiles {
+			e.capabilityProber.StartProbing(profile.BaseURL, profile.CapabilityPaths)
+		}
+	}
+
+	return e
+}
+
+// snapshotForPath answers the websocket hub's sync_response/get_snapshot
+// requests. It currently understands "simulations/{id}/history", returning
+// that simulation's full recorded time-series points (unlike
+// "simulations/{id}/metrics", which only ever carries the latest tick) so a
+// newly connected client can backfill a chart without replaying every
+// metrics_update since the run started. Large histories are split
+// transparently by the hub's writePump, not here.
+func (e *Engine) snapshotForPath(path string) (interface{}, bool) {
+	var simulationID int64
+	if _, err := fmt.Sscanf(path, "simulations/%d/history", &simulationID); err != nil {
+		return nil, false
+	}
+
+	simValue, ok := e.activeSimulations.Load(simulationID)
+	if !ok {
+		return nil, false
+	}
+
+	sim := simValue.(*Simulation)
+	return sim.timeSeries.GetPointsSince(time.Time{}), true
 }
 
 // StartSimulation initiates a new performance test
@@ -138,40 +267,123 @@ func (e *Engine) StartSimulation(c *gin.Context) {
 		return
 	}
 
+	if _, err := e.launchSimulation(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create HTTP client: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"simulation_id": config.ID,
+		"status":        "started",
+		"message":       fmt.Sprintf("Simulation '%s' started successfully", config.Name),
+		"seed":          config.Seed,
+	})
+}
+
+// ReplaySimulation starts a new simulation cloned from a prior one's stored
+// configuration, reusing its seed so every random_* template value and
+// virtual-user RNG sequence reproduces exactly across the two runs.
+func (e *Engine) ReplaySimulation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation id"})
+		return
+	}
+
+	existing, ok := e.activeSimulations.Load(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Simulation not found"})
+		return
+	}
+	original := existing.(*Simulation)
+
+	config := *original.config
+	config.ID = atomic.AddInt64(&e.simulationCounter, 1)
+
+	if err := e.validateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Configuration validation failed: " + err.Error()})
+		return
+	}
+
+	if _, err := e.launchSimulation(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create HTTP client: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"simulation_id": config.ID,
+		"replayed_from": id,
+		"status":        "started",
+		"seed":          config.Seed,
+		"message":       fmt.Sprintf("Replay of simulation %d started as %d", id, config.ID),
+	})
+}
+
+// launchSimulation creates the HTTP client and per-scope variable resolvers
+// for config, registers it as active, and starts it running in the
+// background. It's shared by StartSimulation and ReplaySimulation so both
+// paths build a simulation identically.
+func (e *Engine) launchSimulation(config *SimulationConfig) (*Simulation, error) {
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
 	// Create simulation context
 	ctx, cancel := context.WithTimeout(context.Background(), config.Duration)
-	
+
 	// Set up authentication configuration
 	if config.Auth != nil {
 		e.authManager.SetAuthConfig(config.ID, config.Auth)
 	}
-	
-	// Create HTTP client with authentication support
-	client, err := e.authManager.CreateHTTPClientWithAuth(config.Auth)
+
+	// Create HTTP client, with mTLS/TLS transport wired up if configured
+	client, err := e.authManager.CreateHTTPClientWithAuth(config.TLSConfig)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create HTTP client: " + err.Error()})
-		return
+		cancel()
+		return nil, err
 	}
-	
+
 	// Initialize simulation
 	sim := &Simulation{
-		config: &config,
+		config: config,
 		status: &SimulationStatus{
 			ID:        config.ID,
 			Name:      config.Name,
 			Status:    "starting",
 			StartTime: time.Now(),
 		},
-		ctx:    ctx,
-		cancel: cancel,
-		client: client,
-		responseTimes: make([]time.Duration, 0, 10000),
+		ctx:            ctx,
+		cancel:         cancel,
+		client:         client,
+		responseDigest: metrics.NewDigest(),
 		timeSeries: &TimeSeriesMetrics{
 			SimulationID: config.ID,
 			Points:       make([]TimeSeriesPoint, 0, 10000),
 		},
 	}
 
+	sim.simResolver = NewChildVariableResolver(e.varResolver, config.Seed)
+	if config.Locale != "" {
+		sim.simResolver.Locale = config.Locale
+	}
+	// Give this simulation its own data feeder registry (rather than
+	// inheriting whatever e.varResolver happens to have, which is shared by
+	// every simulation the engine runs) so two simulations reading the same
+	// CSV path each rotate through it independently. Assigning it before
+	// the vuResolvers loop below means every virtual user's resolver picks
+	// up the same registry pointer (see NewChildVariableResolver) and so
+	// shares one cursor per file across the whole simulation.
+	sim.simResolver.EnableDataFeeders()
+	vuCount := config.ConcurrentUsers
+	if vuCount < 1 {
+		vuCount = 1
+	}
+	sim.vuResolvers = make([]*VariableResolver, vuCount)
+	for i := range sim.vuResolvers {
+		sim.vuResolvers[i] = NewChildVariableResolver(sim.simResolver, config.Seed+int64(i)+1)
+		sim.vuResolvers[i].VUID = i
+	}
+
 	// Store simulation
 	e.activeSimulations.Store(config.ID, sim)
 
@@ -181,36 +393,99 @@ func (e *Engine) StartSimulation(c *gin.Context) {
 	// Save configuration to database
 	go e.saveSimulationToDB(sim)
 
-	c.JSON(http.StatusCreated, gin.H{
-		"simulation_id": config.ID,
-		"status":       "started",
-		"message":      fmt.Sprintf("Simulation '%s' started successfully", config.Name),
+	return sim, nil
+}
+
+// Stop cancels the simulation and waits, bounded by ctx, for every in-flight
+// executeRequest goroutine to finish before drawing final percentiles from
+// responseDigest. It is safe to call concurrently or more than once — cancel
+// and the metrics drain only ever run once, funneled through stopOnce, so a
+// StopSimulation request racing the simulation's own natural completion can't
+// double-run either step. Returns ctx's error if the grace period elapsed
+// with requests still in flight.
+func (sim *Simulation) Stop(ctx context.Context) error {
+	var stopErr error
+	sim.stopOnce.Do(func() {
+		sim.cancel()
+
+		done := make(chan struct{})
+		go func() {
+			sim.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			stopErr = ctx.Err()
+			logrus.Warnf("simulation %d: grace period elapsed with requests still in flight", sim.config.ID)
+		}
+
+		sim.mu.Lock()
+		if sim.responseDigest.Count() > 0 {
+			sim.status.ResponseTimes = sim.responseDigest.ResponseTimes()
+		}
+		if sim.status.EndTime == nil {
+			endTime := time.Now()
+			sim.status.EndTime = &endTime
+		}
+		sim.mu.Unlock()
+	})
+	return stopErr
+}
+
+// finishSimulation drives a simulation to a stop via Simulation.Stop, then
+// performs the broadcast and unregistration that must happen exactly once
+// regardless of which caller (runSimulation's own completion, a
+// StopSimulation request, a DeleteSimulation request) wins the race to finish
+// it first; finalizeOnce guards that part separately from Stop's own
+// stopOnce since cancel/drain and broadcast/unregister can be triggered from
+// different call sites at different times.
+func (e *Engine) finishSimulation(sim *Simulation, status string) {
+	ctx, cancel := context.WithTimeout(context.Background(), stopGracePeriod)
+	defer cancel()
+
+	if err := sim.Stop(ctx); err != nil {
+		logrus.Warnf("simulation %d: %v", sim.config.ID, err)
+	}
+
+	sim.finalizeOnce.Do(func() {
+		sim.mu.Lock()
+		sim.status.Status = status
+		sim.mu.Unlock()
+
+		e.calculateFinalMetrics(sim)
+
+		if status == "stopped" {
+			e.wsHub.Broadcast("simulation_stopped", gin.H{
+				"simulation_id": sim.config.ID,
+				"status":        status,
+				"end_time":      sim.status.EndTime,
+			})
+		} else {
+			e.wsHub.Broadcast("simulation_completed", sim.status)
+		}
+
+		e.activeSimulations.Delete(sim.config.ID)
+		e.authManager.ClearAuthConfig(sim.config.ID)
+
+		sim.flushTimeSeriesBuffer(e.store)
+		e.saveSimulationToDB(sim)
+		if err := e.store.SaveSimulationResult(sim.status); err != nil {
+			logrus.Errorf("Failed to save final result for simulation %d: %v", sim.config.ID, err)
+		}
 	})
 }
 
 // runSimulation executes the performance test
 func (e *Engine) runSimulation(sim *Simulation) {
-	defer func() {
-		sim.status.Status = "completed"
-		endTime := time.Now()
-		sim.status.EndTime = &endTime
-		
-		// Calculate final metrics
-		e.calculateFinalMetrics(sim)
-		
-		// Broadcast final status
-		e.wsHub.Broadcast("simulation_completed", sim.status)
-		
-		// Clean up
-		sim.cancel()
-	}()
+	defer e.finishSimulation(sim, "completed")
 
 	logrus.Infof("Starting simulation: %s (ID: %d)", sim.config.Name, sim.config.ID)
 	sim.status.Status = "running"
 
 	// Create worker pool
 	workerPool := make(chan struct{}, sim.config.ConcurrentUsers)
-	var wg sync.WaitGroup
 
 	// Start metrics reporting goroutine
 	go e.reportMetrics(sim)
@@ -234,8 +509,8 @@ func (e *Engine) runSimulation(sim *Simulation) {
 			for i := 0; i < int(targetRPS); i++ {
 				select {
 				case workerPool <- struct{}{}:
-					wg.Add(1)
-					go e.executeRequest(sim, workerPool, &wg)
+					sim.wg.Add(1)
+					go e.executeRequest(sim, workerPool)
 				case <-sim.ctx.Done():
 					return
 				default:
@@ -247,16 +522,44 @@ func (e *Engine) runSimulation(sim *Simulation) {
 }
 
 // resolveVariables resolves dynamic variables in the simulation configuration
-func (e *Engine) resolveVariables(config *SimulationConfig) *SimulationConfig {
+// using resolver, which should be the virtual user's scoped resolver so that
+// variables captured from earlier responses (see applyCaptureRules) are
+// visible to later requests on the same virtual user/simulation/global scope.
+func (e *Engine) resolveVariables(config *SimulationConfig, resolver *VariableResolver) (*SimulationConfig, error) {
+	resolver.BeginIteration()
+
+	name, err := resolver.Resolve(config.Name)
+	if err != nil {
+		return nil, fmt.Errorf("resolve name: %w", err)
+	}
+	targetURL, err := resolver.Resolve(config.TargetURL)
+	if err != nil {
+		return nil, fmt.Errorf("resolve target URL: %w", err)
+	}
+	body, err := resolver.ResolveBody(config.Body)
+	if err != nil {
+		return nil, fmt.Errorf("resolve body: %w", err)
+	}
+	contentType, err := resolver.Resolve(config.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("resolve content type: %w", err)
+	}
+
 	resolved := &SimulationConfig{
 		ID:              config.ID,
-		Name:            e.varResolver.Resolve(config.Name),
-		TargetURL:       e.varResolver.Resolve(config.TargetURL),
+		Name:            name,
+		TargetURL:       targetURL,
 		Method:          config.Method,
 		Headers:         config.Headers, // Will be resolved later
-		Body:            e.varResolver.ResolveBody(config.Body),
+		Body:            body,
+		Protocol:        config.Protocol,
+		GRPC:            config.GRPC,
+		GraphQL:         config.GraphQL,
 		Auth:            config.Auth,
-		ContentType:     e.varResolver.Resolve(config.ContentType),
+		Validation:      config.Validation,
+		CaptureRules:    config.CaptureRules,
+		RequestCorpus:   config.RequestCorpus,
+		ContentType:     contentType,
 		MaxRPS:          config.MaxRPS,
 		MinRPS:          config.MinRPS,
 		Duration:        config.Duration,
@@ -266,58 +569,64 @@ func (e *Engine) resolveVariables(config *SimulationConfig) *SimulationConfig {
 		ScaleMode:       config.ScaleMode,
 		SampleInterval:  config.SampleInterval,
 	}
-	return resolved
+	return resolved, nil
 }
 
 // executeRequest performs a single HTTP request
-func (e *Engine) executeRequest(sim *Simulation, workerPool <-chan struct{}, wg *sync.WaitGroup) {
+func (e *Engine) executeRequest(sim *Simulation, workerPool <-chan struct{}) {
 	defer func() {
 		<-workerPool
-		wg.Done()
+		sim.wg.Done()
 	}()
 
-	startTime := time.Now()
-	
-	// Resolve variables in configuration
-	resolvedConfig := e.resolveVariables(sim.config)
-	
-	// Build request body
-	body, contentType, err := e.buildRequestBody(resolvedConfig)
-	if err != nil {
-		atomic.AddInt64(&sim.errorCount, 1)
-		logrus.Debugf("Failed to build request body: %v", err)
+	// Re-check cancellation immediately after acquiring a worker slot: the
+	// slot may have sat in workerPool's buffer for a while, and at
+	// millions-of-RPS we'd rather skip a request outright than start one
+	// just as the simulation is being torn down.
+	if sim.ctx.Err() != nil {
 		return
 	}
 
-	// Create HTTP request with body
-	req, err := http.NewRequestWithContext(sim.ctx, resolvedConfig.Method, resolvedConfig.TargetURL, body)
-	if err != nil {
-		atomic.AddInt64(&sim.errorCount, 1)
-		return
-	}
+	startTime := time.Now()
 
-	// Set Content-Type if we have one
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
+	// Round-robin this request across the simulation's virtual-user scoped
+	// resolvers so captured variables (see applyCaptureRules) persist across
+	// a user's requests without leaking into other users.
+	vuIndex := int(uint64(atomic.AddInt64(&sim.vuCounter, 1)) % uint64(len(sim.vuResolvers)))
+	resolver := sim.vuResolvers[vuIndex]
+
+	// If this simulation was imported with a RequestCorpus (see
+	// corpus_import.go), sample this request from it instead of always
+	// hitting sim.config's fixed TargetURL, so a mega-scale run reproduces
+	// a realistic mix of endpoints rather than hammering one.
+	requestConfig := sim.config
+	if sim.config.RequestCorpus != nil {
+		if entry := sim.config.RequestCorpus.Next(); entry != nil {
+			requestConfig = sim.config.withCorpusEntry(entry)
+		}
 	}
 
-	// Add resolved headers
-	resolvedHeaders := e.varResolver.ResolveHeaders(resolvedConfig.Headers)
-	for key, value := range resolvedHeaders {
-		req.Header.Set(key, value)
+	// Resolve variables in configuration
+	resolvedConfig, err := e.resolveVariables(requestConfig, resolver)
+	if err != nil {
+		atomic.AddInt64(&sim.errorCount, 1)
+		logrus.Debugf("Failed to resolve variables: %v", err)
+		return
 	}
 
-	// Apply authentication
-	if err := e.authManager.ApplyAuth(req, sim.status.ID); err != nil {
+	// Dispatch the request through resolvedConfig.Protocol's driver (HTTP by
+	// default); gRPC/GraphQL drivers synthesize an equivalent *http.Response
+	// so everything below treats every protocol the same way.
+	driver, err := e.driverFor(resolvedConfig.Protocol)
+	if err != nil {
 		atomic.AddInt64(&sim.errorCount, 1)
-		logrus.Debugf("Authentication failed: %v", err)
+		logrus.Debugf("Unsupported protocol: %v", err)
 		return
 	}
 
-	// Execute request
-	resp, err := sim.client.Do(req)
+	resp, responseBody, err := driver.Execute(sim, resolvedConfig, resolver)
 	responseTime := time.Since(startTime)
-	
+
 	atomic.AddInt64(&sim.requestCount, 1)
 
 	if err != nil {
@@ -336,18 +645,17 @@ func (e *Engine) executeRequest(sim *Simulation, workerPool <-chan struct{}, wg
 	}
 	defer resp.Body.Close()
 
-	// Read response body for validation
-	responseBody, bodyReadErr := e.readResponseBody(resp)
-	if bodyReadErr != nil {
-		atomic.AddInt64(&sim.errorCount, 1)
-		logrus.Debugf("Failed to read response body: %v", bodyReadErr)
-		return
+	// Capture correlation variables from this response before validation,
+	// so a capture rule can feed the very next request even if this one
+	// fails validation.
+	if len(resolvedConfig.CaptureRules) > 0 {
+		e.applyCaptureRules(sim, resolver, resolvedConfig.CaptureRules, resp, responseBody)
 	}
 
 	// Perform validation if configured
 	var validationResult *ValidationResult
 	if resolvedConfig.Validation != nil {
-		validationResult = e.validationEngine.ValidateResponse(resp, responseBody, resolvedConfig.Validation, responseTime)
+		validationResult = e.validationEngine.ValidateResponseForSimulation(resp, responseBody, resolvedConfig.Validation, responseTime, resolvedConfig.ID)
 	} else {
 		// Default validation (status code only)
 		validationResult = &ValidationResult{
@@ -370,10 +678,17 @@ func (e *Engine) executeRequest(sim *Simulation, workerPool <-chan struct{}, wg
 	} else {
 		atomic.AddInt64(&sim.errorCount, 1)
 	}
+	protocol := resolvedConfig.Protocol
+	if protocol == "" {
+		protocol = ProtocolHTTP
+	}
+	e.metricsCollector.RecordRequestOutcome(sim.config.ID, string(protocol), validationResult.Passed)
 
-	// Record response time and validation results
+	// Feed the response time into the simulation's streaming digest rather
+	// than buffering every sample, which is what made multi-million-request
+	// runs blow up memory.
 	sim.mu.Lock()
-	sim.responseTimes = append(sim.responseTimes, responseTime)
+	sim.responseDigest.Add(responseTime)
 	sim.mu.Unlock()
 
 	// Store validation results for reporting
@@ -564,16 +879,11 @@ func (e *Engine) reportMetrics(sim *Simulation) {
 			sim.status.FailedRequests = atomic.LoadInt64(&sim.errorCount)
 			sim.status.CurrentRPS = currentRPS
 			
-			// Calculate response time percentiles
+			// Calculate response time percentiles from the streaming digest
 			var avgResponseTime float64
-			if len(sim.responseTimes) > 0 {
-				sim.status.ResponseTimes = metrics.CalculatePercentiles(sim.responseTimes)
-				// Calculate average response time for time series
-				var totalTime time.Duration
-				for _, rt := range sim.responseTimes {
-					totalTime += rt
-				}
-				avgResponseTime = float64(totalTime.Nanoseconds()) / float64(len(sim.responseTimes)) / 1000000 // Convert to milliseconds
+			if sim.responseDigest.Count() > 0 {
+				sim.status.ResponseTimes = sim.responseDigest.ResponseTimes()
+				avgResponseTime = float64(sim.status.ResponseTimes.Mean.Nanoseconds()) / 1000000 // Convert to milliseconds
 			}
 			
 			// Calculate current target RPS
@@ -599,6 +909,11 @@ func (e *Engine) reportMetrics(sim *Simulation) {
 			}
 			sim.timeSeries.AddPoint(timePoint)
 
+			sim.tsBuffer = append(sim.tsBuffer, timePoint)
+			if len(sim.tsBuffer) >= timeSeriesFlushSize {
+				sim.flushTimeSeriesBuffer(e.store)
+			}
+
 			// Broadcast metrics with time-series data
 			metricsUpdate := map[string]interface{}{
 				"simulation":  sim.status,
@@ -621,8 +936,8 @@ func (e *Engine) calculateFinalMetrics(sim *Simulation) {
 
 	// Final response time statistics
 	sim.mu.Lock()
-	if len(sim.responseTimes) > 0 {
-		sim.status.ResponseTimes = metrics.CalculatePercentiles(sim.responseTimes)
+	if sim.responseDigest.Count() > 0 {
+		sim.status.ResponseTimes = sim.responseDigest.ResponseTimes()
 	}
 	sim.mu.Unlock()
 }
@@ -632,7 +947,7 @@ func (e *Engine) validateConfig(config *SimulationConfig) error {
 	if config.Name == "" {
 		return fmt.Errorf("simulation name is required")
 	}
-	if config.TargetURL == "" {
+	if config.TargetURL == "" && (config.RequestCorpus == nil || len(config.RequestCorpus.Entries) == 0) {
 		return fmt.Errorf("target URL is required")
 	}
 	if config.MaxRPS <= 0 {
@@ -641,6 +956,11 @@ func (e *Engine) validateConfig(config *SimulationConfig) error {
 	if config.Duration <= 0 {
 		return fmt.Errorf("duration must be greater than 0")
 	}
+	if config.Locale != "" {
+		if _, err := providers.Get(config.Locale); err != nil {
+			return fmt.Errorf("invalid locale: %w", err)
+		}
+	}
 	if config.ConcurrentUsers <= 0 {
 		return fmt.Errorf("concurrent users must be greater than 0")
 	}
@@ -657,6 +977,11 @@ func (e *Engine) validateConfig(config *SimulationConfig) error {
 		}
 	}
 
+	// Validate transport-level TLS configuration
+	if err := e.validateTLSConfig(config.TLSConfig); err != nil {
+		return fmt.Errorf("TLS configuration failed: %v", err)
+	}
+
 	// Validate response validation configuration
 	if config.Validation != nil {
 		if err := e.validateValidationConfig(config.Validation); err != nil {
@@ -664,6 +989,41 @@ func (e *Engine) validateConfig(config *SimulationConfig) error {
 		}
 	}
 
+	// Validate capture rules
+	for i, rule := range config.CaptureRules {
+		if rule.Name == "" {
+			return fmt.Errorf("capture rule %d: name is required", i)
+		}
+		if rule.Source != CaptureSourceStatus && rule.Expr == "" {
+			return fmt.Errorf("capture rule %q: expr is required for source %q", rule.Name, rule.Source)
+		}
+	}
+
+	// Validate protocol-specific configuration
+	switch config.Protocol {
+	case "", ProtocolHTTP:
+		// nothing extra to validate
+	case ProtocolGRPC:
+		if config.GRPC == nil {
+			return fmt.Errorf("grpc configuration is required when protocol is %q", ProtocolGRPC)
+		}
+		if config.GRPC.FullMethod == "" {
+			return fmt.Errorf("grpc: full_method is required")
+		}
+		if config.GRPC.ProtoFile == "" && config.GRPC.ReflectionTarget == "" && config.TargetURL == "" {
+			return fmt.Errorf("grpc: either proto_file, reflection_target, or target_url is required to resolve the method")
+		}
+	case ProtocolGraphQL:
+		if config.GraphQL == nil {
+			return fmt.Errorf("graphql configuration is required when protocol is %q", ProtocolGraphQL)
+		}
+		if config.GraphQL.Query == "" {
+			return fmt.Errorf("graphql: query is required")
+		}
+	default:
+		return fmt.Errorf("unsupported protocol: %q", config.Protocol)
+	}
+
 	return nil
 }
 
@@ -692,24 +1052,63 @@ func (e *Engine) validateAuth(auth *AuthConfig) error {
 		}
 		
 	case AuthTypeJWT:
-		if auth.JWT == nil || auth.JWT.Token == "" {
+		if auth.JWT == nil {
+			return fmt.Errorf("JWT configuration is required")
+		}
+		if auth.JWT.Algorithm != "" {
+			// Self-signed minting mode: parse the signing key now so a bad
+			// algorithm/key pair fails fast at simulation start instead of
+			// on the first request.
+			if _, err := newJWTSigner(auth.JWT); err != nil {
+				return fmt.Errorf("invalid JWT signing configuration: %w", err)
+			}
+		} else if auth.JWT.Token == "" {
 			return fmt.Errorf("JWT token is required")
 		}
-		
+
 	case AuthTypeOAuth2:
 		if auth.OAuth2 == nil || auth.OAuth2.ClientID == "" || auth.OAuth2.ClientSecret == "" || auth.OAuth2.TokenURL == "" {
 			return fmt.Errorf("OAuth2 client credentials and token URL are required")
 		}
 		
-	case AuthTypeClientCert:
-		if auth.ClientCert == nil || auth.ClientCert.CertFile == "" || auth.ClientCert.KeyFile == "" {
-			return fmt.Errorf("client certificate and key files are required")
-		}
-		
 	default:
 		return fmt.Errorf("unsupported auth type: %s", auth.Type)
 	}
-	
+
+	return nil
+}
+
+// validateTLSConfig validates a simulation's transport-level TLS
+// configuration (see SimulationConfig.TLSConfig), independent of auth
+// validation — tlsCfg may be nil (plain HTTP/TLS with no client cert or
+// pinning) or configure only some of its fields (e.g. just ServerName/
+// MinVersion with no client certificate at all).
+func (e *Engine) validateTLSConfig(tlsCfg *TLSConfig) error {
+	if tlsCfg == nil {
+		return nil
+	}
+
+	if tlsCfg.P12File != "" && (tlsCfg.CertFile != "" || tlsCfg.KeyFile != "") {
+		return fmt.Errorf("specify either a PKCS#12 bundle or cert_file/key_file, not both")
+	}
+	if tlsCfg.P12File == "" && (tlsCfg.CertFile != "") != (tlsCfg.KeyFile != "") {
+		return fmt.Errorf("client certificate and key files must be set together")
+	}
+
+	if _, err := tlsVersionFromString(tlsCfg.MinVersion); err != nil {
+		return err
+	}
+	if _, err := cipherSuitesFromNames(tlsCfg.CipherSuites); err != nil {
+		return err
+	}
+
+	// Actually parse the cert/key (or PKCS#12 bundle) and any CA bundle
+	// now, so a typo'd path or a mismatched key/leaf pair fails fast at
+	// simulation start instead of on the first request.
+	if _, err := loadTLSMaterial(tlsCfg, e.authManager.SecretManager); err != nil {
+		return fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
 	return nil
 }
 
@@ -822,10 +1221,16 @@ func (e *Engine) GetTimeSeriesData(c *gin.Context) {
 		limit = parsedLimit
 	}
 	
+	id, err := strconv.ParseInt(simulationID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation id"})
+		return
+	}
+
 	// Find simulation
-	if simValue, exists := e.activeSimulations.Load(simulationID); exists {
+	if simValue, exists := e.activeSimulations.Load(id); exists {
 		sim := simValue.(*Simulation)
-		
+
 		var points []TimeSeriesPoint
 		if since.IsZero() {
 			// Return last 'limit' points
@@ -845,7 +1250,7 @@ func (e *Engine) GetTimeSeriesData(c *gin.Context) {
 				points = points[len(points)-limit:]
 			}
 		}
-		
+
 		c.JSON(http.StatusOK, gin.H{
 			"simulation_id": simulationID,
 			"points":        points,
@@ -853,8 +1258,69 @@ func (e *Engine) GetTimeSeriesData(c *gin.Context) {
 		})
 		return
 	}
-	
-	c.JSON(http.StatusNotFound, gin.H{"error": "Simulation not found"})
+
+	points, err := e.store.GetTimeSeriesSince(id, since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load time series: " + err.Error()})
+		return
+	}
+	if len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"simulation_id": simulationID,
+		"points":        points,
+		"total_points":  len(points),
+	})
+}
+
+// ExportSimulation writes a finished (or running) simulation's time series
+// as JSON (default) or, with ?format=csv, as a timestamp/rps/target_rps/
+// response_time/error_rate/active_users CSV - e.g. for loading into a
+// spreadsheet once a run has left the in-memory activeSimulations map.
+func (e *Engine) ExportSimulation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation id"})
+		return
+	}
+
+	var points []TimeSeriesPoint
+	if simValue, exists := e.activeSimulations.Load(id); exists {
+		points = simValue.(*Simulation).timeSeries.GetPointsSince(time.Time{})
+	} else {
+		points, err = e.store.GetTimeSeriesSince(id, time.Time{}, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load time series: " + err.Error()})
+			return
+		}
+	}
+
+	if c.Query("format") == "csv" {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=simulation-%d.csv", id))
+		writer := csv.NewWriter(c.Writer)
+		writer.Write([]string{"timestamp", "rps", "target_rps", "response_time", "error_rate", "active_users"})
+		for _, p := range points {
+			writer.Write([]string{
+				p.Timestamp.Format(time.RFC3339),
+				strconv.FormatFloat(p.RPS, 'f', -1, 64),
+				strconv.FormatFloat(p.TargetRPS, 'f', -1, 64),
+				strconv.FormatFloat(p.ResponseTime, 'f', -1, 64),
+				strconv.FormatFloat(p.ErrorRate, 'f', -1, 64),
+				strconv.Itoa(p.ActiveUsers),
+			})
+		}
+		writer.Flush()
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"simulation_id": id,
+		"points":        points,
+		"total_points":  len(points),
+	})
 }
 
 // GetMegaScalePresets returns predefined mega-scale simulation configurations
@@ -905,18 +1371,28 @@ func (e *Engine) GetMegaScalePresets(c *gin.Context) {
 	c.JSON(http.StatusOK, presets)
 }
 
-// saveSimulationToDB persists simulation configuration and results
+// saveSimulationToDB persists sim's configuration and current status via
+// e.store, so a simulation started just before a process restart is still
+// visible (if no longer running) through ListSimulations/GetSimulation.
 func (e *Engine) saveSimulationToDB(sim *Simulation) {
-	// Implementation would save to database
-	// This is a placeholder for the database operations
-	logrus.Infof("Saving simulation %d to database", sim.config.ID)
+	sim.mu.RLock()
+	status := *sim.status
+	sim.mu.RUnlock()
+
+	if err := e.store.SaveSimulationConfig(sim.config, &status); err != nil {
+		logrus.Errorf("Failed to save simulation %d: %v", sim.config.ID, err)
+	}
 }
 
 // Additional handler methods for REST API endpoints
+
+// ListSimulations returns running simulations (always included, regardless
+// of filter, since they haven't been persisted as "finished" yet) followed
+// by a store-backed, paginated, filterable page of past runs. Query
+// params: status, name, start_from/start_to (RFC3339), limit, offset.
 func (e *Engine) ListSimulations(c *gin.Context) {
 	var simulations []map[string]interface{}
-	
-	// Get active simulations
+
 	e.activeSimulations.Range(func(key, value interface{}) bool {
 		sim := value.(*Simulation)
 		simData := map[string]interface{}{
@@ -933,22 +1409,65 @@ func (e *Engine) ListSimulations(c *gin.Context) {
 		simulations = append(simulations, simData)
 		return true
 	})
-	
+
+	filter := SimulationListFilter{
+		Status: c.Query("status"),
+		Name:   c.Query("name"),
+	}
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil {
+		filter.Limit = v
+	}
+	if v, err := strconv.Atoi(c.Query("offset")); err == nil {
+		filter.Offset = v
+	}
+	if v, err := time.Parse(time.RFC3339, c.Query("start_from")); err == nil {
+		filter.StartFrom = v
+	}
+	if v, err := time.Parse(time.RFC3339, c.Query("start_to")); err == nil {
+		filter.StartTo = v
+	}
+
+	persisted, total, err := e.store.ListSimulations(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list simulations: " + err.Error()})
+		return
+	}
+	for _, p := range persisted {
+		if _, stillActive := e.activeSimulations.Load(p.Status.ID); stillActive {
+			continue
+		}
+		simulations = append(simulations, map[string]interface{}{
+			"id":               p.Status.ID,
+			"name":            p.Status.Name,
+			"status":          p.Status.Status,
+			"start_time":      p.Status.StartTime,
+			"total_requests":  p.Status.TotalRequests,
+			"successful_reqs": p.Status.SuccessfulReqs,
+			"failed_requests": p.Status.FailedRequests,
+			"config":          p.Config,
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"simulations": simulations,
 		"total":       len(simulations),
+		"total_persisted": total,
 	})
 }
 
 func (e *Engine) GetSimulation(c *gin.Context) {
-	simulationID := c.Param("id")
-	
+	simulationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation id"})
+		return
+	}
+
 	if simValue, exists := e.activeSimulations.Load(simulationID); exists {
 		sim := simValue.(*Simulation)
-		
+
 		// Get time-series data
 		timeSeriesPoints := sim.timeSeries.GetPointsSince(time.Time{})
-		
+
 		simData := map[string]interface{}{
 			"id":               sim.status.ID,
 			"name":            sim.status.Name,
@@ -962,44 +1481,26 @@ func (e *Engine) GetSimulation(c *gin.Context) {
 			"time_series":     timeSeriesPoints,
 			"response_times":  sim.status.ResponseTimes,
 		}
-		
+
 		c.JSON(http.StatusOK, simData)
 		return
 	}
-	
-	c.JSON(http.StatusNotFound, gin.H{"error": "Simulation not found"})
-}
 
-func (e *Engine) StopSimulation(c *gin.Context) {
-	simulationID := c.Param("id")
-	
-	if simValue, exists := e.activeSimulations.Load(simulationID); exists {
-		sim := simValue.(*Simulation)
-		
-		// Cancel the simulation context
-		sim.cancel()
-		
-		// Update status
-		sim.mu.Lock()
-		sim.status.Status = "stopped"
-		endTime := time.Now()
-		sim.status.EndTime = &endTime
-		sim.mu.Unlock()
-		
-		// Remove from active simulations
-		e.activeSimulations.Delete(simulationID)
-		
-		// Broadcast stop event
-		e.wsHub.Broadcast("simulation_stopped", gin.H{
-			"simulation_id": simulationID,
-			"status":        "stopped",
-			"end_time":      endTime,
-		})
-		
+	if persisted, err := e.store.GetSimulation(simulationID); err == nil {
+		timeSeriesPoints, err := e.store.GetTimeSeriesSince(simulationID, time.Time{}, 0)
+		if err != nil {
+			logrus.Warnf("Failed to load time series for simulation %d: %v", simulationID, err)
+		}
 		c.JSON(http.StatusOK, gin.H{
-			"message":       "Simulation stopped successfully",
-			"simulation_id": simulationID,
-			"status":        "stopped",
+			"id":               persisted.Status.ID,
+			"name":            persisted.Status.Name,
+			"status":          persisted.Status.Status,
+			"start_time":      persisted.Status.StartTime,
+			"total_requests":  persisted.Status.TotalRequests,
+			"successful_reqs": persisted.Status.SuccessfulReqs,
+			"failed_requests": persisted.Status.FailedRequests,
+			"config":          persisted.Config,
+			"time_series":     timeSeriesPoints,
 		})
 		return
 	}
@@ -1007,22 +1508,55 @@ func (e *Engine) StopSimulation(c *gin.Context) {
 	c.JSON(http.StatusNotFound, gin.H{"error": "Simulation not found"})
 }
 
+// StopSimulation stops a running simulation. It waits (bounded by
+// stopGracePeriod) for in-flight requests to finish via Simulation.Stop
+// before responding, so a client that immediately re-reads the simulation
+// sees its final state rather than one still settling.
+func (e *Engine) StopSimulation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation id"})
+		return
+	}
+
+	simValue, exists := e.activeSimulations.Load(id)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Simulation not found"})
+		return
+	}
+	sim := simValue.(*Simulation)
+
+	e.finishSimulation(sim, "stopped")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Simulation stopped successfully",
+		"simulation_id": id,
+		"status":        "stopped",
+	})
+}
+
 func (e *Engine) DeleteSimulation(c *gin.Context) {
-	simulationID := c.Param("id")
-	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation id"})
+		return
+	}
+
 	// First try to stop if running
-	if simValue, exists := e.activeSimulations.Load(simulationID); exists {
+	if simValue, exists := e.activeSimulations.Load(id); exists {
 		sim := simValue.(*Simulation)
-		sim.cancel()
-		e.activeSimulations.Delete(simulationID)
+		e.finishSimulation(sim, "stopped")
 	}
-	
-	// TODO: Delete from database if implemented
-	// db.Where("id = ?", simulationID).Delete(&Simulation{})
-	
+
+	if err := e.store.DeleteSimulation(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete simulation: " + err.Error()})
+		return
+	}
+	e.validationStats.Reset(id)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Simulation deleted successfully",
-		"simulation_id": simulationID,
+		"simulation_id": id,
 	})
 }
 
@@ -1075,23 +1609,53 @@ func (e *Engine) GetConfigurations(c *gin.Context) {
 }
 
 func (e *Engine) SaveConfiguration(c *gin.Context) {
-	var config SimulationConfig
-	if err := c.ShouldBindJSON(&config); err != nil {
+	var request struct {
+		SimulationConfig
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+		IsDefault   bool     `json:"is_default"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration: " + err.Error()})
 		return
 	}
-	
+
 	// Validate configuration
-	if err := e.validateConfig(&config); err != nil {
+	if err := e.validateConfig(&request.SimulationConfig); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Configuration validation failed: " + err.Error()})
 		return
 	}
-	
-	// TODO: Save to database in production
-	// For now, just return success
+
+	saved := &SavedConfiguration{
+		Name:        request.Name,
+		Description: request.Description,
+		Tags:        request.Tags,
+		IsDefault:   request.IsDefault,
+		Config:      &request.SimulationConfig,
+	}
+	if err := e.store.SaveConfiguration(saved); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save configuration: " + err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Configuration saved successfully",
-		"config":  config,
+		"config":  request.SimulationConfig,
+	})
+}
+
+// GetSavedConfigurations lists every configuration previously saved via
+// SaveConfiguration, independent of the static presets GetConfigurations
+// returns.
+func (e *Engine) GetSavedConfigurations(c *gin.Context) {
+	configs, err := e.store.ListConfigurations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list configurations: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"configurations": configs,
+		"total":          len(configs),
 	})
 }
 
@@ -1189,7 +1753,31 @@ func (e *Engine) GetServiceProfiles(c *gin.Context) {
 			},
 		},
 	}
-	
+
+	registered, err := e.store.ListServiceProfiles()
+	if err != nil {
+		logrus.Warnf("Failed to load registered service profiles: %v", err)
+	}
+	for _, profile := range registered {
+		entry := map[string]interface{}{
+			"name":        profile.Name,
+			"technology":  profile.Technology,
+			"base_url":    profile.BaseURL,
+			"endpoints":   profile.Endpoints,
+			"headers":     profile.Headers,
+			"description": profile.Description,
+		}
+		if snapshot, ok := e.capabilityProber.Get(profile.BaseURL); ok {
+			entry["actual_rps"] = snapshot.ActualRPS
+			entry["actual_p95_latency"] = snapshot.ActualP95Latency.String()
+			entry["last_probed_at"] = snapshot.LastProbedAt
+			if len(snapshot.FeatureFlags) > 0 {
+				entry["feature_flags"] = snapshot.FeatureFlags
+			}
+		}
+		serviceProfiles[profile.Name] = entry
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"service_profiles": serviceProfiles,
 		"total":           len(serviceProfiles),
@@ -1197,37 +1785,212 @@ func (e *Engine) GetServiceProfiles(c *gin.Context) {
 }
 
 func (e *Engine) CreateServiceProfile(c *gin.Context) {
-	var profile map[string]interface{}
-	if err := c.ShouldBindJSON(&profile); err != nil {
+	var request struct {
+		Name        string              `json:"name"`
+		Technology  string              `json:"technology"`
+		BaseURL     string              `json:"base_url"`
+		Endpoints   []map[string]string `json:"endpoints"`
+		Headers     map[string]string   `json:"headers"`
+		Description string              `json:"description"`
+		// CapabilityPaths are the paths CapabilityProber probes on this
+		// profile's BaseURL; defaults to defaultCapabilityPaths if empty.
+		CapabilityPaths []string `json:"capability_paths"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service profile: " + err.Error()})
 		return
 	}
-	
+
 	// Validate required fields
-	requiredFields := []string{"name", "technology", "base_url"}
-	for _, field := range requiredFields {
-		if _, exists := profile[field]; !exists {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Missing required field: %s", field)})
-			return
-		}
+	if request.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: name"})
+		return
 	}
-	
-	// TODO: Save to database in production
-	// For now, just return success
+	if request.Technology == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: technology"})
+		return
+	}
+	if request.BaseURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: base_url"})
+		return
+	}
+
+	profile := &ServiceProfileRecord{
+		Name:            request.Name,
+		Technology:      request.Technology,
+		BaseURL:         request.BaseURL,
+		Endpoints:       request.Endpoints,
+		Headers:         request.Headers,
+		Description:     request.Description,
+		CapabilityPaths: request.CapabilityPaths,
+	}
+	if err := e.store.SaveServiceProfile(profile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save service profile: " + err.Error()})
+		return
+	}
+
+	e.capabilityProber.StartProbing(profile.BaseURL, profile.CapabilityPaths)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Service profile created successfully",
 		"profile": profile,
 	})
 }
 
+// InvalidateServiceCapabilities forces base_url's next CapabilityProber read
+// to trigger a fresh probe, subject to CapabilityProber's own min-interval
+// rate limit (see capabilityMinInvalidateInterval) so a burst of calls can't
+// stampede the target.
+func (e *Engine) InvalidateServiceCapabilities(c *gin.Context) {
+	var request struct {
+		BaseURL string `json:"base_url"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if request.BaseURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: base_url"})
+		return
+	}
+
+	e.capabilityProber.Invalidate(request.BaseURL)
+	c.JSON(http.StatusOK, gin.H{"message": "Invalidation requested"})
+}
+
+// dryRunLimit bounds how many corpus entries ImportHAR/ImportOpenAPI echo
+// back for preview, so a large capture/spec doesn't bloat the response.
+const dryRunLimit = 20
+
+// ImportHAR parses an uploaded HAR 1.2 capture (field "file", or the raw
+// request body if no multipart file is present) into a RequestCorpus
+// grouped by endpoint, registers a ServiceProfile derived from it, and
+// returns the corpus plus a dry-run preview of its first entries so the
+// caller can review the generated traffic mix before starting a simulation
+// with it.
+func (e *Engine) ImportHAR(c *gin.Context) {
+	har, err := readImportPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	corpus, err := BuildCorpusFromHAR(har)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to import HAR: " + err.Error()})
+		return
+	}
+
+	profile := e.registerProfileFromCorpus(c.Query("name"), corpus)
+	c.JSON(http.StatusOK, gin.H{
+		"profile": profile,
+		"corpus":  corpus,
+		"preview": corpusPreview(corpus),
+		"total_entries": len(corpus.Entries),
+	})
+}
+
+// ImportOpenAPI parses an uploaded OpenAPI 3.x document (JSON or YAML) into
+// a RequestCorpus, one entry per operation; see BuildCorpusFromOpenAPI for
+// how request bodies are synthesized. base_url overrides the spec's own
+// servers[0].url when given.
+func (e *Engine) ImportOpenAPI(c *gin.Context) {
+	spec, err := readImportPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	corpus, err := BuildCorpusFromOpenAPI(spec, c.Query("base_url"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to import OpenAPI spec: " + err.Error()})
+		return
+	}
+
+	profile := e.registerProfileFromCorpus(c.Query("name"), corpus)
+	c.JSON(http.StatusOK, gin.H{
+		"profile": profile,
+		"corpus":  corpus,
+		"preview": corpusPreview(corpus),
+		"total_entries": len(corpus.Entries),
+	})
+}
+
+// readImportPayload returns an uploaded file's contents if the request
+// carries one under the "file" form field, falling back to the raw request
+// body so a plain `curl --data-binary @spec.yaml` works too.
+func readImportPayload(c *gin.Context) ([]byte, error) {
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open uploaded file: %w", err)
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, fmt.Errorf("read uploaded file: %w", err)
+		}
+		return data, nil
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no file uploaded and request body is empty")
+	}
+	return data, nil
+}
+
+// registerProfileFromCorpus saves a ServiceProfile summarizing corpus's
+// endpoints under name (a generated name if name is empty), so the import
+// shows up alongside manually created profiles in GetServiceProfiles.
+func (e *Engine) registerProfileFromCorpus(name string, corpus *RequestCorpus) *ServiceProfileRecord {
+	if name == "" {
+		name = fmt.Sprintf("imported-%d", time.Now().UnixNano())
+	}
+
+	endpoints := make([]map[string]string, 0, len(corpus.Entries))
+	for _, entry := range corpus.Entries {
+		endpoints = append(endpoints, map[string]string{"path": entry.URL, "method": entry.Method})
+	}
+
+	profile := &ServiceProfileRecord{
+		Name:        name,
+		Technology:  "imported",
+		BaseURL:     corpus.Entries[0].URL,
+		Endpoints:   endpoints,
+		Description: fmt.Sprintf("Imported corpus with %d endpoint(s)", len(corpus.Entries)),
+	}
+	if err := e.store.SaveServiceProfile(profile); err != nil {
+		logrus.Warnf("Failed to save imported service profile %q: %v", name, err)
+	}
+	return profile
+}
+
+// corpusPreview returns the first dryRunLimit entries of corpus, the
+// "TestConnection-style dry run" showing what a simulation using it would
+// actually send before the caller commits to starting one.
+func corpusPreview(corpus *RequestCorpus) []CorpusEntry {
+	if len(corpus.Entries) <= dryRunLimit {
+		return corpus.Entries
+	}
+	return corpus.Entries[:dryRunLimit]
+}
+
 // TestConnection tests connectivity to a target URL with authentication
 func (e *Engine) TestConnection(c *gin.Context) {
 	var testConfig struct {
-		TargetURL string     `json:"target_url"`
-		Method    string     `json:"method"`
+		TargetURL string            `json:"target_url"`
+		Method    string            `json:"method"`
 		Headers   map[string]string `json:"headers"`
-		Auth      *AuthConfig `json:"auth"`
-		Timeout   int        `json:"timeout"`
+		Auth      *AuthConfig       `json:"auth"`
+		TLSConfig *TLSConfig        `json:"tls_config,omitempty"`
+		Timeout   int               `json:"timeout"`
+		Protocol  Protocol          `json:"protocol,omitempty"`
+		GRPC      *GRPCConfig       `json:"grpc,omitempty"`
+		GraphQL   *GraphQLConfig    `json:"graphql,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&testConfig); err != nil {
@@ -1242,9 +2005,19 @@ func (e *Engine) TestConnection(c *gin.Context) {
 	if testConfig.Timeout == 0 {
 		testConfig.Timeout = 10
 	}
+	timeout := time.Duration(testConfig.Timeout) * time.Second
+
+	switch testConfig.Protocol {
+	case ProtocolGRPC:
+		e.testGRPCConnection(c, testConfig.TargetURL, testConfig.GRPC, timeout)
+		return
+	case ProtocolGraphQL:
+		e.testGraphQLConnection(c, testConfig.TargetURL, testConfig.Headers, testConfig.Auth, testConfig.TLSConfig, testConfig.GraphQL, timeout)
+		return
+	}
 
-	// Create HTTP client with authentication
-	client, err := e.authManager.CreateHTTPClientWithAuth(testConfig.Auth)
+	// Create HTTP client, with mTLS/TLS transport wired up if configured
+	client, err := e.authManager.CreateHTTPClientWithAuth(testConfig.TLSConfig)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -1277,7 +2050,7 @@ func (e *Engine) TestConnection(c *gin.Context) {
 	tempSimID := int64(99999)
 	if testConfig.Auth != nil {
 		e.authManager.SetAuthConfig(tempSimID, testConfig.Auth)
-		if err := e.authManager.ApplyAuth(req, tempSimID); err != nil {
+		if err := e.authManager.ApplyAuthForSimulation(req, tempSimID); err != nil {
 			e.authManager.ClearAuthConfig(tempSimID)
 			c.JSON(http.StatusOK, gin.H{
 				"success": false,
@@ -1321,12 +2094,158 @@ func (e *Engine) TestConnection(c *gin.Context) {
 	})
 }
 
+// testGRPCConnection checks that target's gRPC method can be resolved and,
+// when grpcCfg.FullMethod is empty, falls back to a reflection-only
+// connectivity check so the UI can verify a target before a method is
+// picked.
+func (e *Engine) testGRPCConnection(c *gin.Context, target string, grpcCfg *GRPCConfig, timeout time.Duration) {
+	if grpcCfg == nil {
+		grpcCfg = &GRPCConfig{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	startTime := time.Now()
+
+	conn, err := e.grpcPool.get(target, 0)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": "Failed to dial gRPC target: " + err.Error()})
+		return
+	}
+
+	if grpcCfg.FullMethod == "" {
+		client := grpcreflect.NewClientV1Alpha(ctx, reflectpb.NewServerReflectionClient(conn))
+		defer client.Reset()
+
+		services, err := client.ListServices()
+		responseTime := time.Since(startTime)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"success": false, "error": "Reflection failed: " + err.Error(), "responseTime": responseTime.String()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "responseTime": responseTime.String(), "services": services})
+		return
+	}
+
+	md, err := resolveGRPCMethod(ctx, conn, grpcCfg)
+	responseTime := time.Since(startTime)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": err.Error(), "responseTime": responseTime.String()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"responseTime":    responseTime.String(),
+		"method":          grpcCfg.FullMethod,
+		"inputType":       md.GetInputType().GetFullyQualifiedName(),
+		"outputType":      md.GetOutputType().GetFullyQualifiedName(),
+		"serverStreaming": md.IsServerStreaming(),
+	})
+}
+
+// testGraphQLConnection POSTs graphqlCfg's query (or a minimal introspection
+// query if none is set) to confirm the endpoint accepts GraphQL requests.
+func (e *Engine) testGraphQLConnection(c *gin.Context, target string, headers map[string]string, auth *AuthConfig, tlsCfg *TLSConfig, graphqlCfg *GraphQLConfig, timeout time.Duration) {
+	if graphqlCfg == nil {
+		graphqlCfg = &GraphQLConfig{}
+	}
+	query := graphqlCfg.Query
+	if query == "" {
+		query = "query { __typename }"
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"query":         query,
+		"variables":     graphqlCfg.Variables,
+		"operationName": graphqlCfg.OperationName,
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": "Failed to encode GraphQL request: " + err.Error()})
+		return
+	}
+
+	client, err := e.authManager.CreateHTTPClientWithAuth(tlsCfg)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": "Failed to create HTTP client: " + err.Error()})
+		return
+	}
+	client.Timeout = timeout
+
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": "Failed to create request: " + err.Error()})
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	tempSimID := int64(99999)
+	if auth != nil {
+		e.authManager.SetAuthConfig(tempSimID, auth)
+		defer e.authManager.ClearAuthConfig(tempSimID)
+		if err := e.authManager.ApplyAuthForSimulation(req, tempSimID); err != nil {
+			c.JSON(http.StatusOK, gin.H{"success": false, "error": "Authentication failed: " + err.Error()})
+			return
+		}
+	}
+
+	startTime := time.Now()
+	resp, err := client.Do(req)
+	responseTime := time.Since(startTime)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": err.Error(), "responseTime": responseTime.String()})
+		return
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := e.readResponseBody(resp)
+	c.JSON(http.StatusOK, gin.H{
+		"success":      resp.StatusCode >= 200 && resp.StatusCode < 300,
+		"statusCode":   resp.StatusCode,
+		"responseTime": responseTime.String(),
+		"preview":      string(responseBody),
+	})
+}
+
+// debugMintJWT mints a sample token from auth using the engine's shared
+// VariableResolver and returns its decoded header/claims alongside the
+// compact token, so a user can confirm a self-signed JWT config produces
+// the claims they expect without needing a live endpoint to send it to.
+func (e *Engine) debugMintJWT(c *gin.Context, auth *JWTAuth) {
+	signer, err := newJWTSigner(auth)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": "invalid JWT signing configuration: " + err.Error()})
+		return
+	}
+
+	token, claims, err := signer.mint(auth, e.varResolver)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": "failed to mint sample JWT: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"token":   token,
+		"header": gin.H{
+			"alg": signer.method.Alg(),
+			"kid": signer.keyID,
+		},
+		"claims": claims,
+	})
+}
+
 // TestAuth tests authentication configuration
 func (e *Engine) TestAuth(c *gin.Context) {
 	var testConfig struct {
-		AuthType  string     `json:"auth_type"`
-		TargetURL string     `json:"target_url"`
+		AuthType  string      `json:"auth_type"`
+		TargetURL string      `json:"target_url"`
 		Config    *AuthConfig `json:"config"`
+		TLSConfig *TLSConfig  `json:"tls_config,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&testConfig); err != nil {
@@ -1334,6 +2253,16 @@ func (e *Engine) TestAuth(c *gin.Context) {
 		return
 	}
 
+	// Self-signed JWT minting can be debugged without a target: mint a
+	// sample token and return its decoded header/claims, rather than
+	// requiring a live endpoint just to inspect what the simulation will
+	// send.
+	if testConfig.TargetURL == "" && testConfig.Config != nil && testConfig.Config.Type == AuthTypeJWT &&
+		testConfig.Config.JWT != nil && testConfig.Config.JWT.Algorithm != "" {
+		e.debugMintJWT(c, testConfig.Config.JWT)
+		return
+	}
+
 	if testConfig.TargetURL == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Target URL is required for auth testing"})
 		return
@@ -1347,9 +2276,16 @@ func (e *Engine) TestAuth(c *gin.Context) {
 		})
 		return
 	}
+	if err := e.validateTLSConfig(testConfig.TLSConfig); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"error":   "TLS validation failed: " + err.Error(),
+		})
+		return
+	}
 
-	// Create HTTP client with authentication
-	client, err := e.authManager.CreateHTTPClientWithAuth(testConfig.Config)
+	// Create HTTP client, with mTLS/TLS transport wired up if configured
+	client, err := e.authManager.CreateHTTPClientWithAuth(testConfig.TLSConfig)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -1374,7 +2310,7 @@ func (e *Engine) TestAuth(c *gin.Context) {
 	tempSimID := int64(99998)
 	e.authManager.SetAuthConfig(tempSimID, testConfig.Config)
 	
-	if err := e.authManager.ApplyAuth(req, tempSimID); err != nil {
+	if err := e.authManager.ApplyAuthForSimulationWithResolver(req, tempSimID, e.varResolver); err != nil {
 		e.authManager.ClearAuthConfig(tempSimID)
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -1412,15 +2348,18 @@ func (e *Engine) TestAuth(c *gin.Context) {
 // GetAvailableVariables returns all available dynamic variables
 func (e *Engine) GetAvailableVariables(c *gin.Context) {
 	variables := e.varResolver.GetAvailableVariables()
-	
+	locales := e.varResolver.LocaleSamples()
+
 	c.JSON(http.StatusOK, gin.H{
 		"variables": variables,
 		"total":     len(variables),
+		"locales":   locales,
 		"examples": map[string]string{
 			"Basic usage":     "{{username}} will be replaced with a random username",
 			"In JSON body":    `{"name": "{{full_name}}", "email": "{{email}}"}`,
 			"In headers":      "X-Request-ID: {{uuid}}",
 			"Multiple vars":   "User {{username}} created at {{timestamp}}",
+			"Locale override": `{{address.city locale="de_DE"}}`,
 		},
 	})
 }
@@ -1447,6 +2386,8 @@ func (e *Engine) recordValidationResult(sim *Simulation, resp *http.Response, re
 		record.StatusCode = resp.StatusCode
 	}
 
+	e.validationStats.Record(sim.config.ID, validation)
+
 	// Store validation results in memory for this simulation
 	key := fmt.Sprintf("validation-%d", sim.config.ID)
 	if existing, ok := e.validationResults.Load(key); ok {
@@ -1462,6 +2403,15 @@ func (e *Engine) recordValidationResult(sim *Simulation, resp *http.Response, re
 		e.validationResults.Store(key, []ValidationRecord{record})
 	}
 
+	// Persist asynchronously so a slow write never adds latency to the
+	// request that triggered it; e.store.ListValidationRecords is how a
+	// client reads history past the in-memory 1000-record cap above.
+	go func() {
+		if err := e.store.AppendValidationRecords(sim.config.ID, []ValidationRecord{record}); err != nil {
+			logrus.Errorf("Failed to persist validation record for simulation %d: %v", sim.config.ID, err)
+		}
+	}()
+
 	// Broadcast validation failures via WebSocket for real-time monitoring
 	if !validation.Passed {
 		e.wsHub.Broadcast("validation_failure", map[string]interface{}{
@@ -1483,10 +2433,14 @@ func (e *Engine) recordValidationResult(sim *Simulation, resp *http.Response, re
 }
 
 // GetValidationResults returns validation results for a simulation
+// GetValidationResults returns validation history for a simulation: the
+// in-memory cache if it's still running (or hasn't been evicted past the
+// 1000-record cap), falling back to e.store for older records a restart or
+// the cap would otherwise lose, with offset pagination via limit/offset.
 func (e *Engine) GetValidationResults(c *gin.Context) {
 	simulationID := c.Param("id")
 	key := fmt.Sprintf("validation-%s", simulationID)
-	
+
 	if results, ok := e.validationResults.Load(key); ok {
 		if records, ok := results.([]ValidationRecord); ok {
 			c.JSON(http.StatusOK, gin.H{
@@ -1498,13 +2452,41 @@ func (e *Engine) GetValidationResults(c *gin.Context) {
 		}
 	}
 
+	id, err := strconv.ParseInt(simulationID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation id"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	records, total, err := e.store.ListValidationRecords(id, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load validation records: " + err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"simulation_id": simulationID,
-		"total_records": 0,
-		"results":       []ValidationRecord{},
+		"total_records": total,
+		"results":       records,
 	})
 }
 
+// GetValidationStats returns a simulation's aggregated ValidationStats —
+// pass/fail totals and its most common validation errors ranked by
+// frequency — as maintained by ValidationStatsTracker.
+func (e *Engine) GetValidationStats(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation id"})
+		return
+	}
+
+	c.JSON(http.StatusOK, e.validationStats.Stats(id))
+}
+
 // TestValidation tests validation rules against a sample response
 func (e *Engine) TestValidation(c *gin.Context) {
 	var testRequest struct {
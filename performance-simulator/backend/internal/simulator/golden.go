@@ -0,0 +1,194 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/origo-stack/performance-simulator/internal/database"
+	"github.com/wI2L/jsondiff"
+	"gorm.io/gorm"
+)
+
+// GoldenResponse is a recorded response Body.Diff validation compares a
+// live response against: the same shape validateDiff needs regardless of
+// where it's actually persisted, which GoldenStore abstracts away.
+type GoldenResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+}
+
+// GoldenStore records and retrieves golden responses, scoped by simulation
+// ID and a caller-chosen key, the same interface-over-storage pattern
+// JSONPathEvaluator/XPathEvaluator use over their respective libraries —
+// here so ValidationEngine doesn't depend on *gorm.DB directly.
+type GoldenStore interface {
+	GetGolden(simulationID int64, key string) (*GoldenResponse, error)
+	SaveGolden(simulationID int64, key string, resp *GoldenResponse) error
+}
+
+type gormGoldenStore struct {
+	db *gorm.DB
+}
+
+// NewGormGoldenStore returns a GoldenStore backed by db's golden_responses
+// table (database.GoldenResponse).
+func NewGormGoldenStore(db *gorm.DB) GoldenStore {
+	return &gormGoldenStore{db: db}
+}
+
+func (s *gormGoldenStore) GetGolden(simulationID int64, key string) (*GoldenResponse, error) {
+	var row database.GoldenResponse
+	if err := s.db.Where("simulation_id = ? AND key = ?", simulationID, key).First(&row).Error; err != nil {
+		return nil, err
+	}
+
+	var headers map[string]string
+	if row.Headers != "" {
+		if err := json.Unmarshal([]byte(row.Headers), &headers); err != nil {
+			return nil, fmt.Errorf("decode stored headers: %w", err)
+		}
+	}
+
+	return &GoldenResponse{StatusCode: row.StatusCode, Headers: headers, Body: []byte(row.Body)}, nil
+}
+
+func (s *gormGoldenStore) SaveGolden(simulationID int64, key string, resp *GoldenResponse) error {
+	headers, err := json.Marshal(resp.Headers)
+	if err != nil {
+		return fmt.Errorf("encode headers: %w", err)
+	}
+
+	row := database.GoldenResponse{
+		SimulationID: simulationID,
+		Key:          key,
+		StatusCode:   resp.StatusCode,
+		Headers:      string(headers),
+		Body:         string(resp.Body),
+	}
+	return s.db.Where("simulation_id = ? AND key = ?", simulationID, key).
+		Assign(row).
+		FirstOrCreate(&row).Error
+}
+
+// validateDiff implements Body.Diff: it either records body as the golden
+// response for validation.Diff.Key (when Record is set) or compares body
+// against the previously recorded golden, appending one ValidationError per
+// RFC 6902 patch operation jsondiff finds between them.
+func (ve *ValidationEngine) validateDiff(resp *http.Response, body []byte, validation *BodyValidation, simulationID int64, result *ValidationResult) {
+	diff := validation.Diff
+
+	ve.mutex.RLock()
+	store := ve.goldenStore
+	ve.mutex.RUnlock()
+	if store == nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:    "diff",
+			Message: "diff validation requires a golden store; call ValidationEngine.SetGoldenStore first",
+		})
+		return
+	}
+
+	key := diff.Key
+	if key == "" {
+		key = "default"
+	}
+
+	live := &GoldenResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    flattenHeader(resp.Header),
+		Body:       body,
+	}
+
+	if diff.Record {
+		if err := store.SaveGolden(simulationID, key, live); err != nil {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:    "diff",
+				Message: fmt.Sprintf("failed to record golden response for %q: %v", key, err),
+			})
+			return
+		}
+		result.Warnings = append(result.Warnings, ValidationWarning{
+			Type:    "diff",
+			Message: fmt.Sprintf("recorded golden response for %q", key),
+		})
+		return
+	}
+
+	golden, err := store.GetGolden(simulationID, key)
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:    "diff",
+			Message: fmt.Sprintf("no golden response recorded for %q: %v (run a pass with diff.record=true first)", key, err),
+		})
+		return
+	}
+
+	goldenValue, err := canonicalizeForDiff(validation.Type, golden.Body)
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{Type: "diff", Message: fmt.Sprintf("failed to parse golden response body: %v", err)})
+		return
+	}
+	liveValue, err := canonicalizeForDiff(validation.Type, body)
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{Type: "diff", Message: fmt.Sprintf("failed to parse live response body: %v", err)})
+		return
+	}
+
+	var opts []jsondiff.Option
+	if len(diff.IgnorePaths) > 0 {
+		opts = append(opts, jsondiff.Ignores(diff.IgnorePaths...))
+	}
+
+	patch, err := jsondiff.Compare(goldenValue, liveValue, opts...)
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{Type: "diff", Message: fmt.Sprintf("failed to diff against golden response: %v", err)})
+		return
+	}
+
+	if len(patch) > 0 {
+		result.Passed = false
+	}
+	for _, op := range patch {
+		result.Errors = append(result.Errors, ValidationError{
+			Type:     "diff",
+			Field:    op.Path,
+			Expected: fmt.Sprintf("%v", op.OldValue),
+			Actual:   fmt.Sprintf("%v", op.Value),
+			Message:  fmt.Sprintf("%s %s", op.Type, op.Path),
+			Pointer:  op.Path,
+		})
+	}
+}
+
+// canonicalizeForDiff parses body into a value jsondiff can structurally
+// compare: JSON unmarshals normally (jsondiff compares by value, so key
+// order is a non-issue); XML is canonicalized by converting it into the
+// same nested-map shape CEL assertions use (xmlNodeToCELValue) rather than
+// full textual xml-c14n, which is sufficient for structural diffing and
+// avoids the added complexity of namespace-declaration canonicalization.
+func canonicalizeForDiff(bodyType BodyValidationType, body []byte) (interface{}, error) {
+	if bodyType == ValidationTypeXML {
+		doc, err := parseXMLDocument(string(body))
+		if err != nil {
+			return nil, err
+		}
+		return xmlNodeToCELValue(doc), nil
+	}
+
+	if len(body) == 0 {
+		return nil, nil
+	}
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
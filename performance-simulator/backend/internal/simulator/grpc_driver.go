@@ -0,0 +1,274 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCConfig describes how a simulation's requests are made as gRPC calls
+// instead of plain HTTP. Either ProtoFile or ReflectionTarget must be usable
+// so the method's request/response shape can be resolved without generated
+// client stubs; FullMethod names the RPC, e.g.
+// "/checkout.v1.CheckoutService/PlaceOrder". ReflectionTarget is optional —
+// when empty, the simulation's own TargetURL is queried for reflection.
+type GRPCConfig struct {
+	ProtoFile        string        `json:"proto_file,omitempty"`
+	ReflectionTarget string        `json:"reflection_target,omitempty"`
+	FullMethod       string        `json:"full_method"`
+	Keepalive        time.Duration `json:"keepalive,omitempty"`
+}
+
+// GRPCDriver executes simulation requests as unary or server-streaming gRPC
+// calls, with the request/response message shape resolved dynamically via
+// protoreflect rather than generated stubs, so a simulation can target any
+// gRPC service from its config alone. The synthesized *http.Response carries
+// the raw gRPC status code (0/codes.OK for success) as StatusCode, initial
+// metadata as Header, and trailing metadata as Trailer, so assertion scripts
+// can write `status == 0` and `trailer('grpc-message')` the same way they'd
+// write `status == 200` and `header(...)` for a plain HTTP call.
+type GRPCDriver struct {
+	engine *Engine
+}
+
+// grpcConnPool shares one *grpc.ClientConn per target authority, so a
+// simulation's worker pool reuses a single connection instead of dialing
+// per request.
+type grpcConnPool struct {
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func newGRPCConnPool() *grpcConnPool {
+	return &grpcConnPool{conns: make(map[string]*grpc.ClientConn)}
+}
+
+func (p *grpcConnPool) get(authority string, ka time.Duration) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if conn, ok := p.conns[authority]; ok {
+		return conn, nil
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if ka > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    ka,
+			Timeout: ka,
+		}))
+	}
+
+	conn, err := grpc.Dial(authority, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", authority, err)
+	}
+	p.conns[authority] = conn
+	return conn, nil
+}
+
+// Execute marshals resolvedConfig.Body as the request message via
+// dynamicpb-backed dynamic.Message (whose JSON codec follows the same
+// proto3 JSON mapping as google.golang.org/protobuf/encoding/protojson),
+// invokes the RPC, and synthesizes an *http.Response carrying the raw gRPC
+// status/headers/trailers plus the response message re-encoded as JSON, so
+// validation/capture/metrics can treat a gRPC call like any other response.
+func (d *GRPCDriver) Execute(sim *Simulation, resolvedConfig *SimulationConfig, resolver *VariableResolver) (*http.Response, []byte, error) {
+	grpcCfg := resolvedConfig.GRPC
+	if grpcCfg == nil {
+		return nil, nil, fmt.Errorf("grpc: simulation has no grpc configuration")
+	}
+
+	conn, err := d.engine.grpcPool.get(resolvedConfig.TargetURL, grpcCfg.Keepalive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	md, err := resolveGRPCMethod(sim.ctx, conn, grpcCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("grpc: resolve method: %w", err)
+	}
+
+	requestJSON := requestBodyContent(resolvedConfig)
+
+	reqMsg := dynamic.NewMessage(md.GetInputType())
+	if strings.TrimSpace(requestJSON) != "" {
+		if err := reqMsg.UnmarshalJSON([]byte(requestJSON)); err != nil {
+			return nil, nil, fmt.Errorf("grpc: unmarshal request JSON: %w", err)
+		}
+	}
+
+	headers, err := resolver.ResolveHeaders(resolvedConfig.Headers)
+	if err != nil {
+		logrus.Debugf("grpc: failed to resolve headers: %v", err)
+	}
+	ctx := metadata.NewOutgoingContext(sim.ctx, metadata.New(headers))
+
+	fullMethod := grpcCfg.FullMethod
+	if !strings.HasPrefix(fullMethod, "/") {
+		fullMethod = "/" + fullMethod
+	}
+
+	var headerMD, trailerMD metadata.MD
+	var respErr error
+	var body []byte
+	if md.IsServerStreaming() {
+		body, respErr = d.invokeServerStream(ctx, conn, fullMethod, reqMsg, md, &headerMD, &trailerMD)
+	} else {
+		respMsg := dynamic.NewMessage(md.GetOutputType())
+		respErr = conn.Invoke(ctx, fullMethod, reqMsg, respMsg, grpc.Header(&headerMD), grpc.Trailer(&trailerMD))
+		if respErr == nil {
+			body, respErr = respMsg.MarshalJSON()
+		}
+	}
+
+	statusCode, body := grpcResultToHTTP(respErr, body)
+	resp := &http.Response{
+		StatusCode: statusCode,
+		Header:     http.Header(headerMD),
+		Trailer:    http.Header(trailerMD),
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+	return resp, body, nil
+}
+
+// invokeServerStream drives a server-streaming RPC to completion, collecting
+// every message into a JSON array so the rest of the pipeline sees one body
+// to validate/capture against. headerOut/trailerOut receive the stream's
+// initial and trailing metadata once available.
+func (d *GRPCDriver) invokeServerStream(ctx context.Context, conn *grpc.ClientConn, fullMethod string, reqMsg *dynamic.Message, md *desc.MethodDescriptor, headerOut, trailerOut *metadata.MD) ([]byte, error) {
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(reqMsg); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	var messages []json.RawMessage
+	for {
+		respMsg := dynamic.NewMessage(md.GetOutputType())
+		if err := stream.RecvMsg(respMsg); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		encoded, err := respMsg.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, encoded)
+	}
+
+	if header, err := stream.Header(); err == nil {
+		*headerOut = header
+	}
+	*trailerOut = stream.Trailer()
+
+	return json.Marshal(messages)
+}
+
+// resolveGRPCMethod resolves grpcCfg.FullMethod to its *desc.MethodDescriptor,
+// either by parsing the configured .proto file or by querying the target's
+// reflection service.
+func resolveGRPCMethod(ctx context.Context, conn *grpc.ClientConn, grpcCfg *GRPCConfig) (*desc.MethodDescriptor, error) {
+	serviceName, methodName, err := splitFullMethod(grpcCfg.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	if grpcCfg.ProtoFile != "" {
+		parser := protoparse.Parser{ImportPaths: []string{"."}}
+		fds, err := parser.ParseFiles(grpcCfg.ProtoFile)
+		if err != nil {
+			return nil, fmt.Errorf("parse proto file %s: %w", grpcCfg.ProtoFile, err)
+		}
+		for _, fd := range fds {
+			if sd := fd.FindService(serviceName); sd != nil {
+				if md := sd.FindMethodByName(methodName); md != nil {
+					return md, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("method %s not found in %s", grpcCfg.FullMethod, grpcCfg.ProtoFile)
+	}
+
+	client := grpcreflect.NewClientV1Alpha(ctx, reflectpb.NewServerReflectionClient(conn))
+	defer client.Reset()
+
+	sd, err := client.ResolveService(serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("resolve service %s via reflection: %w", serviceName, err)
+	}
+	md := sd.FindMethodByName(methodName)
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found on service %s", methodName, serviceName)
+	}
+	return md, nil
+}
+
+// splitFullMethod splits "/package.Service/Method" (the leading slash is
+// optional) into its service and method name.
+func splitFullMethod(fullMethod string) (service, method string, err error) {
+	trimmed := strings.TrimPrefix(fullMethod, "/")
+	idx := strings.LastIndex(trimmed, "/")
+	if idx <= 0 {
+		return "", "", fmt.Errorf("invalid full method %q, expected /package.Service/Method", fullMethod)
+	}
+	return trimmed[:idx], trimmed[idx+1:], nil
+}
+
+// requestBodyContent returns the raw JSON a gRPC request message is
+// unmarshaled from, reusing the same RequestBody.Content field an HTTP JSON
+// body would use.
+func requestBodyContent(config *SimulationConfig) string {
+	if config.Body == nil {
+		return ""
+	}
+	return config.Body.Content
+}
+
+// grpcResultToHTTP maps a gRPC call's outcome to the (status, body) shape
+// the rest of the engine's validation and capture logic expects. StatusCode
+// is the raw gRPC status code (codes.OK == 0 on success), not an HTTP
+// status, so assertion scripts use ordinary gRPC conventions: `status == 0`.
+func grpcResultToHTTP(err error, successBody []byte) (int, []byte) {
+	if err == nil {
+		return int(codes.OK), successBody
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return int(codes.Unknown), body
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"code":    st.Code().String(),
+		"message": st.Message(),
+	})
+	return int(st.Code()), body
+}
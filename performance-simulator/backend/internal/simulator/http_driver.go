@@ -0,0 +1,62 @@
+package simulator
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HTTPDriver is the default ProtocolDriver, executing a plain HTTP(S)
+// request the same way the engine always has.
+type HTTPDriver struct {
+	engine *Engine
+}
+
+// Execute builds resolvedConfig's body and headers, applies authentication,
+// and performs the request.
+func (d *HTTPDriver) Execute(sim *Simulation, resolvedConfig *SimulationConfig, resolver *VariableResolver) (*http.Response, []byte, error) {
+	e := d.engine
+
+	body, contentType, contentLength, err := e.buildRequestBody(resolvedConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("build request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(sim.ctx, resolvedConfig.Method, resolvedConfig.TargetURL, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if contentLength >= 0 {
+		req.ContentLength = contentLength
+	}
+
+	resolvedHeaders, err := resolver.ResolveHeaders(resolvedConfig.Headers)
+	if err != nil {
+		logrus.Debugf("Failed to resolve headers: %v", err)
+	}
+	for key, value := range resolvedHeaders {
+		req.Header.Set(key, value)
+	}
+
+	if err := e.authManager.ApplyAuthForSimulationWithResolver(req, sim.status.ID, resolver); err != nil {
+		return nil, nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	resp, err := sim.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := e.readResponseBody(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, responseBody, nil
+}
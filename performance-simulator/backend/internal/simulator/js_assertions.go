@@ -0,0 +1,101 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// jsAssertionTimeout bounds how long a single JS assertion (Type ==
+// AssertionTypeJS) may run before it's interrupted, the same way
+// compileCELProgram's CEL environment never lets an expression loop
+// forever — a hand-authored assertion script shouldn't be able to hang a
+// load test.
+const jsAssertionTimeout = 2 * time.Second
+
+// compileJSProgram parses (but does not run) expression, caching the
+// result by a hash of its source the same way compileCELProgram caches
+// compiled CEL programs, so a scenario that runs the same JS assertion
+// thousands of times only parses it once.
+func (ae *AssertionEngine) compileJSProgram(expression string) (*goja.Program, error) {
+	hash := sha256Hex(expression)
+
+	ae.jsMutex.RLock()
+	cached, ok := ae.jsProgramCache[hash]
+	ae.jsMutex.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	program, err := goja.Compile("assertion.js", expression, false)
+	if err != nil {
+		return nil, fmt.Errorf("compile JS expression %q: %w", expression, err)
+	}
+
+	ae.jsMutex.Lock()
+	ae.jsProgramCache[hash] = program
+	ae.jsMutex.Unlock()
+	return program, nil
+}
+
+// evaluateJS compiles (or reuses a cached compilation of) expression and
+// runs it in a fresh goja.Runtime against ctx, exposing a Postman-style
+// `pm.response` object (json(), headers, code, responseTime) the script
+// reads to decide whether the assertion passed. A fresh runtime per
+// evaluation keeps one assertion's globals from leaking into another's,
+// at the cost of re-registering `pm` every call; scripts are expected to
+// be small boolean expressions, not programs that justify a pooled VM.
+func (ae *AssertionEngine) evaluateJS(expression string, ctx *assertionContext) (interface{}, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, fmt.Errorf("JS expression cannot be empty")
+	}
+
+	program, err := ae.compileJSProgram(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := goja.New()
+	timer := time.AfterFunc(jsAssertionTimeout, func() {
+		vm.Interrupt("JS assertion timed out")
+	})
+	defer timer.Stop()
+
+	if err := vm.Set("pm", buildPMObject(vm, ctx)); err != nil {
+		return nil, fmt.Errorf("initialize JS assertion context: %w", err)
+	}
+
+	value, err := vm.RunProgram(program)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate JS expression %q: %w", expression, err)
+	}
+	return value.Export(), nil
+}
+
+// buildPMObject builds the sandboxed `pm` object a JS assertion script
+// sees: pm.response.json()/headers/code/responseTime, mirroring the
+// identifiers the hand-rolled expression language (assertionContext.lookup)
+// and CEL (evaluateCEL's "response" variable) expose, under the Postman-
+// style name JS assertion authors are most likely to already know.
+func buildPMObject(vm *goja.Runtime, ctx *assertionContext) *goja.Object {
+	response := vm.NewObject()
+	response.Set("code", ctx.status)
+	response.Set("responseTime", ctx.durationMs)
+	response.Set("headers", flattenHeader(ctx.headers))
+	response.Set("json", func(goja.FunctionCall) goja.Value {
+		if ctx.jsonErr != nil {
+			panic(vm.NewGoError(fmt.Errorf("response.json(): invalid JSON body: %w", ctx.jsonErr)))
+		}
+		return vm.ToValue(ctx.jsonValue)
+	})
+	response.Set("text", func(goja.FunctionCall) goja.Value {
+		return vm.ToValue(ctx.body)
+	})
+
+	pm := vm.NewObject()
+	pm.Set("response", response)
+	return pm
+}
@@ -0,0 +1,201 @@
+package simulator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// validateJSONSchema validates body against validation's JSON Schema —
+// either inlined in JSONSchema or looked up by JSONSchemaRef in the schema
+// store (RegisterSchema) — supporting draft-04 through 2020-12 and $ref
+// resolution against every schema in the store, via gojsonschema. Compiled
+// schemas are cached by a hash of their source so repeated runs of the same
+// scenario don't recompile on every request.
+func (ve *ValidationEngine) validateJSONSchema(body []byte, validation *BodyValidation, result *ValidationResult) {
+	schemaStr := validation.JSONSchema
+	if schemaStr == "" {
+		ve.mutex.RLock()
+		ref, ok := ve.schemaStore[validation.JSONSchemaRef]
+		ve.mutex.RUnlock()
+		if !ok {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:    "json_schema",
+				Message: fmt.Sprintf("unknown json_schema_ref %q: register it with RegisterSchema first", validation.JSONSchemaRef),
+			})
+			return
+		}
+		schemaStr = ref
+	}
+
+	schema, err := ve.compileJSONSchema(schemaStr)
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:    "json_schema",
+			Message: fmt.Sprintf("invalid JSON schema: %v", err),
+		})
+		return
+	}
+
+	schemaResult, err := schema.Validate(gojsonschema.NewBytesLoader(body))
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:    "json_schema",
+			Message: fmt.Sprintf("schema validation error: %v", err),
+		})
+		return
+	}
+
+	for _, resultErr := range schemaResult.Errors() {
+		result.Passed = false
+
+		expected := resultErr.Type()
+		if v, ok := resultErr.Details()["expected"]; ok {
+			expected = fmt.Sprintf("%v", v)
+		}
+
+		result.Errors = append(result.Errors, ValidationError{
+			Type:     "json_schema",
+			Field:    resultErr.Field(),
+			Expected: expected,
+			Actual:   fmt.Sprintf("%v", resultErr.Value()),
+			Message:  resultErr.Description(),
+			Pointer:  jsonSchemaFieldToPointer(resultErr.Field()),
+		})
+	}
+}
+
+// compileJSONSchema compiles schemaStr, resolving $ref against every schema
+// in ve.schemaStore, and caches the result by sha256(schemaStr).
+func (ve *ValidationEngine) compileJSONSchema(schemaStr string) (*gojsonschema.Schema, error) {
+	hash := sha256Hex(schemaStr)
+
+	ve.mutex.RLock()
+	cached, ok := ve.jsonSchemaCache[hash]
+	ve.mutex.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	loader := gojsonschema.NewSchemaLoader()
+
+	ve.mutex.RLock()
+	for ref, raw := range ve.schemaStore {
+		if err := loader.AddSchema(ref, gojsonschema.NewStringLoader(raw)); err != nil {
+			ve.mutex.RUnlock()
+			return nil, fmt.Errorf("register referenced schema %q: %w", ref, err)
+		}
+	}
+	ve.mutex.RUnlock()
+
+	schema, err := loader.Compile(gojsonschema.NewStringLoader(schemaStr))
+	if err != nil {
+		return nil, err
+	}
+
+	ve.mutex.Lock()
+	ve.jsonSchemaCache[hash] = schema
+	ve.mutex.Unlock()
+	return schema, nil
+}
+
+// RegisterSchema adds schemaJSON to the schema store under uri, so it can be
+// referenced either directly via BodyValidation.JSONSchemaRef or as a $ref
+// target from another schema being validated.
+func (ve *ValidationEngine) RegisterSchema(uri string, schemaJSON string) {
+	ve.mutex.Lock()
+	defer ve.mutex.Unlock()
+	ve.schemaStore[uri] = schemaJSON
+}
+
+// jsonSchemaFieldToPointer converts a gojsonschema Field() path (dot
+// notation rooted at "(root)", e.g. "(root).items.0.name") into the
+// equivalent RFC 6901 JSON pointer ("/items/0/name").
+func jsonSchemaFieldToPointer(field string) string {
+	field = strings.TrimPrefix(field, "(root)")
+	field = strings.TrimPrefix(field, ".")
+	if field == "" {
+		return "/"
+	}
+	return "/" + strings.ReplaceAll(field, ".", "/")
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// funcFormatChecker adapts a plain func(interface{}) bool to gojsonschema's
+// FormatChecker interface, so RegisterFormatChecker's callers don't need to
+// define a named type of their own.
+type funcFormatChecker func(interface{}) bool
+
+func (f funcFormatChecker) IsFormat(input interface{}) bool {
+	return f(input)
+}
+
+// RegisterFormatChecker registers a custom "format" keyword checker (e.g. a
+// "ports" format analogous to docker-compose's) with the underlying
+// gojsonschema format checker registry, which is global rather than
+// per-engine, so this takes effect for every ValidationEngine in the
+// process.
+func (ve *ValidationEngine) RegisterFormatChecker(name string, fn func(interface{}) bool) {
+	gojsonschema.FormatCheckers.Add(name, funcFormatChecker(fn))
+}
+
+var registerDefaultFormatCheckersOnce sync.Once
+
+// registerDefaultFormatCheckers adds the "duration", "uuid", and
+// "date-time" format checkers this package depends on, beyond whatever
+// gojsonschema already ships with. Idempotent and safe to call from every
+// NewValidationEngine, since the underlying registry is a process-wide
+// global.
+func registerDefaultFormatCheckers() {
+	registerDefaultFormatCheckersOnce.Do(func() {
+		gojsonschema.FormatCheckers.Add("uuid", funcFormatChecker(isValidUUIDFormat))
+		gojsonschema.FormatCheckers.Add("date-time", funcFormatChecker(isValidDateTimeFormat))
+		gojsonschema.FormatCheckers.Add("duration", funcFormatChecker(isValidDurationFormat))
+	})
+}
+
+var uuidFormatPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isValidUUIDFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return true // format checkers only constrain strings; anything else is the "type" keyword's job
+	}
+	return uuidFormatPattern.MatchString(s)
+}
+
+func isValidDateTimeFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return true
+	}
+	_, err := time.Parse(time.RFC3339, s)
+	return err == nil
+}
+
+// durationFormatPattern matches an ISO 8601 duration, e.g. "P3DT4H5M6S".
+var durationFormatPattern = regexp.MustCompile(`^P(\d+Y)?(\d+M)?(\d+W)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+
+func isValidDurationFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return true
+	}
+	if s == "P" || s == "" {
+		return false
+	}
+	return durationFormatPattern.MatchString(s)
+}
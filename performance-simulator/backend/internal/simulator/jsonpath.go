@@ -0,0 +1,114 @@
+package simulator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ohler55/ojg/jp"
+)
+
+// JSONPathEvaluator resolves a path expression against parsed JSON data. It
+// is an interface — rather than calling the underlying library directly —
+// so AssertionEngine and ValidationEngine share one implementation and a
+// different library or a lighter in-house evaluator can be swapped in later
+// without touching either call site.
+type JSONPathEvaluator interface {
+	// Evaluate resolves path against data. A path matching zero nodes
+	// returns (nil, nil); one matching node is unwrapped to that node's
+	// value; more than one is returned as a []interface{} so it can be used
+	// with contains(...) or equality assertions as a membership check.
+	Evaluate(data interface{}, path string) (interface{}, error)
+}
+
+// ojgJSONPathEvaluator implements JSONPathEvaluator using ohler55/ojg/jp,
+// which supports the full JSONPath grammar (wildcards `[*]`, recursive
+// descent `..`, filters `[?(@.price>10)]`, slices `[0:3]`) as well as RFC
+// 6901 JSON Pointer syntax ("/items/0/id") as an alternative to JSONPath.
+type ojgJSONPathEvaluator struct{}
+
+// NewJSONPathEvaluator returns the engine's default JSONPathEvaluator.
+func NewJSONPathEvaluator() JSONPathEvaluator {
+	return &ojgJSONPathEvaluator{}
+}
+
+// defaultJSONPathEvaluator is shared by AssertionEngine and ValidationEngine
+// so both honor the same path syntax without threading an evaluator through
+// every constructor.
+var defaultJSONPathEvaluator = NewJSONPathEvaluator()
+
+func (e *ojgJSONPathEvaluator) Evaluate(data interface{}, path string) (interface{}, error) {
+	expr, err := parseJSONPathExpr(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+
+	results := expr.Get(data)
+	switch len(results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return results[0], nil
+	default:
+		return results, nil
+	}
+}
+
+// parseJSONPathExpr parses path as a JSON Pointer when it looks like one
+// (a leading '/', or empty for the document root), and as JSONPath
+// otherwise.
+func parseJSONPathExpr(path string) (jp.Expr, error) {
+	if path == "" || strings.HasPrefix(path, "/") {
+		return parseJSONPointer(path)
+	}
+	return jp.ParseString(path)
+}
+
+// parseJSONPointer builds a jp.Expr from an RFC 6901 JSON Pointer such as
+// "/items/0/id" by hand: ojg/jp has no pointer parser of its own, only the
+// JSONPath grammar. Each segment's "~1"/"~0" escapes are undone per the
+// spec, and an all-digit segment addresses an array index (jp.N) rather
+// than an object key (jp.C). An empty pointer addresses the document root.
+func parseJSONPointer(pointer string) (jp.Expr, error) {
+	if pointer == "" {
+		return jp.R(), nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("json pointer must start with '/', got %q", pointer)
+	}
+
+	expr := jp.R()
+	for _, segment := range strings.Split(pointer[1:], "/") {
+		token := strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+		if isJSONPointerIndex(token) {
+			n, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, fmt.Errorf("invalid json pointer index %q: %w", token, err)
+			}
+			expr = expr.N(n)
+			continue
+		}
+		expr = expr.C(token)
+	}
+	return expr, nil
+}
+
+// isJSONPointerIndex reports whether token is a valid RFC 6901 array index:
+// "0", or a non-zero-leading sequence of digits.
+func isJSONPointerIndex(token string) bool {
+	if token == "" {
+		return false
+	}
+	if token == "0" {
+		return true
+	}
+	if token[0] == '0' {
+		return false
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,352 @@
+package simulator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// jwksMinRefreshInterval bounds how often a cache miss on a key ID triggers
+// a re-fetch of a JWKS document, so a burst of requests carrying an unknown
+// kid (a misconfigured client, or an attacker probing) can't hammer the
+// provider's JWKS endpoint.
+const jwksMinRefreshInterval = time.Minute
+
+// oidcDiscoveryDocument is the subset of a provider's
+// /.well-known/openid-configuration response AuthManager needs.
+type oidcDiscoveryDocument struct {
+	JWKSURI       string `json:"jwks_uri"`
+	TokenEndpoint string `json:"token_endpoint"`
+}
+
+// jwk is one entry of a JWKS "keys" array, as returned by a provider's JWKS
+// endpoint. Only the fields needed to reconstruct an RSA or EC public key are
+// decoded.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksKeySet is the parsed, cached form of one JWKS document, keyed by key
+// ID. nextRefresh enforces jwksMinRefreshInterval between re-fetches
+// triggered by a kid miss.
+type jwksKeySet struct {
+	mu          sync.RWMutex
+	keys        map[string]interface{} // kid -> *rsa.PublicKey | *ecdsa.PublicKey
+	nextRefresh time.Time
+}
+
+func (ks *jwksKeySet) key(kid string) (interface{}, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// oidcCacheKeyFor identifies an OIDC discovery/JWKS configuration, so
+// simulations pointed at the same issuer or JWKS URL share one discovery
+// round-trip and one parsed key set.
+func oidcCacheKeyFor(auth *JWTAuth) string {
+	if auth.JWKSURL != "" {
+		return auth.JWKSURL
+	}
+	return auth.OIDCIssuer
+}
+
+// resolveJWKSURI returns the JWKS endpoint for auth, fetching and caching
+// the OIDC discovery document if only OIDCIssuer is set.
+func (am *AuthManager) resolveJWKSURI(auth *JWTAuth) (jwksURI string, tokenEndpoint string, err error) {
+	if auth.JWKSURL != "" && auth.OIDCIssuer == "" {
+		return auth.JWKSURL, auth.RefreshURL, nil
+	}
+
+	am.oidcMu.Lock()
+	defer am.oidcMu.Unlock()
+
+	if cached, ok := am.oidcDiscovery[auth.OIDCIssuer]; ok {
+		jwksURI = cached.JWKSURI
+		tokenEndpoint = cached.TokenEndpoint
+	} else {
+		discoveryURL := strings.TrimRight(auth.OIDCIssuer, "/") + "/.well-known/openid-configuration"
+		resp, getErr := http.Get(discoveryURL)
+		if getErr != nil {
+			return "", "", fmt.Errorf("OIDC discovery: %w", getErr)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return "", "", fmt.Errorf("OIDC discovery at %s returned status %d", discoveryURL, resp.StatusCode)
+		}
+
+		var doc oidcDiscoveryDocument
+		if decodeErr := json.NewDecoder(resp.Body).Decode(&doc); decodeErr != nil {
+			return "", "", fmt.Errorf("decode OIDC discovery document: %w", decodeErr)
+		}
+		am.oidcDiscovery[auth.OIDCIssuer] = &doc
+		jwksURI, tokenEndpoint = doc.JWKSURI, doc.TokenEndpoint
+	}
+
+	if auth.JWKSURL != "" {
+		jwksURI = auth.JWKSURL
+	}
+	// RefreshURL, when set, overrides the discovered token endpoint — the
+	// same field JWT static-token mode uses for its refresh URL, repurposed
+	// here since both mean "where to go back to the provider for a token".
+	if auth.RefreshURL != "" {
+		tokenEndpoint = auth.RefreshURL
+	}
+	return jwksURI, tokenEndpoint, nil
+}
+
+// jwksSetFor returns the cached key set for jwksURI, fetching it on first
+// use.
+func (am *AuthManager) jwksSetFor(jwksURI string) (*jwksKeySet, error) {
+	am.jwksMu.RLock()
+	existing, ok := am.jwksSets[jwksURI]
+	am.jwksMu.RUnlock()
+	if ok {
+		return existing, nil
+	}
+
+	am.jwksMu.Lock()
+	defer am.jwksMu.Unlock()
+	if existing, ok := am.jwksSets[jwksURI]; ok {
+		return existing, nil
+	}
+
+	ks, err := am.fetchJWKS(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	am.jwksSets[jwksURI] = ks
+	return ks, nil
+}
+
+// fetchJWKS GETs and parses the JWKS document at jwksURI into a jwksKeySet.
+func (am *AuthManager) fetchJWKS(jwksURI string) (*jwksKeySet, error) {
+	resp, err := http.Get(jwksURI)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint %s returned status %d", jwksURI, resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode JWKS document: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, k := range doc.Keys {
+		pubKey, err := k.publicKey()
+		if err != nil {
+			// Skip keys we can't parse (e.g. an "oct" symmetric key some
+			// providers list for introspection) rather than failing the
+			// whole set.
+			continue
+		}
+		keys[k.Kid] = pubKey
+	}
+
+	return &jwksKeySet{keys: keys, nextRefresh: am.now().Add(jwksMinRefreshInterval)}, nil
+}
+
+// publicKey reconstructs k's crypto.PublicKey from its JWKS fields.
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode RSA exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode EC x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode EC y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type: %q", k.Kty)
+	}
+}
+
+// keyForToken resolves the verification key for token's "kid"/"alg" header
+// pair against jwksURI's cached key set, re-fetching the set (subject to
+// jwksMinRefreshInterval) on a kid miss in case the provider rotated keys
+// since the last fetch.
+func (am *AuthManager) keyForToken(jwksURI string, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	ks, err := am.jwksSetFor(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := ks.key(kid); ok {
+		return key, nil
+	}
+
+	ks.mu.Lock()
+	if am.now().Before(ks.nextRefresh) {
+		ks.mu.Unlock()
+		return nil, fmt.Errorf("kid %q not found in JWKS (refresh on cooldown until %s)", kid, ks.nextRefresh)
+	}
+	ks.mu.Unlock()
+
+	refreshed, err := am.fetchJWKS(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	am.jwksMu.Lock()
+	am.jwksSets[jwksURI] = refreshed
+	am.jwksMu.Unlock()
+
+	if key, ok := refreshed.key(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("kid %q not found in JWKS after refresh", kid)
+}
+
+// verifyOIDCToken parses tokenString, selects its verification key by "kid"
+// (falling back to auth.SigningKey as an HMAC secret for HS256/HS384/HS512,
+// since symmetric keys aren't published via JWKS), checks the signature
+// plus exp/nbf/iss/aud, and returns its claims.
+func (am *AuthManager) verifyOIDCToken(tokenString string, jwksURI string, auth *JWTAuth) (jwt.MapClaims, error) {
+	var opts []jwt.ParserOption
+	if auth.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(auth.Issuer))
+	}
+	if auth.Audience != "" {
+		opts = append(opts, jwt.WithAudience(auth.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if auth.SigningKey == "" {
+				return nil, fmt.Errorf("HMAC-signed token but no SigningKey configured to verify it")
+			}
+			return []byte(auth.SigningKey), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+			return am.keyForToken(jwksURI, token)
+		default:
+			return nil, fmt.Errorf("unsupported JWT signing algorithm: %q", token.Method.Alg())
+		}
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("verify JWT: %w", err)
+	}
+	return claims, nil
+}
+
+// applyJWTOIDCAuth fetches a token from auth's discovered (or explicit)
+// OIDC provider via the client_credentials grant, verifies it against the
+// provider's JWKS, and attaches it — all cached per simulationID so a
+// long-running simulation doesn't re-fetch and re-verify on every request.
+func (am *AuthManager) applyJWTOIDCAuth(req *http.Request, auth *JWTAuth, simulationID int64) error {
+	cacheKey := fmt.Sprintf("jwt-oidc:%d", simulationID)
+
+	fetch := func() (*TokenCache, error) { return am.fetchOIDCToken(auth) }
+	token, err := am.getOrRefreshToken(cacheKey, fetch, func(*TokenCache) (*TokenCache, error) { return fetch() })
+	if err != nil {
+		return fmt.Errorf("OIDC JWT token: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return nil
+}
+
+// fetchOIDCToken requests and verifies a fresh token for auth, as described
+// on applyJWTOIDCAuth.
+func (am *AuthManager) fetchOIDCToken(auth *JWTAuth) (*TokenCache, error) {
+	jwksURI, tokenEndpoint, err := am.resolveJWKSURI(auth)
+	if err != nil {
+		return nil, err
+	}
+	if tokenEndpoint == "" {
+		return nil, fmt.Errorf("no token endpoint: set JWTAuth.RefreshURL or an OIDCIssuer whose discovery document includes one")
+	}
+
+	var scopes []string
+	if auth.Scope != "" {
+		scopes = strings.Fields(auth.Scope)
+	}
+	cfg := &clientcredentials.Config{
+		ClientID:     auth.ClientID,
+		ClientSecret: auth.ClientSecret,
+		TokenURL:     tokenEndpoint,
+		Scopes:       scopes,
+	}
+	oauthToken, err := cfg.Token(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fetch token from %s: %w", tokenEndpoint, err)
+	}
+
+	claims, err := am.verifyOIDCToken(oauthToken.AccessToken, jwksURI, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &TokenCache{AccessToken: oauthToken.AccessToken}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		tc.ExpiresAt = exp.Unix()
+	} else if !oauthToken.Expiry.IsZero() {
+		tc.ExpiresAt = oauthToken.Expiry.Unix()
+	}
+	return tc, nil
+}
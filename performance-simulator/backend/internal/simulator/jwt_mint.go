@@ -0,0 +1,100 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// defaultJWTTTL is used when a self-signed JWTAuth doesn't set TTL.
+const defaultJWTTTL = 5 * time.Minute
+
+// jwtSigner holds a parsed signing key for one JWTAuth minting configuration
+// (see jwtSignerCacheKey), so AuthManager only parses a PEM key or validates
+// an HMAC secret once no matter how many requests mint tokens against it.
+type jwtSigner struct {
+	method jwt.SigningMethod
+	key    interface{}
+	keyID  string
+}
+
+// newJWTSigner parses auth's SigningKey for auth.Algorithm.
+func newJWTSigner(auth *JWTAuth) (*jwtSigner, error) {
+	method := jwt.GetSigningMethod(auth.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported JWT signing algorithm: %q", auth.Algorithm)
+	}
+
+	var key interface{}
+	var err error
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		key = []byte(auth.SigningKey)
+	case *jwt.SigningMethodRSA:
+		key, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(auth.SigningKey))
+	case *jwt.SigningMethodECDSA:
+		key, err = jwt.ParseECPrivateKeyFromPEM([]byte(auth.SigningKey))
+	default:
+		return nil, fmt.Errorf("unsupported JWT signing algorithm: %q", auth.Algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse JWT signing key: %w", err)
+	}
+
+	return &jwtSigner{method: method, key: key, keyID: auth.KeyID}, nil
+}
+
+// mint builds auth's claim set — resolving ClaimTemplate's string values
+// through resolver (e.g. "{{username}}", "{{uuid}}") if set, then adding
+// iat/exp/jti and, if configured, iss/aud — and signs it with s. Returns the
+// compact JWS and the claim set used, the latter so callers like TestAuth's
+// debug mode can display it without re-decoding the token.
+func (s *jwtSigner) mint(auth *JWTAuth, resolver *VariableResolver) (string, jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	for name, value := range auth.ClaimTemplate {
+		claims[name] = resolveClaimValue(value, resolver)
+	}
+
+	ttl := auth.TTL
+	if ttl <= 0 {
+		ttl = defaultJWTTTL
+	}
+	now := time.Now()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	claims["jti"] = uuid.New().String()
+	if auth.Issuer != "" {
+		claims["iss"] = auth.Issuer
+	}
+	if auth.Audience != "" {
+		claims["aud"] = auth.Audience
+	}
+
+	token := jwt.NewWithClaims(s.method, claims)
+	if s.keyID != "" {
+		token.Header["kid"] = s.keyID
+	}
+
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// resolveClaimValue expands "{{var}}" templates in a string claim template
+// value via resolver. Non-string values, and string values when resolver is
+// nil or resolution fails, pass through unchanged.
+func resolveClaimValue(value interface{}, resolver *VariableResolver) interface{} {
+	s, ok := value.(string)
+	if !ok || resolver == nil {
+		return value
+	}
+	resolved, err := resolver.Resolve(s)
+	if err != nil {
+		return value
+	}
+	return resolved
+}
@@ -0,0 +1,386 @@
+package simulator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// tlsMaterial is the certificate/CA pool a rotatingClientCert currently
+// serves; swapped out wholesale on each reload rather than mutated in
+// place, so a handshake in progress never sees a half-updated pair. cert is
+// the zero value when tlsCfg configured no client certificate — TLS is
+// optional on the transport layer (see TLSConfig), so "mTLS" may really
+// just be server-certificate pinning or a SPIFFE ID check.
+type tlsMaterial struct {
+	cert tls.Certificate
+	pool *x509.CertPool // nil means "verify against the system root pool"
+}
+
+// rotatingClientCert holds the latest tlsMaterial loaded for a TLSConfig,
+// re-reading it from disk on ReloadInterval so long-running simulations
+// survive short-lived certificates without restarting. Reads go through an
+// atomic.Value so concurrent requests never block on, or observe a torn
+// update from, a reload.
+type rotatingClientCert struct {
+	material atomic.Value // *tlsMaterial
+	stop     chan struct{}
+	secrets  *SecretManager
+	spiffeID string
+}
+
+// newRotatingClientCert loads tlsCfg's initial certificate/CA material and,
+// if tlsCfg.ReloadInterval is set, starts a background goroutine that
+// refreshes it periodically for the lifetime of the process. secrets
+// resolves any of tlsCfg's file fields that are a "scheme://..."
+// SecretManager reference rather than a plain path (see loadTLSMaterial).
+func newRotatingClientCert(tlsCfg *TLSConfig, secrets *SecretManager) (*rotatingClientCert, error) {
+	mat, err := loadTLSMaterial(tlsCfg, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &rotatingClientCert{stop: make(chan struct{}), secrets: secrets, spiffeID: tlsCfg.SPIFFEID}
+	rc.material.Store(mat)
+
+	if tlsCfg.ReloadInterval > 0 {
+		go rc.reloadLoop(tlsCfg)
+	}
+
+	return rc, nil
+}
+
+func (rc *rotatingClientCert) reloadLoop(tlsCfg *TLSConfig) {
+	ticker := time.NewTicker(tlsCfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// A failed reload keeps serving the last good material rather
+			// than breaking in-flight requests on a transient disk error;
+			// the next tick tries again.
+			if mat, err := loadTLSMaterial(tlsCfg, rc.secrets); err == nil {
+				rc.material.Store(mat)
+			}
+		case <-rc.stop:
+			return
+		}
+	}
+}
+
+func (rc *rotatingClientCert) current() *tlsMaterial {
+	return rc.material.Load().(*tlsMaterial)
+}
+
+// loadTLSMaterial parses tlsCfg's certificate/key (or PKCS#12 bundle, if
+// any — a client certificate is optional) and CA bundle from disk. Used
+// both for the initial load and every periodic reload, and directly by
+// validateTLSConfig to confirm the configuration is usable before a
+// simulation starts. Each of CertFile/KeyFile/P12File/CAFile may be a
+// literal path or a secrets.go "scheme://..." reference, resolved via
+// secrets (materialized to a temp file for the ones consumed as paths).
+func loadTLSMaterial(tlsCfg *TLSConfig, secrets *SecretManager) (*tlsMaterial, error) {
+	var cert tls.Certificate
+	var err error
+
+	switch {
+	case tlsCfg.P12File != "":
+		p12File, rerr := secrets.ResolvePath(tlsCfg.P12File)
+		if rerr != nil {
+			return nil, fmt.Errorf("resolve P12 file: %w", rerr)
+		}
+		p12Password, rerr := secrets.Resolve(tlsCfg.P12Password)
+		if rerr != nil {
+			return nil, fmt.Errorf("resolve P12 password: %w", rerr)
+		}
+		cert, err = loadPKCS12KeyPair(p12File, p12Password)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+
+	case tlsCfg.CertFile != "" || tlsCfg.KeyFile != "":
+		certFile, rerr := secrets.ResolvePath(tlsCfg.CertFile)
+		if rerr != nil {
+			return nil, fmt.Errorf("resolve cert file: %w", rerr)
+		}
+		keyFile, rerr := secrets.ResolvePath(tlsCfg.KeyFile)
+		if rerr != nil {
+			return nil, fmt.Errorf("resolve key file: %w", rerr)
+		}
+		keyPassphrase, rerr := secrets.Resolve(tlsCfg.KeyPassphrase)
+		if rerr != nil {
+			return nil, fmt.Errorf("resolve key passphrase: %w", rerr)
+		}
+		cert, err = loadX509KeyPair(certFile, keyFile, keyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+	}
+
+	pool, err := loadCAPool(tlsCfg, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tlsMaterial{cert: cert, pool: pool}, nil
+}
+
+// loadCAPool builds the RootCAs pool from CABundle (inline PEM) or CAFile,
+// whichever is set; CABundle takes precedence. Returns a nil pool (meaning
+// "verify against the system root pool") when neither is set.
+func loadCAPool(tlsCfg *TLSConfig, secrets *SecretManager) (*x509.CertPool, error) {
+	var pemData []byte
+	switch {
+	case tlsCfg.CABundle != "":
+		pemData = []byte(tlsCfg.CABundle)
+	case tlsCfg.CAFile != "":
+		caFile, err := secrets.ResolvePath(tlsCfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("resolve CA file: %w", err)
+		}
+		data, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pemData = data
+	default:
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("CA bundle contains no valid certificates")
+	}
+	return pool, nil
+}
+
+// loadPKCS12KeyPair decodes a PKCS#12 bundle into a tls.Certificate, the
+// PKCS#12 equivalent of tls.LoadX509KeyPair.
+func loadPKCS12KeyPair(path, password string) (tls.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("read PKCS#12 bundle: %w", err)
+	}
+
+	key, leaf, err := pkcs12.Decode(data, password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("decode PKCS#12 bundle: %w", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// loadX509KeyPair is tls.LoadX509KeyPair, plus support for an
+// RFC 1423-style passphrase-encrypted PEM private key when passphrase is
+// non-empty.
+func loadX509KeyPair(certFile, keyFile, passphrase string) (tls.Certificate, error) {
+	if passphrase == "" {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("read certificate: %w", err)
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("read key: %w", err)
+	}
+
+	decryptedPEM, err := decryptPEMKey(keyPEM, passphrase)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certPEM, decryptedPEM)
+}
+
+// decryptPEMKey decrypts a legacy (RFC 1423 "DEK-Info" header) encrypted PEM
+// private key with passphrase. There's no modern standard-library
+// replacement for this format, so this deliberately uses the deprecated
+// x509.IsEncryptedPEMBlock/DecryptPEMBlock — the only way to read keys
+// produced by `openssl genrsa -aes256` and similar without a new dependency.
+func decryptPEMKey(keyPEM []byte, passphrase string) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("decode PEM key: no PEM block found")
+	}
+	if !x509.IsEncryptedPEMBlock(block) {
+		return keyPEM, nil
+	}
+
+	der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt PEM key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+}
+
+// tlsVersionsByName maps TLSConfig.MinVersion's accepted values to the
+// crypto/tls version constants.
+var tlsVersionsByName = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsVersionFromString resolves a TLSConfig.MinVersion string, returning 0
+// (meaning "use the standard library's default") for an empty string.
+func tlsVersionFromString(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersionsByName[version]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q (want one of 1.0, 1.1, 1.2, 1.3)", version)
+	}
+	return v, nil
+}
+
+// cipherSuitesFromNames resolves TLSConfig.CipherSuites' suite names (as
+// reported by crypto/tls.CipherSuites()/InsecureCipherSuites(), e.g.
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256") to their IDs.
+func cipherSuitesFromNames(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	byName := make(map[string]uint16, len(tls.CipherSuites())+len(tls.InsecureCipherSuites()))
+	for _, suite := range tls.CipherSuites() {
+		byName[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		byName[suite.Name] = suite.ID
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// tlsConfigFromRotating builds a *tls.Config that always serves rc's latest
+// certificate/CA pool, so a reload takes effect on the next handshake
+// without rebuilding the http.Transport. Because the RootCAs pool can
+// rotate, server verification can't rely on tls.Config's own
+// (config-build-time) RootCAs field; instead it's skipped at the standard
+// library level and redone in VerifyPeerCertificate against whatever pool
+// is current at handshake time. When only tlsCfg.SPIFFEID is set (no custom
+// CA), the standard library's own verification against the system root pool
+// runs as normal and VerifyPeerCertificate only adds the SPIFFE check.
+func tlsConfigFromRotating(rc *rotatingClientCert, tlsCfg *TLSConfig) *tls.Config {
+	cfg := &tls.Config{
+		ServerName: tlsCfg.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &rc.current().cert, nil
+		},
+	}
+
+	// Parse errors here were already surfaced by validateTLSConfig at
+	// simulation start; a zero value falls back to the standard library's
+	// own default.
+	if v, err := tlsVersionFromString(tlsCfg.MinVersion); err == nil {
+		cfg.MinVersion = v
+	}
+	if suites, err := cipherSuitesFromNames(tlsCfg.CipherSuites); err == nil {
+		cfg.CipherSuites = suites
+	}
+
+	switch {
+	case tlsCfg.InsecureSkipVerify:
+		cfg.InsecureSkipVerify = true
+	case tlsCfg.CAFile != "" || tlsCfg.CABundle != "" || tlsCfg.SPIFFEID != "":
+		if tlsCfg.CAFile != "" || tlsCfg.CABundle != "" {
+			cfg.InsecureSkipVerify = true
+		}
+		cfg.VerifyPeerCertificate = rc.verifyPeerCertificate
+	}
+
+	return cfg
+}
+
+// verifyPeerCertificate re-implements the chain verification tls.Config
+// normally does itself, against rc's current CA pool rather than the pool
+// that was current when the *tls.Config was built — only when rc's pool is
+// non-nil; otherwise the standard library has already verified the chain
+// against the system root pool, and this only adds the SPIFFE ID check.
+func (rc *rotatingClientCert) verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("no peer certificates presented")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("parse peer certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+
+	if pool := rc.current().pool; pool != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{Roots: pool, Intermediates: intermediates}); err != nil {
+			return err
+		}
+	}
+
+	if rc.spiffeID != "" {
+		if err := verifySPIFFEID(certs[0], rc.spiffeID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifySPIFFEID reports an error unless leaf's URI SANs include want, the
+// SPIFFE-style workload identity check for TLSConfig.SPIFFEID.
+func verifySPIFFEID(leaf *x509.Certificate, want string) error {
+	for _, uri := range leaf.URIs {
+		if uri.String() == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("peer certificate does not present expected SPIFFE ID %q", want)
+}
+
+// peerCertificatesFrom converts a negotiated TLS chain into the
+// PeerCertificate summaries exposed on ValidationResult.
+func peerCertificatesFrom(certs []*x509.Certificate) []PeerCertificate {
+	if len(certs) == 0 {
+		return nil
+	}
+
+	out := make([]PeerCertificate, len(certs))
+	for i, c := range certs {
+		out[i] = PeerCertificate{
+			Subject:   c.Subject.String(),
+			Issuer:    c.Issuer.String(),
+			DNSNames:  c.DNSNames,
+			NotBefore: c.NotBefore,
+			NotAfter:  c.NotAfter,
+		}
+	}
+	return out
+}
@@ -0,0 +1,224 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	apierrors "github.com/go-openapi/errors"
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+)
+
+// validateOpenAPI validates resp/body against the operation validation.OperationID
+// declares in validation.OpenAPISpec: the response's status code must have a
+// documented response, its body must satisfy that response's schema, and its
+// Content-Type must be one the operation (or the document) produces.
+// Failures are appended as ValidationError{Type: "openapi", ...}; a spec or
+// operation that can't be found is also reported this way rather than
+// panicking, since a bad spec path is a config mistake, not a bug.
+func (ve *ValidationEngine) validateOpenAPI(resp *http.Response, body []byte, validation *ResponseValidation, result *ValidationResult) {
+	doc, err := ve.openAPIDocument(validation.OpenAPISpec)
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:    "openapi",
+			Message: fmt.Sprintf("failed to load OpenAPI spec %q: %v", validation.OpenAPISpec, err),
+		})
+		return
+	}
+
+	op, path, method, err := findOperationByID(doc, validation.OperationID)
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:    "openapi",
+			Message: fmt.Sprintf("operation %q not found in %q: %v", validation.OperationID, validation.OpenAPISpec, err),
+		})
+		return
+	}
+
+	if !contentTypeAllowed(resp.Header.Get("Content-Type"), op, doc.Spec()) {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:     "openapi",
+			Field:    fmt.Sprintf("%s %s", method, path),
+			Expected: strings.Join(producesFor(op, doc.Spec()), ", "),
+			Actual:   resp.Header.Get("Content-Type"),
+			Message:  fmt.Sprintf("operation %q does not produce Content-Type %q", validation.OperationID, resp.Header.Get("Content-Type")),
+		})
+	}
+
+	response, ok := responseForStatus(op, resp.StatusCode)
+	if !ok {
+		result.Warnings = append(result.Warnings, ValidationWarning{
+			Type:    "openapi",
+			Message: fmt.Sprintf("operation %q declares no response for status %d; skipping schema validation", validation.OperationID, resp.StatusCode),
+		})
+		return
+	}
+
+	for name := range response.Headers {
+		if resp.Header.Get(name) == "" {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:    "openapi",
+				Field:   name,
+				Message: fmt.Sprintf("response missing header %q declared by operation %q", name, validation.OperationID),
+			})
+		}
+	}
+
+	if response.Schema == nil {
+		return
+	}
+
+	var data interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &data); err != nil {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:    "openapi",
+				Message: fmt.Sprintf("response body is not valid JSON: %v", err),
+			})
+			return
+		}
+	}
+
+	if err := validate.AgainstSchema(response.Schema, data, strfmt.Default); err != nil {
+		result.Passed = false
+		for _, schemaErr := range flattenOpenAPIError(err) {
+			result.Errors = append(result.Errors, ValidationError{
+				Type:    "openapi",
+				Field:   schemaErr.field,
+				Message: schemaErr.message,
+				Pointer: jsonSchemaFieldToPointer(schemaErr.field),
+			})
+		}
+	}
+}
+
+// openAPIDocument loads and expands (resolving $ref) the OpenAPI/Swagger
+// document at specPath, caching the result so a simulation running the same
+// scenario thousands of times doesn't re-parse the spec on every response.
+func (ve *ValidationEngine) openAPIDocument(specPath string) (*loads.Document, error) {
+	ve.mutex.RLock()
+	cached, ok := ve.openAPICache[specPath]
+	ve.mutex.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	doc, err := loads.Spec(specPath)
+	if err != nil {
+		return nil, err
+	}
+	doc, err = doc.Expanded(&spec.ExpandOptions{RelativeBase: specPath})
+	if err != nil {
+		return nil, err
+	}
+
+	ve.mutex.Lock()
+	ve.openAPICache[specPath] = doc
+	ve.mutex.Unlock()
+	return doc, nil
+}
+
+// findOperationByID scans every path+method in doc for the operation whose
+// OperationID matches id, returning it along with the path template and
+// HTTP method it was found under (for error messages).
+func findOperationByID(doc *loads.Document, id string) (*spec.Operation, string, string, error) {
+	if doc.Spec().Paths == nil {
+		return nil, "", "", fmt.Errorf("spec declares no paths")
+	}
+	for path, item := range doc.Spec().Paths.Paths {
+		for method, op := range operationsOf(&item) {
+			if op != nil && op.ID == id {
+				return op, path, method, nil
+			}
+		}
+	}
+	return nil, "", "", fmt.Errorf("no operation with operationId %q", id)
+}
+
+// operationsOf returns every operation defined on a path item, keyed by its
+// HTTP method.
+func operationsOf(item *spec.PathItem) map[string]*spec.Operation {
+	return map[string]*spec.Operation{
+		http.MethodGet:     item.Get,
+		http.MethodPut:     item.Put,
+		http.MethodPost:    item.Post,
+		http.MethodDelete:  item.Delete,
+		http.MethodOptions: item.Options,
+		http.MethodHead:    item.Head,
+		http.MethodPatch:   item.Patch,
+	}
+}
+
+// responseForStatus returns the response object op declares for statusCode,
+// falling back to the operation's default response.
+func responseForStatus(op *spec.Operation, statusCode int) (*spec.Response, bool) {
+	if op.Responses == nil {
+		return nil, false
+	}
+	if resp, ok := op.Responses.StatusCodeResponses[statusCode]; ok {
+		return &resp, true
+	}
+	if op.Responses.Default != nil {
+		return op.Responses.Default, true
+	}
+	return nil, false
+}
+
+// producesFor returns the media types op (or, if it declares none, the spec
+// as a whole) produces.
+func producesFor(op *spec.Operation, swagger *spec.Swagger) []string {
+	if len(op.Produces) > 0 {
+		return op.Produces
+	}
+	return swagger.Produces
+}
+
+// contentTypeAllowed reports whether contentType (which may carry a
+// "; charset=..." suffix) matches one of op's produced media types. An
+// operation that declares no produces list at all is treated as
+// unconstrained, since plenty of real specs omit it.
+func contentTypeAllowed(contentType string, op *spec.Operation, swagger *spec.Swagger) bool {
+	produces := producesFor(op, swagger)
+	if len(produces) == 0 {
+		return true
+	}
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	for _, p := range produces {
+		if p == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+type openAPIFieldError struct {
+	field   string
+	message string
+}
+
+// flattenOpenAPIError unwraps go-openapi's validation errors into one
+// openAPIFieldError per leaf failure, so each one becomes its own
+// ValidationError instead of a single opaque blob.
+func flattenOpenAPIError(err error) []openAPIFieldError {
+	switch e := err.(type) {
+	case *apierrors.CompositeError:
+		var out []openAPIFieldError
+		for _, child := range e.Errors {
+			out = append(out, flattenOpenAPIError(child)...)
+		}
+		return out
+	case *apierrors.Validation:
+		return []openAPIFieldError{{field: e.Name, message: e.Error()}}
+	default:
+		return []openAPIFieldError{{field: "(root)", message: err.Error()}}
+	}
+}
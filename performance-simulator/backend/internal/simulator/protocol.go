@@ -0,0 +1,48 @@
+package simulator
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Protocol selects which ProtocolDriver a simulation's requests are sent
+// through. The zero value behaves as ProtocolHTTP so existing configs
+// without a protocol field keep working unmodified.
+type Protocol string
+
+const (
+	ProtocolHTTP    Protocol = "http"
+	ProtocolGRPC    Protocol = "grpc"
+	ProtocolGraphQL Protocol = "graphql"
+)
+
+// ProtocolDriver executes a single resolved request against a simulation's
+// target. It reports back in the same shape the rest of the engine
+// (validation, capture, metrics) already consumes: an *http.Response for its
+// status code, headers, and trailers, paired with the body already read into
+// memory. A driver whose wire protocol isn't HTTP (gRPC) synthesizes an
+// equivalent *http.Response — carrying its own protocol's status codes, e.g.
+// gRPC's codes.OK == 0, in StatusCode, and any trailing metadata in Trailer —
+// so the rest of the pipeline doesn't need a protocol-specific code path.
+// Execute should only return a non-nil error for failures in getting a
+// request onto the wire at all (dial, marshal, auth); an application-level
+// failure (HTTP 5xx, a gRPC error status) belongs in the synthesized
+// response so validation/capture still run against it.
+type ProtocolDriver interface {
+	Execute(sim *Simulation, resolvedConfig *SimulationConfig, resolver *VariableResolver) (*http.Response, []byte, error)
+}
+
+// driverFor returns the ProtocolDriver resolvedConfig.Protocol selects,
+// defaulting to HTTP for the zero value so existing configs are unaffected.
+func (e *Engine) driverFor(protocol Protocol) (ProtocolDriver, error) {
+	switch protocol {
+	case "", ProtocolHTTP:
+		return &HTTPDriver{engine: e}, nil
+	case ProtocolGRPC:
+		return &GRPCDriver{engine: e}, nil
+	case ProtocolGraphQL:
+		return &GraphQLDriver{engine: e}, nil
+	default:
+		return nil, fmt.Errorf("unsupported protocol: %q", protocol)
+	}
+}
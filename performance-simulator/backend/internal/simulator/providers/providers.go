@@ -0,0 +1,186 @@
+// Package providers holds locale-specific synthetic data (names, addresses,
+// phone numbers, company suffixes, currency) so the simulator's built-in
+// template functions can generate realistic traffic for a target audience
+// instead of always producing US-centric values.
+package providers
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed data/*.json
+var datasetFS embed.FS
+
+// DefaultLocale is used when a resolver or call site doesn't request a
+// specific locale.
+const DefaultLocale = "en_US"
+
+// City is one real-world city a locale's addresses are drawn from, with a
+// postal code that actually matches the city rather than a locale-wide
+// pattern, so e.g. a GB address always gets a plausible "SW1A 1AA"-shaped
+// code and a JP address always gets a "100-0001"-shaped one.
+type City struct {
+	Name       string `json:"name"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+}
+
+// Dataset is a single locale's synthetic data, loaded from
+// data/<locale>.json.
+type Dataset struct {
+	Locale           string   `json:"locale"`
+	Country          string   `json:"country"`
+	CurrencyCode     string   `json:"currency_code"`
+	CurrencySymbol   string   `json:"currency_symbol"`
+	PhoneCountryCode string   `json:"phone_country_code"`
+	PhoneFormat      string   `json:"phone_format"`
+	FirstNames       []string `json:"first_names"`
+	LastNames        []string `json:"last_names"`
+	CompanySuffixes  []string `json:"company_suffixes"`
+	Streets          []string `json:"streets"`
+	Cities           []City   `json:"cities"`
+}
+
+// IntnFunc draws a random integer in [0, n) and backs every Dataset method
+// below, so callers can supply a resolver's own seeded generator instead of
+// this package touching randomness directly.
+type IntnFunc func(n int) int
+
+var (
+	mu       sync.RWMutex
+	datasets = make(map[string]*Dataset)
+)
+
+// Get returns the Dataset for locale, parsing its embedded JSON on first use
+// and caching the result. An unknown locale is an error rather than a silent
+// fallback, so a typo in a `locale=` override is visible immediately.
+func Get(locale string) (*Dataset, error) {
+	mu.RLock()
+	ds, ok := datasets[locale]
+	mu.RUnlock()
+	if ok {
+		return ds, nil
+	}
+
+	raw, err := datasetFS.ReadFile("data/" + locale + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("providers: unknown locale %q", locale)
+	}
+	ds = &Dataset{}
+	if err := json.Unmarshal(raw, ds); err != nil {
+		return nil, fmt.Errorf("providers: parse locale %q: %w", locale, err)
+	}
+
+	mu.Lock()
+	datasets[locale] = ds
+	mu.Unlock()
+	return ds, nil
+}
+
+// Locales returns the locale codes with an embedded dataset, sorted.
+func Locales() []string {
+	entries, err := datasetFS.ReadDir("data")
+	if err != nil {
+		return nil
+	}
+	locales := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		locales = append(locales, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// FirstName returns a random first name from d.
+func (d *Dataset) FirstName(intn IntnFunc) string {
+	return d.FirstNames[intn(len(d.FirstNames))]
+}
+
+// LastName returns a random last name from d.
+func (d *Dataset) LastName(intn IntnFunc) string {
+	return d.LastNames[intn(len(d.LastNames))]
+}
+
+// CompanySuffix returns a random legal-entity suffix, e.g. "GmbH" for de_DE.
+func (d *Dataset) CompanySuffix(intn IntnFunc) string {
+	return d.CompanySuffixes[intn(len(d.CompanySuffixes))]
+}
+
+// RandomCity returns a random city, paired with a region and postal code
+// that are consistent with each other and with d's locale.
+func (d *Dataset) RandomCity(intn IntnFunc) City {
+	return d.Cities[intn(len(d.Cities))]
+}
+
+// Street returns a random street name, with no house number.
+func (d *Dataset) Street(intn IntnFunc) string {
+	return d.Streets[intn(len(d.Streets))]
+}
+
+// StreetAddress returns a house number plus a random street name.
+func (d *Dataset) StreetAddress(intn IntnFunc) string {
+	return fmt.Sprintf("%d %s", intn(9999)+1, d.Street(intn))
+}
+
+// FullAddress returns a complete, internally-consistent address: street,
+// city, region, postal code, and country.
+func (d *Dataset) FullAddress(intn IntnFunc) string {
+	city := d.RandomCity(intn)
+	return fmt.Sprintf("%s, %s, %s %s, %s", d.StreetAddress(intn), city.Name, city.Region, city.PostalCode, d.Country)
+}
+
+// Phone fills d.PhoneFormat's '#' placeholders with random digits, producing
+// an E.164-shaped number with d's country code, e.g. "+49 151 2345678".
+func (d *Dataset) Phone(intn IntnFunc) string {
+	var sb strings.Builder
+	for _, r := range d.PhoneFormat {
+		if r == '#' {
+			sb.WriteByte(byte('0' + intn(10)))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// Amount formats a random monetary amount in d's currency, e.g. "€123.45".
+func (d *Dataset) Amount(intn IntnFunc) string {
+	whole := intn(10000)
+	cents := intn(100)
+	return fmt.Sprintf("%s%d.%02d", d.CurrencySymbol, whole, cents)
+}
+
+// Sample returns one fixed, deterministic example per field this dataset can
+// generate, for the UI's locale picker — a preview, not a random draw.
+func (d *Dataset) Sample() map[string]string {
+	sample := map[string]string{
+		"country":  d.Country,
+		"currency": d.CurrencyCode,
+	}
+	if len(d.FirstNames) > 0 {
+		sample["first_name"] = d.FirstNames[0]
+	}
+	if len(d.LastNames) > 0 {
+		sample["last_name"] = d.LastNames[0]
+	}
+	if len(d.CompanySuffixes) > 0 {
+		sample["company_suffix"] = d.CompanySuffixes[0]
+	}
+	if len(d.Streets) > 0 {
+		sample["street"] = "123 " + d.Streets[0]
+	}
+	if len(d.Cities) > 0 {
+		city := d.Cities[0]
+		sample["city"] = city.Name
+		sample["region"] = city.Region
+		sample["postal_code"] = city.PostalCode
+	}
+	sample["phone"] = strings.ReplaceAll(d.PhoneFormat, "#", "5")
+	sample["amount"] = fmt.Sprintf("%s1,234.56", d.CurrencySymbol)
+	return sample
+}
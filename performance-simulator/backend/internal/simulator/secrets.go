@@ -0,0 +1,393 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSecretTTL is how long a resolved secret is cached before
+// SecretManager re-resolves it, independent of any provider-specific lease.
+const defaultSecretTTL = 5 * time.Minute
+
+// SecretResolver resolves one secret-reference scheme (the part of a
+// "scheme://..." URI before "://") to its plaintext value.
+type SecretResolver interface {
+	ResolveSecret(uri string) (string, error)
+}
+
+// SecretManager resolves "scheme://..." secret references to their
+// plaintext values, caching each with a TTL so a value isn't re-fetched
+// (and, for Vault, re-authenticated) on every request. AuthConfig fields
+// that accept a literal-or-secret-reference value (BearerAuth.Token,
+// BasicAuth.Password, OAuth2Auth.ClientSecret, TLSConfig.KeyFile, etc.)
+// go through Resolve/ResolvePath before use.
+type SecretManager struct {
+	TTL func() time.Duration
+
+	mu        sync.RWMutex
+	resolvers map[string]SecretResolver
+	cache     map[string]secretCacheEntry
+
+	// pathCache holds, per secret URI, a temp file materializing the
+	// resolved content for fields (like TLSConfig.KeyFile) that need a
+	// filesystem path rather than the raw value — see ResolvePath.
+	pathMu    sync.Mutex
+	pathCache map[string]string
+
+	now func() time.Time
+}
+
+type secretCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewSecretManager creates a SecretManager with the built-in env/file/vault
+// resolvers registered and a default TTL of defaultSecretTTL.
+func NewSecretManager() *SecretManager {
+	sm := &SecretManager{
+		resolvers: make(map[string]SecretResolver),
+		cache:     make(map[string]secretCacheEntry),
+		pathCache: make(map[string]string),
+		now:       time.Now,
+	}
+	sm.TTL = func() time.Duration { return defaultSecretTTL }
+	sm.RegisterResolver("env", EnvSecretResolver{})
+	sm.RegisterResolver("file", FileSecretResolver{})
+	return sm
+}
+
+// RegisterResolver registers (or replaces) the resolver for scheme, e.g.
+// sm.RegisterResolver("vault", vaultResolver) once a VaultSecretResolver is
+// configured with the deployment's VAULT_ADDR and credentials.
+func (sm *SecretManager) RegisterResolver(scheme string, resolver SecretResolver) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.resolvers[scheme] = resolver
+}
+
+// secretScheme splits a "scheme://rest" reference, reporting ok=false for
+// anything else (a literal value, to be used as-is).
+func secretScheme(value string) (scheme string, ok bool) {
+	idx := strings.Index(value, "://")
+	if idx < 0 {
+		return "", false
+	}
+	return value[:idx], true
+}
+
+// Resolve returns value unchanged if it isn't a "scheme://..." secret
+// reference; otherwise it returns the cached or freshly resolved secret.
+func (sm *SecretManager) Resolve(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	scheme, ok := secretScheme(value)
+	if !ok {
+		return value, nil
+	}
+
+	if cached, ok := sm.cached(value); ok {
+		return cached, nil
+	}
+
+	sm.mu.RLock()
+	resolver, ok := sm.resolvers[scheme]
+	sm.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no secret resolver registered for scheme %q", scheme)
+	}
+
+	resolved, err := resolver.ResolveSecret(value)
+	if err != nil {
+		return "", fmt.Errorf("resolve secret %q: %w", value, err)
+	}
+
+	sm.mu.Lock()
+	sm.cache[value] = secretCacheEntry{value: resolved, expiresAt: sm.now().Add(sm.TTL())}
+	sm.mu.Unlock()
+	return resolved, nil
+}
+
+func (sm *SecretManager) cached(uri string) (string, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	entry, ok := sm.cache[uri]
+	if !ok || sm.now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.value, true
+}
+
+// ResolvePath is like Resolve, but for fields that are consumed as a
+// filesystem path (TLSConfig.CertFile/KeyFile/CAFile/P12File): a
+// literal value passes through as a path unchanged; a secret reference is
+// resolved to its content and materialized into a cached, 0600 temp file,
+// whose path is returned instead.
+func (sm *SecretManager) ResolvePath(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	if _, ok := secretScheme(value); !ok {
+		return value, nil
+	}
+
+	sm.pathMu.Lock()
+	if path, ok := sm.pathCache[value]; ok {
+		sm.pathMu.Unlock()
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		// The materialized file vanished (e.g. /tmp cleanup); fall through
+		// and recreate it below.
+	} else {
+		sm.pathMu.Unlock()
+	}
+
+	content, err := sm.Resolve(value)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "origo-secret-*")
+	if err != nil {
+		return "", fmt.Errorf("materialize secret to temp file: %w", err)
+	}
+	defer f.Close()
+	if err := f.Chmod(0o600); err != nil {
+		return "", fmt.Errorf("chmod secret temp file: %w", err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		return "", fmt.Errorf("write secret temp file: %w", err)
+	}
+
+	sm.pathMu.Lock()
+	sm.pathCache[value] = f.Name()
+	sm.pathMu.Unlock()
+	return f.Name(), nil
+}
+
+// Invalidate drops the cached value for a single secret reference, forcing
+// the next Resolve/ResolvePath call to re-fetch it. Intended for a
+// provider-side lease-renewal goroutine (see VaultSecretResolver) to call
+// once a lease is near (or past) expiry.
+func (sm *SecretManager) Invalidate(uri string) {
+	sm.mu.Lock()
+	delete(sm.cache, uri)
+	sm.mu.Unlock()
+}
+
+// InvalidateAll drops every cached secret value.
+func (sm *SecretManager) InvalidateAll() {
+	sm.mu.Lock()
+	sm.cache = make(map[string]secretCacheEntry)
+	sm.mu.Unlock()
+}
+
+// EnvSecretResolver resolves "env://VAR_NAME" references to the named
+// environment variable.
+type EnvSecretResolver struct{}
+
+func (EnvSecretResolver) ResolveSecret(uri string) (string, error) {
+	name := strings.TrimPrefix(uri, "env://")
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q is not set", name)
+	}
+	return value, nil
+}
+
+// FileSecretResolver resolves "file:///path/to/secret" references to the
+// named file's contents, trimming a single trailing newline (the common
+// convention for secrets written by `echo` or mounted by orchestrators).
+type FileSecretResolver struct{}
+
+func (FileSecretResolver) ResolveSecret(uri string) (string, error) {
+	path := strings.TrimPrefix(uri, "file://")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file %q: %w", path, err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// VaultSecretResolver resolves "vault://mount/path#field" references
+// against a HashiCorp Vault server, authenticating once with either a
+// static Token or AppRole (RoleID/SecretID) credentials and reusing that
+// Vault token (re-logging in via AppRole once it's known to have expired).
+type VaultSecretResolver struct {
+	Addr  string // VAULT_ADDR, e.g. "https://vault.internal:8200"
+	Token string // static token auth; takes precedence over AppRole if set
+
+	// RoleID/SecretID authenticate via the AppRole auth method when Token
+	// is empty.
+	RoleID   string
+	SecretID string
+
+	Client *http.Client
+
+	mu          sync.Mutex
+	loginToken  string
+	tokenExpiry time.Time
+}
+
+// ResolveSecret fetches mount/path from Vault's KV API and extracts field.
+// Supports both KV v2 (data.data.<field>) and KV v1 (data.<field>) response
+// shapes.
+func (v *VaultSecretResolver) ResolveSecret(uri string) (string, error) {
+	path, field, err := parseVaultURI(uri)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := v.authToken()
+	if err != nil {
+		return "", fmt.Errorf("vault authentication: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(v.Addr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := v.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d for %s", resp.StatusCode, path)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+
+	// KV v2 nests the secret under data.data; KV v1 puts it directly under
+	// data. body.Data.Data is only populated for v2, so fall back to
+	// re-decoding as v1 shape when it's empty.
+	fields := body.Data.Data
+	if fields == nil {
+		var v1 struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(mustRemarshal(body), &v1); err == nil {
+			fields = v1.Data
+		}
+	}
+
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found at vault path %q", field, path)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %q at vault path %q is not a string", field, path)
+	}
+	return str, nil
+}
+
+// mustRemarshal is a small helper for re-decoding body under a different
+// shape; json.Marshal on an already-decoded struct never fails.
+func mustRemarshal(v interface{}) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+// authToken returns a valid Vault token, logging in via AppRole if Token
+// isn't set and the cached login token has expired or was never obtained.
+func (v *VaultSecretResolver) authToken() (string, error) {
+	if v.Token != "" {
+		return v.Token, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.loginToken != "" && time.Now().Before(v.tokenExpiry) {
+		return v.loginToken, nil
+	}
+
+	payload, err := json.Marshal(map[string]string{"role_id": v.RoleID, "secret_id": v.SecretID})
+	if err != nil {
+		return "", err
+	}
+	resp, err := v.httpClient().Post(strings.TrimRight(v.Addr, "/")+"/v1/auth/approle/login", "application/json", strings.NewReader(string(payload)))
+	if err != nil {
+		return "", fmt.Errorf("AppRole login: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("AppRole login returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode AppRole login response: %w", err)
+	}
+
+	v.loginToken = result.Auth.ClientToken
+	v.tokenExpiry = time.Now().Add(time.Duration(result.Auth.LeaseDuration) * time.Second)
+	return v.loginToken, nil
+}
+
+func (v *VaultSecretResolver) httpClient() *http.Client {
+	if v.Client != nil {
+		return v.Client
+	}
+	return http.DefaultClient
+}
+
+// parseVaultURI splits a "vault://mount/path#field" reference into its
+// Vault API path ("mount/path") and field name.
+func parseVaultURI(uri string) (path string, field string, err error) {
+	rest := strings.TrimPrefix(uri, "vault://")
+	idx := strings.LastIndex(rest, "#")
+	if idx < 0 {
+		return "", "", fmt.Errorf("vault secret reference %q is missing a #field suffix", uri)
+	}
+	path, field = rest[:idx], rest[idx+1:]
+	if path == "" || field == "" {
+		return "", "", fmt.Errorf("invalid vault secret reference %q", uri)
+	}
+	return path, field, nil
+}
+
+// StartLeaseRenewal starts a background goroutine that invalidates uri's
+// cached SecretManager entry every interval, forcing the next Resolve to
+// re-authenticate and re-fetch — the general pattern for keeping a
+// Vault-issued dynamic secret (e.g. a database credential with a lease)
+// fresh without waiting for it to actually expire. Returns a stop function.
+func (v *VaultSecretResolver) StartLeaseRenewal(sm *SecretManager, uri string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sm.Invalidate(uri)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
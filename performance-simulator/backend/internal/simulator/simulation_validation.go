@@ -0,0 +1,161 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/metrics"
+)
+
+// ValidateSimulation checks validation's simulation-wide thresholds against
+// results, the full set of per-request outcomes from a completed run.
+// Percentiles are computed with a streaming metrics.Digest rather than
+// sorting results, the same t-digest Engine already streams live response
+// times into (see Simulation.responseDigest), so this stays O(1) memory in
+// the number of centroids regardless of how many requests the run made.
+// baseline is optional; nil skips the distribution-shift check entirely.
+func (ve *ValidationEngine) ValidateSimulation(validation *SimulationValidation, results []RequestResult, baseline *BaselineRun) *ValidationResult {
+	result := &ValidationResult{Passed: true}
+	if validation == nil {
+		return result
+	}
+
+	digest := metrics.NewDigest()
+	var failedCount int64
+	var bodySum, bodySumSq float64
+	currentTimes := make([]time.Duration, 0, len(results))
+
+	for _, r := range results {
+		digest.Add(r.ResponseTime)
+		currentTimes = append(currentTimes, r.ResponseTime)
+		if !r.Success {
+			failedCount++
+		}
+		size := float64(r.BodySize)
+		bodySum += size
+		bodySumSq += size * size
+	}
+
+	total := int64(len(results))
+
+	if validation.MaxP95ResponseTime > 0 && digest.Count() > 0 {
+		if p95 := digest.Quantile(0.95); p95 > validation.MaxP95ResponseTime {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:     "simulation_p95",
+				Expected: validation.MaxP95ResponseTime.String(),
+				Actual:   p95.String(),
+				Message:  fmt.Sprintf("P95 response time %s exceeds threshold %s", p95, validation.MaxP95ResponseTime),
+			})
+		}
+	}
+
+	if validation.MaxP99ResponseTime > 0 && digest.Count() > 0 {
+		if p99 := digest.Quantile(0.99); p99 > validation.MaxP99ResponseTime {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:     "simulation_p99",
+				Expected: validation.MaxP99ResponseTime.String(),
+				Actual:   p99.String(),
+				Message:  fmt.Sprintf("P99 response time %s exceeds threshold %s", p99, validation.MaxP99ResponseTime),
+			})
+		}
+	}
+
+	if validation.MaxErrorRate > 0 && total > 0 {
+		errorRate := float64(failedCount) / float64(total)
+		if errorRate > validation.MaxErrorRate {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:     "simulation_error_rate",
+				Expected: fmt.Sprintf("<= %.4f", validation.MaxErrorRate),
+				Actual:   fmt.Sprintf("%.4f", errorRate),
+				Message:  fmt.Sprintf("error rate %.2f%% exceeds threshold %.2f%%", errorRate*100, validation.MaxErrorRate*100),
+			})
+		}
+	}
+
+	if validation.MaxBodySizeSigma > 0 && total > 1 {
+		mean := bodySum / float64(total)
+		variance := bodySumSq/float64(total) - mean*mean
+		if variance < 0 {
+			variance = 0
+		}
+		stdDev := math.Sqrt(variance)
+		if stdDev > 0 {
+			for i, r := range results {
+				sigma := math.Abs(float64(r.BodySize)-mean) / stdDev
+				if sigma > validation.MaxBodySizeSigma {
+					result.Passed = false
+					result.Errors = append(result.Errors, ValidationError{
+						Type:     "simulation_body_size_anomaly",
+						Field:    fmt.Sprintf("results[%d]", i),
+						Expected: fmt.Sprintf("within %.1f sigma of mean %.0f bytes", validation.MaxBodySizeSigma, mean),
+						Actual:   fmt.Sprintf("%d bytes (%.1f sigma)", r.BodySize, sigma),
+						Message:  "response body size is a statistical outlier for this run",
+					})
+				}
+			}
+		}
+	}
+
+	if baseline != nil && baseline.Result != nil && validation.MaxResponseTimeShiftPercent > 0 && digest.Count() > 0 {
+		ve.checkResponseTimeShift(validation, digest, currentTimes, baseline, result)
+	}
+
+	return result
+}
+
+// checkResponseTimeShift compares the current run's P50/P95 against
+// baseline.Result's stored percentiles, only failing the check when the
+// shift also clears statistical significance. Significance is tested with a
+// Mann-Whitney U test (metrics.mannWhitneyU via CompareResponseTimeSamples)
+// against baseline.ResponseTimes when available; without raw baseline
+// samples there's no way to test significance, so any shift past the
+// threshold is reported directly.
+func (ve *ValidationEngine) checkResponseTimeShift(validation *SimulationValidation, digest *metrics.Digest, currentTimes []time.Duration, baseline *BaselineRun, result *ValidationResult) {
+	alpha := validation.ShiftSignificanceAlpha
+	if alpha == 0 {
+		alpha = 0.05
+	}
+
+	checks := []struct {
+		name     string
+		current  time.Duration
+		baseline time.Duration
+	}{
+		{"p50", digest.Quantile(0.5), time.Duration(baseline.Result.MedianResponseTime)},
+		{"p95", digest.Quantile(0.95), time.Duration(baseline.Result.P95ResponseTime)},
+	}
+
+	significant := true
+	var pValue float64
+	if len(baseline.ResponseTimes) > 0 {
+		significant, pValue = metrics.CompareResponseTimeSamples(currentTimes, baseline.ResponseTimes, alpha)
+	}
+
+	for _, c := range checks {
+		if c.baseline <= 0 {
+			continue
+		}
+		shiftPercent := float64(c.current-c.baseline) / float64(c.baseline) * 100
+		if math.Abs(shiftPercent) <= validation.MaxResponseTimeShiftPercent {
+			continue
+		}
+		if !significant {
+			result.Warnings = append(result.Warnings, ValidationWarning{
+				Type:    "simulation_distribution_shift",
+				Message: fmt.Sprintf("%s shifted %.1f%% vs baseline (%s -> %s) but isn't statistically significant (p=%.4f, alpha=%.4f)", c.name, shiftPercent, c.baseline, c.current, pValue, alpha),
+			})
+			continue
+		}
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:     "simulation_distribution_shift",
+			Expected: fmt.Sprintf("within %.1f%% of baseline %s", validation.MaxResponseTimeShiftPercent, c.baseline),
+			Actual:   fmt.Sprintf("%s (%.1f%% shift, p=%.4f)", c.current, shiftPercent, pValue),
+			Message:  fmt.Sprintf("%s response time shifted %.1f%% vs baseline, which is statistically significant (p=%.4f <= alpha %.4f)", c.name, shiftPercent, pValue, alpha),
+		})
+	}
+}
@@ -0,0 +1,562 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/database"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SimulationListFilter narrows ListSimulations by status, a name substring,
+// and a start-time range, with offset pagination so a dashboard history
+// view doesn't have to load every past run at once.
+type SimulationListFilter struct {
+	Status    string
+	Name      string
+	StartFrom time.Time
+	StartTo   time.Time
+	Limit     int
+	Offset    int
+}
+
+// PersistedSimulation is one simulation's full configuration plus its
+// latest known status snapshot, as read back from a SimulationStore.
+type PersistedSimulation struct {
+	Config *SimulationConfig
+	Status *SimulationStatus
+}
+
+// SavedConfiguration is a named, reusable SimulationConfig saved via
+// SaveConfiguration, independent of any particular run.
+type SavedConfiguration struct {
+	ID          int64
+	Name        string
+	Description string
+	Tags        []string
+	IsDefault   bool
+	Config      *SimulationConfig
+}
+
+// ServiceProfileRecord is a user-registered service profile, the persisted
+// counterpart to the built-in profiles GetServiceProfiles returns statically.
+type ServiceProfileRecord struct {
+	ID          int64
+	Name        string
+	Technology  string
+	BaseURL     string
+	Endpoints   []map[string]string
+	Headers     map[string]string
+	Description string
+	// CapabilityPaths are the paths CapabilityProber probes for this
+	// profile's BaseURL; empty means the prober's own defaults.
+	CapabilityPaths []string
+}
+
+// RetentionPolicy controls how long SimulationStore keeps rows for each
+// entity before RunRetentionSweep deletes them.
+type RetentionPolicy struct {
+	SimulationTTL time.Duration
+	TimeSeriesTTL time.Duration
+	ValidationTTL time.Duration
+}
+
+// DefaultSimulationRetentionPolicy keeps finished simulations and their
+// validation history for 30 days and raw time-series points (the bulkiest,
+// least useful-long-term rows) for 7.
+func DefaultSimulationRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		SimulationTTL: 30 * 24 * time.Hour,
+		TimeSeriesTTL: 7 * 24 * time.Hour,
+		ValidationTTL: 30 * 24 * time.Hour,
+	}
+}
+
+// SimulationStore persists everything about a simulation run - its
+// configuration, status snapshots, time-series history, and validation
+// records - so they survive process restarts, plus the saved
+// configurations and service profiles that exist independent of any run.
+// GormSimulationStore is the only implementation; the interface exists so
+// it can be swapped for a fake in tests the way ValidationEngine's
+// GoldenStore is.
+type SimulationStore interface {
+	// SaveSimulationConfig upserts a simulation's configuration and latest
+	// status, keyed by config.ID.
+	SaveSimulationConfig(config *SimulationConfig, status *SimulationStatus) error
+	// SaveSimulationResult records a finished simulation's final metrics as
+	// a new SimulationResult row, preserving history across re-runs.
+	SaveSimulationResult(status *SimulationStatus) error
+	GetSimulation(id int64) (*PersistedSimulation, error)
+	// ListSimulations returns the page of simulations matching filter plus
+	// the total row count it was paginated from.
+	ListSimulations(filter SimulationListFilter) ([]PersistedSimulation, int64, error)
+	DeleteSimulation(id int64) error
+
+	// AppendTimeSeriesPoints batches points into one write so the
+	// once-a-second reportMetrics tick doesn't issue a round-trip per point
+	// at high simulation counts.
+	AppendTimeSeriesPoints(simulationID int64, points []TimeSeriesPoint) error
+	GetTimeSeriesSince(simulationID int64, since time.Time, limit int) ([]TimeSeriesPoint, error)
+
+	AppendValidationRecords(simulationID int64, records []ValidationRecord) error
+	ListValidationRecords(simulationID int64, limit, offset int) ([]ValidationRecord, int64, error)
+
+	SaveConfiguration(cfg *SavedConfiguration) error
+	ListConfigurations() ([]SavedConfiguration, error)
+
+	SaveServiceProfile(profile *ServiceProfileRecord) error
+	ListServiceProfiles() ([]ServiceProfileRecord, error)
+
+	// RunRetentionSweep deletes rows past each entity's TTL in policy.
+	RunRetentionSweep(policy RetentionPolicy) error
+}
+
+// GormSimulationStore is the GORM-backed SimulationStore, working the same
+// against the sqlite.Open and postgres.Open dialectors database.Initialize
+// and database.InitializeSQLite configure.
+type GormSimulationStore struct {
+	db *gorm.DB
+}
+
+// NewGormSimulationStore returns a SimulationStore backed by db. db's
+// schema must already be migrated (see database.Initialize).
+func NewGormSimulationStore(db *gorm.DB) *GormSimulationStore {
+	return &GormSimulationStore{db: db}
+}
+
+func (s *GormSimulationStore) SaveSimulationConfig(config *SimulationConfig, status *SimulationStatus) error {
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("encode simulation config: %w", err)
+	}
+
+	row := database.Simulation{
+		ID:         config.ID,
+		Name:       config.Name,
+		TargetURL:  config.TargetURL,
+		Method:     config.Method,
+		MaxRPS:     int(config.MaxRPS),
+		Duration:   int64(config.Duration.Seconds()),
+		Users:      config.ConcurrentUsers,
+		Pattern:    string(config.Pattern),
+		Status:     status.Status,
+		StartTime:  status.StartTime,
+		EndTime:    status.EndTime,
+		ConfigJSON: string(configJSON),
+	}
+
+	return s.db.Where("id = ?", config.ID).
+		Assign(row).
+		FirstOrCreate(&row).Error
+}
+
+func (s *GormSimulationStore) SaveSimulationResult(status *SimulationStatus) error {
+	result := database.SimulationResult{
+		SimulationID:   status.ID,
+		TotalRequests:  status.TotalRequests,
+		SuccessfulReqs: status.SuccessfulReqs,
+		FailedRequests: status.FailedRequests,
+		AverageRPS:     status.AverageRPS,
+	}
+	if status.ResponseTimes != nil {
+		result.MinResponseTime = status.ResponseTimes.Min.Microseconds()
+		result.MaxResponseTime = status.ResponseTimes.Max.Microseconds()
+		result.AvgResponseTime = status.ResponseTimes.Mean.Microseconds()
+		result.MedianResponseTime = status.ResponseTimes.Median.Microseconds()
+		result.P95ResponseTime = status.ResponseTimes.P95.Microseconds()
+		result.P99ResponseTime = status.ResponseTimes.P99.Microseconds()
+	}
+	if status.TotalRequests > 0 {
+		result.ErrorRate = float64(status.FailedRequests) / float64(status.TotalRequests) * 100
+	}
+
+	return s.db.Create(&result).Error
+}
+
+func (s *GormSimulationStore) GetSimulation(id int64) (*PersistedSimulation, error) {
+	var row database.Simulation
+	if err := s.db.Preload("Results", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at desc").Limit(1)
+	}).Where("id = ?", id).First(&row).Error; err != nil {
+		return nil, err
+	}
+	return persistedSimulationFromRow(&row)
+}
+
+func (s *GormSimulationStore) ListSimulations(filter SimulationListFilter) ([]PersistedSimulation, int64, error) {
+	query := s.db.Model(&database.Simulation{}).Preload("Results", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at desc").Limit(1)
+	})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Name != "" {
+		query = query.Where("name LIKE ?", "%"+filter.Name+"%")
+	}
+	if !filter.StartFrom.IsZero() {
+		query = query.Where("start_time >= ?", filter.StartFrom)
+	}
+	if !filter.StartTo.IsZero() {
+		query = query.Where("start_time <= ?", filter.StartTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count simulations: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var rows []database.Simulation
+	err := query.Order("start_time desc").Limit(limit).Offset(filter.Offset).Find(&rows).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list simulations: %w", err)
+	}
+
+	simulations := make([]PersistedSimulation, 0, len(rows))
+	for i := range rows {
+		sim, err := persistedSimulationFromRow(&rows[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		simulations = append(simulations, *sim)
+	}
+	return simulations, total, nil
+}
+
+func (s *GormSimulationStore) DeleteSimulation(id int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).Delete(&database.Simulation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("simulation_id = ?", id).Delete(&database.SimulationResult{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("simulation_id = ?", id).Delete(&database.TimeSeriesEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("simulation_id = ?", id).Delete(&database.ValidationRecord{}).Error
+	})
+}
+
+func (s *GormSimulationStore) AppendTimeSeriesPoints(simulationID int64, points []TimeSeriesPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	rows := make([]database.TimeSeriesEntry, len(points))
+	for i, p := range points {
+		rows[i] = database.TimeSeriesEntry{
+			SimulationID: simulationID,
+			Timestamp:    p.Timestamp,
+			RPS:          p.RPS,
+			TargetRPS:    p.TargetRPS,
+			ResponseTime: p.ResponseTime,
+			ErrorRate:    p.ErrorRate,
+			ActiveUsers:  p.ActiveUsers,
+		}
+	}
+	return s.db.Create(&rows).Error
+}
+
+func (s *GormSimulationStore) GetTimeSeriesSince(simulationID int64, since time.Time, limit int) ([]TimeSeriesPoint, error) {
+	query := s.db.Where("simulation_id = ?", simulationID)
+	if !since.IsZero() {
+		query = query.Where("timestamp >= ?", since)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var rows []database.TimeSeriesEntry
+	if err := query.Order("timestamp asc").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("query time series: %w", err)
+	}
+
+	points := make([]TimeSeriesPoint, len(rows))
+	for i, r := range rows {
+		points[i] = TimeSeriesPoint{
+			Timestamp:    r.Timestamp,
+			RPS:          r.RPS,
+			TargetRPS:    r.TargetRPS,
+			ResponseTime: r.ResponseTime,
+			ErrorRate:    r.ErrorRate,
+			ActiveUsers:  r.ActiveUsers,
+		}
+	}
+	return points, nil
+}
+
+func (s *GormSimulationStore) AppendValidationRecords(simulationID int64, records []ValidationRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	rows := make([]database.ValidationRecord, len(records))
+	for i, rec := range records {
+		resultJSON, err := json.Marshal(rec.ValidationResult)
+		if err != nil {
+			return fmt.Errorf("encode validation result: %w", err)
+		}
+		rows[i] = database.ValidationRecord{
+			SimulationID:   simulationID,
+			Timestamp:      rec.Timestamp,
+			ResponseTimeNs: rec.ResponseTime.Nanoseconds(),
+			StatusCode:     rec.StatusCode,
+			Passed:         rec.ValidationResult != nil && rec.ValidationResult.Passed,
+			ResultJSON:     string(resultJSON),
+		}
+	}
+	return s.db.Create(&rows).Error
+}
+
+func (s *GormSimulationStore) ListValidationRecords(simulationID int64, limit, offset int) ([]ValidationRecord, int64, error) {
+	query := s.db.Model(&database.ValidationRecord{}).Where("simulation_id = ?", simulationID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count validation records: %w", err)
+	}
+
+	if limit <= 0 {
+		limit = 1000
+	}
+	var rows []database.ValidationRecord
+	if err := query.Order("timestamp desc").Limit(limit).Offset(offset).Find(&rows).Error; err != nil {
+		return nil, 0, fmt.Errorf("list validation records: %w", err)
+	}
+
+	records := make([]ValidationRecord, len(rows))
+	for i, r := range rows {
+		var result ValidationResult
+		if r.ResultJSON != "" {
+			if err := json.Unmarshal([]byte(r.ResultJSON), &result); err != nil {
+				return nil, 0, fmt.Errorf("decode validation result: %w", err)
+			}
+		}
+		records[i] = ValidationRecord{
+			SimulationID:     r.SimulationID,
+			Timestamp:        r.Timestamp,
+			ResponseTime:     time.Duration(r.ResponseTimeNs),
+			StatusCode:       r.StatusCode,
+			ValidationResult: &result,
+		}
+	}
+	return records, total, nil
+}
+
+func (s *GormSimulationStore) SaveConfiguration(cfg *SavedConfiguration) error {
+	configJSON, err := json.Marshal(cfg.Config)
+	if err != nil {
+		return fmt.Errorf("encode configuration: %w", err)
+	}
+
+	row := database.Configuration{
+		ID:          cfg.ID,
+		Name:        cfg.Name,
+		Description: cfg.Description,
+		Config:      string(configJSON),
+		Tags:        joinTags(cfg.Tags),
+		IsDefault:   cfg.IsDefault,
+	}
+
+	if cfg.ID != 0 {
+		return s.db.Where("id = ?", cfg.ID).Assign(row).FirstOrCreate(&row).Error
+	}
+	return s.db.Where("name = ?", cfg.Name).Assign(row).FirstOrCreate(&row).Error
+}
+
+func (s *GormSimulationStore) ListConfigurations() ([]SavedConfiguration, error) {
+	var rows []database.Configuration
+	if err := s.db.Order("updated_at desc").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("list configurations: %w", err)
+	}
+
+	configs := make([]SavedConfiguration, 0, len(rows))
+	for _, row := range rows {
+		var config SimulationConfig
+		if row.Config != "" {
+			if err := json.Unmarshal([]byte(row.Config), &config); err != nil {
+				return nil, fmt.Errorf("decode configuration %q: %w", row.Name, err)
+			}
+		}
+		configs = append(configs, SavedConfiguration{
+			ID:          row.ID,
+			Name:        row.Name,
+			Description: row.Description,
+			Tags:        splitTags(row.Tags),
+			IsDefault:   row.IsDefault,
+			Config:      &config,
+		})
+	}
+	return configs, nil
+}
+
+func (s *GormSimulationStore) SaveServiceProfile(profile *ServiceProfileRecord) error {
+	endpointsJSON, err := json.Marshal(profile.Endpoints)
+	if err != nil {
+		return fmt.Errorf("encode endpoints: %w", err)
+	}
+	headersJSON, err := json.Marshal(profile.Headers)
+	if err != nil {
+		return fmt.Errorf("encode headers: %w", err)
+	}
+	capabilityPathsJSON, err := json.Marshal(profile.CapabilityPaths)
+	if err != nil {
+		return fmt.Errorf("encode capability paths: %w", err)
+	}
+
+	row := database.ServiceProfile{
+		Name:            profile.Name,
+		Technology:      profile.Technology,
+		BaseURL:         profile.BaseURL,
+		Endpoints:       string(endpointsJSON),
+		Headers:         string(headersJSON),
+		Description:     profile.Description,
+		CapabilityPaths: string(capabilityPathsJSON),
+		IsActive:        true,
+	}
+
+	return s.db.Where("name = ?", profile.Name).Assign(row).FirstOrCreate(&row).Error
+}
+
+func (s *GormSimulationStore) ListServiceProfiles() ([]ServiceProfileRecord, error) {
+	var rows []database.ServiceProfile
+	if err := s.db.Where("is_active = ?", true).Order("name asc").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("list service profiles: %w", err)
+	}
+
+	profiles := make([]ServiceProfileRecord, 0, len(rows))
+	for _, row := range rows {
+		var endpoints []map[string]string
+		if row.Endpoints != "" {
+			if err := json.Unmarshal([]byte(row.Endpoints), &endpoints); err != nil {
+				return nil, fmt.Errorf("decode endpoints for %q: %w", row.Name, err)
+			}
+		}
+		var headers map[string]string
+		if row.Headers != "" {
+			if err := json.Unmarshal([]byte(row.Headers), &headers); err != nil {
+				return nil, fmt.Errorf("decode headers for %q: %w", row.Name, err)
+			}
+		}
+		var capabilityPaths []string
+		if row.CapabilityPaths != "" {
+			if err := json.Unmarshal([]byte(row.CapabilityPaths), &capabilityPaths); err != nil {
+				return nil, fmt.Errorf("decode capability paths for %q: %w", row.Name, err)
+			}
+		}
+		profiles = append(profiles, ServiceProfileRecord{
+			ID:              row.ID,
+			Name:            row.Name,
+			Technology:      row.Technology,
+			BaseURL:         row.BaseURL,
+			Endpoints:       endpoints,
+			Headers:         headers,
+			Description:     row.Description,
+			CapabilityPaths: capabilityPaths,
+		})
+	}
+	return profiles, nil
+}
+
+func (s *GormSimulationStore) RunRetentionSweep(policy RetentionPolicy) error {
+	now := time.Now()
+
+	if policy.SimulationTTL > 0 {
+		cutoff := now.Add(-policy.SimulationTTL)
+		var expired []int64
+		if err := s.db.Model(&database.Simulation{}).
+			Where("end_time IS NOT NULL AND end_time < ?", cutoff).
+			Pluck("id", &expired).Error; err != nil {
+			return fmt.Errorf("find expired simulations: %w", err)
+		}
+		for _, id := range expired {
+			if err := s.DeleteSimulation(id); err != nil {
+				return fmt.Errorf("expire simulation %d: %w", id, err)
+			}
+		}
+	}
+
+	if policy.TimeSeriesTTL > 0 {
+		cutoff := now.Add(-policy.TimeSeriesTTL)
+		if err := s.db.Where("timestamp < ?", cutoff).Delete(&database.TimeSeriesEntry{}).Error; err != nil {
+			return fmt.Errorf("expire time series: %w", err)
+		}
+	}
+
+	if policy.ValidationTTL > 0 {
+		cutoff := now.Add(-policy.ValidationTTL)
+		if err := s.db.Where("timestamp < ?", cutoff).Delete(&database.ValidationRecord{}).Error; err != nil {
+			return fmt.Errorf("expire validation records: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// persistedSimulationFromRow decodes row's ConfigJSON blob back into a
+// SimulationConfig and reassembles the status fields ListSimulations/
+// GetSimulation callers expect alongside it.
+func persistedSimulationFromRow(row *database.Simulation) (*PersistedSimulation, error) {
+	var config SimulationConfig
+	if row.ConfigJSON != "" {
+		if err := json.Unmarshal([]byte(row.ConfigJSON), &config); err != nil {
+			return nil, fmt.Errorf("decode simulation %d config: %w", row.ID, err)
+		}
+	}
+
+	status := &SimulationStatus{
+		ID:        row.ID,
+		Name:      row.Name,
+		Status:    row.Status,
+		StartTime: row.StartTime,
+		EndTime:   row.EndTime,
+		Config:    &config,
+	}
+	if len(row.Results) > 0 {
+		latest := row.Results[len(row.Results)-1]
+		status.TotalRequests = latest.TotalRequests
+		status.SuccessfulReqs = latest.SuccessfulReqs
+		status.FailedRequests = latest.FailedRequests
+		status.AverageRPS = latest.AverageRPS
+	}
+
+	return &PersistedSimulation{Config: &config, Status: status}, nil
+}
+
+func joinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
+
+// StartRetentionWorker runs store.RunRetentionSweep(policy) on interval
+// until ctx is cancelled, logging (not failing) sweep errors so a transient
+// DB hiccup doesn't take down the process.
+func StartRetentionWorker(ctx context.Context, store SimulationStore, policy RetentionPolicy, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := store.RunRetentionSweep(policy); err != nil {
+					logrus.Warnf("simulation retention sweep failed: %v", err)
+				}
+			}
+		}
+	}()
+}
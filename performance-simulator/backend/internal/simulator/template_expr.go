@@ -0,0 +1,260 @@
+package simulator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// valueKind identifies which literal type a parsed template argument holds.
+type valueKind int
+
+const (
+	stringValue valueKind = iota
+	intValue
+	floatValue
+	boolValue
+)
+
+// Value is a single positional or keyword argument parsed out of a template
+// expression such as "random_int 100 999" or "date offset=-7d". Literals are
+// typed at parse time (quoted text is always a string; barewords are sniffed
+// as bool, int, float, then string) so functions can work with native Go
+// types instead of re-parsing strings themselves.
+type Value struct {
+	kind valueKind
+	str  string
+	i    int64
+	f    float64
+	b    bool
+}
+
+// StringValue wraps s as a string-typed Value.
+func StringValue(s string) Value { return Value{kind: stringValue, str: s} }
+
+// IntValue wraps i as an int-typed Value.
+func IntValue(i int64) Value { return Value{kind: intValue, i: i} }
+
+// FloatValue wraps f as a float-typed Value.
+func FloatValue(f float64) Value { return Value{kind: floatValue, f: f} }
+
+// BoolValue wraps b as a bool-typed Value.
+func BoolValue(b bool) Value { return Value{kind: boolValue, b: b} }
+
+// AsString renders v as plain text regardless of its underlying kind.
+func (v Value) AsString() string {
+	switch v.kind {
+	case intValue:
+		return strconv.FormatInt(v.i, 10)
+	case floatValue:
+		return strconv.FormatFloat(v.f, 'f', -1, 64)
+	case boolValue:
+		return strconv.FormatBool(v.b)
+	default:
+		return v.str
+	}
+}
+
+// AsInt coerces v to an int64, parsing string values if necessary.
+func (v Value) AsInt() (int64, error) {
+	switch v.kind {
+	case intValue:
+		return v.i, nil
+	case floatValue:
+		return int64(v.f), nil
+	case stringValue:
+		return strconv.ParseInt(v.str, 10, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %q to int", v.AsString())
+	}
+}
+
+// AsFloat coerces v to a float64, parsing string values if necessary.
+func (v Value) AsFloat() (float64, error) {
+	switch v.kind {
+	case floatValue:
+		return v.f, nil
+	case intValue:
+		return float64(v.i), nil
+	case stringValue:
+		return strconv.ParseFloat(v.str, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %q to float", v.AsString())
+	}
+}
+
+// VariableFunc produces a resolved string from the positional and keyword
+// arguments parsed out of a template expression. Functions that ignore args
+// and kwargs remain valid zero-arg calls, e.g. {{uuid}}.
+type VariableFunc func(args []Value, kwargs map[string]Value) (string, error)
+
+// FilterFunc post-processes the string a VariableFunc (or static variable)
+// resolved to, e.g. {{uuid | upper}}.
+type FilterFunc func(value string) (string, error)
+
+// templateExpr is the parsed form of a "{{...}}" placeholder body:
+// name arg1 arg2 key=value | filter1 | filter2
+type templateExpr struct {
+	Name    string
+	Args    []Value
+	Kwargs  map[string]Value
+	Filters []string
+}
+
+// parseTemplateExpr tokenizes a template expression body into a call (name,
+// positional args, keyword args) plus an ordered list of filters applied to
+// its result.
+func parseTemplateExpr(raw string) (*templateExpr, error) {
+	segments := splitPipeline(raw)
+
+	name, args, kwargs, err := parseCallSegment(segments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	expr := &templateExpr{Name: name, Args: args, Kwargs: kwargs}
+	for _, seg := range segments[1:] {
+		filterName := strings.TrimSpace(seg)
+		if filterName == "" {
+			return nil, fmt.Errorf("empty filter name")
+		}
+		expr.Filters = append(expr.Filters, filterName)
+	}
+	return expr, nil
+}
+
+// splitPipeline splits raw on top-level '|' characters, treating anything
+// between double quotes as opaque so a '|' inside a quoted argument isn't
+// mistaken for a filter separator.
+func splitPipeline(raw string) []string {
+	var segments []string
+	var sb strings.Builder
+	inQuotes := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+			sb.WriteByte(c)
+		case c == '|' && !inQuotes:
+			segments = append(segments, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	segments = append(segments, sb.String())
+	return segments
+}
+
+// parseCallSegment tokenizes the "name arg1 arg2 key=value" portion of a
+// template expression. Tokens are separated by whitespace or commas; double
+// quotes group a token (including embedded whitespace/commas) into a single
+// string literal. The first token is always the function/variable name.
+func parseCallSegment(seg string) (string, []Value, map[string]Value, error) {
+	kwargs := make(map[string]Value)
+	var args []Value
+	name := ""
+
+	i, n := 0, len(seg)
+	for i < n {
+		for i < n && (seg[i] == ' ' || seg[i] == '\t' || seg[i] == ',') {
+			i++
+		}
+		if i >= n {
+			break
+		}
+
+		var raw string
+		var quoted bool
+		if seg[i] == '"' {
+			j := i + 1
+			var sb strings.Builder
+			closed := false
+			for j < n {
+				if seg[j] == '\\' && j+1 < n {
+					sb.WriteByte(seg[j+1])
+					j += 2
+					continue
+				}
+				if seg[j] == '"' {
+					closed = true
+					j++
+					break
+				}
+				sb.WriteByte(seg[j])
+				j++
+			}
+			if !closed {
+				return "", nil, nil, fmt.Errorf("unterminated string literal")
+			}
+			raw, quoted = sb.String(), true
+			i = j
+		} else {
+			j := i
+			for j < n && seg[j] != ' ' && seg[j] != '\t' && seg[j] != ',' {
+				j++
+			}
+			raw = seg[i:j]
+			i = j
+		}
+
+		if name == "" {
+			if quoted {
+				return "", nil, nil, fmt.Errorf("function name cannot be a quoted string")
+			}
+			name = raw
+			continue
+		}
+
+		if quoted {
+			args = append(args, StringValue(raw))
+			continue
+		}
+
+		if eq := strings.IndexByte(raw, '='); eq > 0 {
+			key := raw[:eq]
+			valText, valQuoted := unquote(raw[eq+1:])
+			if valQuoted {
+				kwargs[key] = StringValue(valText)
+			} else {
+				kwargs[key] = parseBareValue(valText)
+			}
+			continue
+		}
+
+		args = append(args, parseBareValue(raw))
+	}
+
+	if name == "" {
+		return "", nil, nil, fmt.Errorf("empty expression")
+	}
+	return name, args, kwargs, nil
+}
+
+// unquote strips a single layer of surrounding double quotes from s, if
+// present, reporting whether it did so.
+func unquote(s string) (string, bool) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1], true
+	}
+	return s, false
+}
+
+// parseBareValue sniffs an unquoted token as a bool, int, or float literal,
+// falling back to a plain string (e.g. "-7d" in offset=-7d).
+func parseBareValue(s string) Value {
+	switch s {
+	case "true":
+		return BoolValue(true)
+	case "false":
+		return BoolValue(false)
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return IntValue(i)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return FloatValue(f)
+	}
+	return StringValue(s)
+}
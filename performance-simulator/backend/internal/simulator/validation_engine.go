@@ -11,21 +11,43 @@ import (
 	"sync"
 	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/antchfx/xmlquery"
+	"github.com/go-openapi/loads"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 // ValidationEngine handles response validation logic
 type ValidationEngine struct {
-	jsonSchemaCache map[string]interface{}
+	jsonSchemaCache map[string]*gojsonschema.Schema // keyed by sha256(schema JSON), see json_schema.go
+	schemaStore     map[string]string               // ref/URI -> raw schema JSON, see RegisterSchema
+	openAPICache    map[string]*loads.Document       // keyed by OpenAPISpec path/URL, see openapi_validation.go
 	regexCache      map[string]*regexp.Regexp
 	mutex           sync.RWMutex
 	assertionEngine *AssertionEngine
+
+	// goldenStore backs Body.Diff validation (golden.go). Nil until
+	// SetGoldenStore is called, in which case diff validation reports an
+	// error explaining it hasn't been wired up rather than panicking.
+	goldenStore GoldenStore
+}
+
+// SetGoldenStore wires store into the engine so Body.Diff validation can
+// record and look up golden responses. Called once from NewEngine with a
+// GORM-backed store; left unset (nil) in contexts like TestValidation that
+// never exercise diff validation.
+func (ve *ValidationEngine) SetGoldenStore(store GoldenStore) {
+	ve.mutex.Lock()
+	defer ve.mutex.Unlock()
+	ve.goldenStore = store
 }
 
 // NewValidationEngine creates a new validation engine
 func NewValidationEngine() *ValidationEngine {
+	registerDefaultFormatCheckers()
 	return &ValidationEngine{
-		jsonSchemaCache: make(map[string]interface{}),
+		jsonSchemaCache: make(map[string]*gojsonschema.Schema),
+		schemaStore:     make(map[string]string),
+		openAPICache:    make(map[string]*loads.Document),
 		regexCache:      make(map[string]*regexp.Regexp),
 		assertionEngine: NewAssertionEngine(),
 	}
@@ -33,6 +55,18 @@ func NewValidationEngine() *ValidationEngine {
 
 // ValidateResponse performs comprehensive response validation
 func (ve *ValidationEngine) ValidateResponse(resp *http.Response, body []byte, validation *ResponseValidation, responseTime time.Duration) *ValidationResult {
+	return ve.validateResponse(resp, body, validation, responseTime, 0)
+}
+
+// ValidateResponseForSimulation is ValidateResponse scoped to a specific
+// simulation run. The simulation ID is only needed by Body.Diff, which
+// records and looks up golden responses per simulation (see
+// ValidationEngine.validateDiff); every other validation is unaffected.
+func (ve *ValidationEngine) ValidateResponseForSimulation(resp *http.Response, body []byte, validation *ResponseValidation, responseTime time.Duration, simulationID int64) *ValidationResult {
+	return ve.validateResponse(resp, body, validation, responseTime, simulationID)
+}
+
+func (ve *ValidationEngine) validateResponse(resp *http.Response, body []byte, validation *ResponseValidation, responseTime time.Duration, simulationID int64) *ValidationResult {
 	startTime := time.Now()
 	
 	result := &ValidationResult{
@@ -42,6 +76,10 @@ func (ve *ValidationEngine) ValidateResponse(resp *http.Response, body []byte, v
 		Assertions: make([]AssertionResult, 0),
 	}
 
+	if resp.TLS != nil {
+		result.TLSPeerCertificates = peerCertificatesFrom(resp.TLS.PeerCertificates)
+	}
+
 	// Validate status code
 	if len(validation.StatusCodes) > 0 {
 		ve.validateStatusCode(resp, validation.StatusCodes, result)
@@ -68,11 +106,20 @@ func (ve *ValidationEngine) ValidateResponse(resp *http.Response, body []byte, v
 		result.Passed = result.Passed && bodyResult.Passed
 		result.Errors = append(result.Errors, bodyResult.Errors...)
 		result.Warnings = append(result.Warnings, bodyResult.Warnings...)
+
+		if validation.Body.Diff != nil {
+			ve.validateDiff(resp, body, validation.Body, simulationID, result)
+		}
+	}
+
+	// Validate against an OpenAPI/Swagger operation contract
+	if validation.OpenAPISpec != "" && validation.OperationID != "" {
+		ve.validateOpenAPI(resp, body, validation, result)
 	}
 
 	// Execute custom assertions
 	if len(validation.Assertions) > 0 {
-		assertionResults := ve.assertionEngine.ExecuteAssertions(resp, body, validation.Assertions)
+		assertionResults := ve.assertionEngine.ExecuteAssertions(resp, body, validation.Assertions, responseTime)
 		result.Assertions = assertionResults
 		for _, ar := range assertionResults {
 			if !ar.Passed {
@@ -250,8 +297,8 @@ func (ve *ValidationEngine) validateJSONBody(body []byte, validation *BodyValida
 	}
 
 	// JSON Schema validation
-	if validation.JSONSchema != "" {
-		ve.validateJSONSchema(body, validation.JSONSchema, result)
+	if validation.JSONSchema != "" || validation.JSONSchemaRef != "" {
+		ve.validateJSONSchema(body, validation, result)
 	}
 
 	// JSONPath assertions
@@ -260,45 +307,20 @@ func (ve *ValidationEngine) validateJSONBody(body []byte, validation *BodyValida
 	}
 }
 
-// validateJSONSchema validates JSON against a schema (simplified implementation)
-func (ve *ValidationEngine) validateJSONSchema(body []byte, schemaStr string, result *ValidationResult) {
-	// For a full implementation, you would use a proper JSON Schema library
-	// This is a simplified version for demonstration
-	logrus.Debug("JSON Schema validation - simplified implementation")
-	
-	// Parse schema
-	var schema interface{}
-	if err := json.Unmarshal([]byte(schemaStr), &schema); err != nil {
-		result.Passed = false
-		result.Errors = append(result.Errors, ValidationError{
-			Type:    "json_schema",
-			Message: fmt.Sprintf("Invalid JSON schema: %v", err),
-		})
-		return
-	}
-
-	// Parse response body
-	var jsonData interface{}
-	if err := json.Unmarshal(body, &jsonData); err != nil {
-		result.Passed = false
-		result.Errors = append(result.Errors, ValidationError{
-			Type:    "json_parse",
-			Message: fmt.Sprintf("Invalid JSON response: %v", err),
-		})
-		return
-	}
-
-	// Basic schema validation (in production, use a proper JSON Schema library)
-	logrus.Debug("JSON Schema validation passed (simplified)")
-}
-
-// validateJSONPath validates JSONPath assertions
+// validateJSONPath validates JSONPath (or JSONPointer) assertions
 func (ve *ValidationEngine) validateJSONPath(jsonData interface{}, assertions []JSONPathAssertion, result *ValidationResult) {
 	for _, assertion := range assertions {
-		// Simplified JSONPath implementation
-		// In production, use a proper JSONPath library like github.com/oliveagle/jsonpath
-		value := ve.extractJSONPathValue(jsonData, assertion.Path)
-		
+		value, err := defaultJSONPathEvaluator.Evaluate(jsonData, assertion.Path)
+		if err != nil {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:    "jsonpath",
+				Field:   assertion.Path,
+				Message: fmt.Sprintf("invalid JSONPath %q: %v", assertion.Path, err),
+			})
+			continue
+		}
+
 		if !ve.evaluateAssertion(value, assertion.Expected, assertion.Operator) {
 			result.Passed = false
 			result.Errors = append(result.Errors, ValidationError{
@@ -312,48 +334,57 @@ func (ve *ValidationEngine) validateJSONPath(jsonData interface{}, assertions []
 	}
 }
 
-// extractJSONPathValue extracts a value using a simplified JSONPath implementation
-func (ve *ValidationEngine) extractJSONPathValue(data interface{}, path string) interface{} {
-	// Simplified JSONPath implementation for basic paths like $.field or $.field.subfield
-	// In production, use a proper JSONPath library
-	
-	if path == "$" {
-		return data
+// validateXMLBody performs XML-specific validation, parsing body once into
+// an xmlquery document and evaluating every XPath assertion against it.
+func (ve *ValidationEngine) validateXMLBody(body []byte, validation *BodyValidation, result *ValidationResult) {
+	if len(validation.XPath) == 0 {
+		return
 	}
-	
-	if strings.HasPrefix(path, "$.") {
-		fieldPath := strings.TrimPrefix(path, "$.")
-		return ve.getNestedValue(data, fieldPath)
+
+	doc, err := parseXMLDocument(string(body))
+	if err != nil {
+		result.Passed = false
+		result.Errors = append(result.Errors, ValidationError{
+			Type:    "xpath",
+			Message: fmt.Sprintf("invalid XML: %v", err),
+		})
+		return
 	}
-	
-	return nil
+
+	ve.validateXPath(doc, validation.XPath, result)
 }
 
-// getNestedValue extracts nested values from JSON data
-func (ve *ValidationEngine) getNestedValue(data interface{}, path string) interface{} {
-	parts := strings.Split(path, ".")
-	current := data
-	
-	for _, part := range parts {
-		if m, ok := current.(map[string]interface{}); ok {
-			current = m[part]
+// validateXPath validates XPath assertions against an already-parsed XML
+// document, mirroring validateJSONPath's structure and operator semantics.
+func (ve *ValidationEngine) validateXPath(doc *xmlquery.Node, assertions []XPathAssertion, result *ValidationResult) {
+	for _, assertion := range assertions {
+		var value interface{}
+		var err error
+		if len(assertion.Namespaces) > 0 {
+			value, err = defaultXPathEvaluator.EvaluateWithNamespaces(doc, assertion.XPath, assertion.Namespaces)
 		} else {
-			return nil
+			value, err = defaultXPathEvaluator.Evaluate(doc, assertion.XPath)
+		}
+		if err != nil {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:    "xpath",
+				Field:   assertion.XPath,
+				Message: fmt.Sprintf("invalid XPath %q: %v", assertion.XPath, err),
+			})
+			continue
 		}
-	}
-	
-	return current
-}
 
-// validateXMLBody performs XML-specific validation
-func (ve *ValidationEngine) validateXMLBody(body []byte, validation *BodyValidation, result *ValidationResult) {
-	// XML and XPath validation would require XML parsing libraries
-	// This is a placeholder for the full implementation
-	logrus.Debug("XML validation - placeholder implementation")
-	
-	for _, xpath := range validation.XPath {
-		// In production, use an XML parser and XPath library
-		logrus.Debugf("XPath validation: %s", xpath.XPath)
+		if !ve.evaluateAssertion(value, assertion.Expected, assertion.Operator) {
+			result.Passed = false
+			result.Errors = append(result.Errors, ValidationError{
+				Type:     "xpath",
+				Field:    assertion.XPath,
+				Expected: assertion.Expected,
+				Actual:   fmt.Sprintf("%v", value),
+				Message:  fmt.Sprintf("XPath assertion failed: %s %s %v", assertion.XPath, assertion.Operator, assertion.Expected),
+			})
+		}
 	}
 }
 
@@ -410,8 +441,20 @@ func (ve *ValidationEngine) getCompiledRegex(pattern string) (*regexp.Regexp, er
 	return regex, nil
 }
 
-// evaluateAssertion evaluates a comparison assertion
+// evaluateAssertion evaluates a comparison assertion. When actual is a
+// []interface{} (a multi-match JSONPath result), "equals" and "contains"
+// both become a membership check against expected, rather than comparing
+// the whole slice as one value.
 func (ve *ValidationEngine) evaluateAssertion(actual interface{}, expected interface{}, operator string) bool {
+	if arr, ok := actual.([]interface{}); ok && (operator == "equals" || operator == "contains") {
+		for _, item := range arr {
+			if reflect.DeepEqual(item, expected) {
+				return true
+			}
+		}
+		return false
+	}
+
 	switch operator {
 	case "equals":
 		return reflect.DeepEqual(actual, expected)
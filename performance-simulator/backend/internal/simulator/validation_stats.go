@@ -0,0 +1,175 @@
+package simulator
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// validationStatsRefreshInterval is how often ValidationStatsTracker
+// re-ranks each simulation's error counts into CommonErrors. Counts
+// themselves are tallied on the request-handling hot path (a cheap map
+// increment, the same pattern metrics.Collector.RecordRequestOutcome
+// uses); sorting them into a ranked list is deferred to this background
+// tick so a busy simulation never pays for a sort per request.
+const validationStatsRefreshInterval = 5 * time.Second
+
+// simulationValidationStats is one simulation's accumulated validation
+// tallies. errorCounts is updated synchronously by Record; commonErrors is
+// rebuilt from it periodically by ValidationStatsTracker's background
+// goroutine.
+type simulationValidationStats struct {
+	total, passed, failed int64
+	errorCounts           map[string]*ValidationErrorSummary
+	commonErrors          []ValidationErrorSummary
+}
+
+// ValidationStatsTracker aggregates recorded ValidationResults into a
+// per-simulation ValidationStats, the same fetch-cache-refresh shape
+// CapabilityProber uses for probed targets: cheap synchronous writes on
+// the hot path, with the more expensive ranking work done by a background
+// goroutine a reader never blocks on.
+type ValidationStatsTracker struct {
+	mu    sync.RWMutex
+	stats map[int64]*simulationValidationStats
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// NewValidationStatsTracker starts the background CommonErrors refresh
+// loop and returns a ready-to-use ValidationStatsTracker. Call Stop when
+// the engine shuts down so the goroutine doesn't leak.
+func NewValidationStatsTracker() *ValidationStatsTracker {
+	return NewValidationStatsTrackerWithInterval(validationStatsRefreshInterval)
+}
+
+// NewValidationStatsTrackerWithInterval is NewValidationStatsTracker with
+// an injectable refresh interval, so tests can observe a CommonErrors
+// refresh without waiting on validationStatsRefreshInterval.
+func NewValidationStatsTrackerWithInterval(refreshInterval time.Duration) *ValidationStatsTracker {
+	t := &ValidationStatsTracker{
+		stats:  make(map[int64]*simulationValidationStats),
+		stopCh: make(chan struct{}),
+	}
+	go t.refreshLoop(refreshInterval)
+	return t
+}
+
+// Record tallies one validation outcome for simulationID, bumping the
+// count for each of result.Errors' Type when result didn't pass.
+func (t *ValidationStatsTracker) Record(simulationID int64, result *ValidationResult) {
+	if result == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sc, ok := t.stats[simulationID]
+	if !ok {
+		sc = &simulationValidationStats{errorCounts: make(map[string]*ValidationErrorSummary)}
+		t.stats[simulationID] = sc
+	}
+
+	sc.total++
+	if result.Passed {
+		sc.passed++
+		return
+	}
+	sc.failed++
+
+	now := time.Now()
+	for _, e := range result.Errors {
+		summary, ok := sc.errorCounts[e.Type]
+		if !ok {
+			summary = &ValidationErrorSummary{Type: e.Type}
+			sc.errorCounts[e.Type] = summary
+		}
+		summary.Count++
+		summary.LastSeen = now
+	}
+}
+
+// Stats returns simulationID's current ValidationStats, including the
+// CommonErrors ranking as of the tracker's last background refresh.
+func (t *ValidationStatsTracker) Stats(simulationID int64) ValidationStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	sc, ok := t.stats[simulationID]
+	if !ok {
+		return ValidationStats{}
+	}
+
+	stats := ValidationStats{
+		TotalValidations:  sc.total,
+		PassedValidations: sc.passed,
+		FailedValidations: sc.failed,
+		CommonErrors:      sc.commonErrors,
+	}
+	if sc.total > 0 {
+		stats.PassRate = float64(sc.passed) / float64(sc.total)
+	}
+	return stats
+}
+
+// Reset discards simulationID's tallies, e.g. once its results have been
+// persisted and the in-memory view is no longer needed.
+func (t *ValidationStatsTracker) Reset(simulationID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.stats, simulationID)
+}
+
+// Stop halts the background refresh goroutine; safe to call more than
+// once.
+func (t *ValidationStatsTracker) Stop() {
+	t.stopOnce.Do(func() { close(t.stopCh) })
+}
+
+func (t *ValidationStatsTracker) refreshLoop(refreshInterval time.Duration) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.refresh()
+		case <-t.stopCh:
+			return
+		}
+	}
+}
+
+// refresh rebuilds every tracked simulation's CommonErrors, ranked by
+// frequency (most common first, ties broken by error type for a stable
+// order).
+func (t *ValidationStatsTracker) refresh() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, sc := range t.stats {
+		if len(sc.errorCounts) == 0 {
+			continue
+		}
+
+		common := make([]ValidationErrorSummary, 0, len(sc.errorCounts))
+		for _, summary := range sc.errorCounts {
+			s := *summary
+			if sc.failed > 0 {
+				s.Percentage = float64(s.Count) / float64(sc.failed) * 100
+			}
+			common = append(common, s)
+		}
+
+		sort.Slice(common, func(i, j int) bool {
+			if common[i].Count != common[j].Count {
+				return common[i].Count > common[j].Count
+			}
+			return common[i].Type < common[j].Type
+		})
+
+		sc.commonErrors = common
+	}
+}
@@ -1,6 +1,10 @@
 package simulator
 
-import "time"
+import (
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/database"
+)
 
 // ResponseValidation defines the validation rules for HTTP responses
 type ResponseValidation struct {
@@ -10,18 +14,116 @@ type ResponseValidation struct {
 	ResponseTime  *TimeValidation       `json:"response_time,omitempty"` // SLA validation
 	ContentType   string                `json:"content_type,omitempty"` // Expected content type
 	Assertions    []Assertion           `json:"assertions,omitempty"`   // Custom assertions
+
+	// OpenAPISpec is a file path or URL to an OpenAPI 3 / Swagger 2 document.
+	// When set together with OperationID, ValidateResponse validates the
+	// response's status, headers, content-type, and body against the schema
+	// the spec declares for that operation (see ValidationEngine.validateOpenAPI).
+	// The parsed spec is cached by ValidationEngine, so repeated runs of the
+	// same scenario don't re-parse it on every request.
+	OpenAPISpec string `json:"openapi_spec,omitempty"`
+
+	// OperationID identifies the operation within OpenAPISpec to validate
+	// against, matching the spec's operationId for the matching path+method.
+	OperationID string `json:"operation_id,omitempty"`
 }
 
 // BodyValidation defines validation rules for response body
 type BodyValidation struct {
-	Type        BodyValidationType `json:"type"`
-	JSONSchema  string            `json:"json_schema,omitempty"`
-	XPath       []XPathAssertion  `json:"xpath,omitempty"`
+	Type       BodyValidationType `json:"type"`
+	JSONSchema string             `json:"json_schema,omitempty"`
+
+	// JSONSchemaRef references a schema registered with
+	// ValidationEngine.RegisterSchema by URI, as an alternative to inlining
+	// the schema in JSONSchema. Ignored if JSONSchema is also set.
+	JSONSchemaRef string `json:"json_schema_ref,omitempty"`
+
+	XPath       []XPathAssertion    `json:"xpath,omitempty"`
 	JSONPath    []JSONPathAssertion `json:"jsonpath,omitempty"`
-	Regex       []RegexAssertion  `json:"regex,omitempty"`
-	Contains    []string          `json:"contains,omitempty"`
-	NotContains []string          `json:"not_contains,omitempty"`
-	Size        *SizeValidation   `json:"size,omitempty"`
+	Regex       []RegexAssertion    `json:"regex,omitempty"`
+	Contains    []string            `json:"contains,omitempty"`
+	NotContains []string            `json:"not_contains,omitempty"`
+	Size        *SizeValidation     `json:"size,omitempty"`
+
+	// Diff, when set, compares the response to a previously recorded golden
+	// response instead of (or alongside) the rules above. See
+	// ValidationEngine.validateDiff (golden.go).
+	Diff *DiffValidation `json:"diff,omitempty"`
+}
+
+// DiffValidation compares a response against a golden response recorded for
+// Key, normalizing both sides (sorted JSON object keys, or canonicalized
+// XML element/attribute structure) before diffing them with
+// github.com/wI2L/jsondiff. Set Record to true during a dedicated "record"
+// run to capture/overwrite the golden instead of diffing against it.
+type DiffValidation struct {
+	// Key identifies which golden response to compare against, scoped to
+	// the current simulation (e.g. an endpoint name); defaults to "default"
+	// if empty.
+	Key string `json:"key,omitempty"`
+
+	// IgnorePaths lists RFC 6901 JSON Pointer paths (e.g. "/timestamp",
+	// "/requestId") to exclude from the comparison.
+	IgnorePaths []string `json:"ignore_paths,omitempty"`
+
+	// Record captures/overwrites the golden response for Key with the live
+	// response instead of diffing against it.
+	Record bool `json:"record,omitempty"`
+}
+
+// SimulationValidation defines simulation-wide statistical thresholds,
+// checked once over a run's full result set by
+// ValidationEngine.ValidateSimulation, as opposed to ResponseValidation's
+// per-request checks. Persisted per-simulation as
+// database.SimulationValidationConfig.
+type SimulationValidation struct {
+	// MaxP95ResponseTime and MaxP99ResponseTime cap the run's overall P95/P99
+	// latency, computed from a streaming digest rather than the per-request
+	// TimeValidation thresholds (which only ever see one response at a time).
+	MaxP95ResponseTime time.Duration `json:"max_p95_response_time,omitempty"`
+	MaxP99ResponseTime time.Duration `json:"max_p99_response_time,omitempty"`
+
+	// MaxErrorRate caps the fraction of failed requests (0-1).
+	MaxErrorRate float64 `json:"max_error_rate,omitempty"`
+
+	// MaxResponseTimeShiftPercent caps how far the run's P50/P95 may move
+	// from Baseline's, as a percentage of the baseline value. A shift is
+	// only reported when it also clears ShiftSignificanceAlpha, so normal
+	// run-to-run noise on a small sample doesn't trip the check.
+	MaxResponseTimeShiftPercent float64 `json:"max_response_time_shift_percent,omitempty"`
+
+	// ShiftSignificanceAlpha is the p-value threshold below which a
+	// Mann-Whitney U test (see metrics.mannWhitneyU) treats the shift as
+	// real rather than noise. Defaults to 0.05 if zero. Only applies when
+	// Baseline carries raw response-time samples.
+	ShiftSignificanceAlpha float64 `json:"shift_significance_alpha,omitempty"`
+
+	// MaxBodySizeSigma flags any response whose body size deviates from the
+	// run's own mean body size by more than this many standard deviations.
+	MaxBodySizeSigma float64 `json:"max_body_size_sigma,omitempty"`
+}
+
+// RequestResult is the per-request outcome ValidateSimulation aggregates
+// over: just enough to drive the simulation-wide checks without requiring
+// the full http.Response/body each request produced.
+type RequestResult struct {
+	ResponseTime time.Duration
+	StatusCode   int
+	Success      bool
+	BodySize     int
+}
+
+// BaselineRun is a prior simulation's results, compared against the current
+// run by ValidateSimulation's distribution-shift check. Result is typically
+// the prior run's persisted database.SimulationResult row, which covers the
+// plain percentage-shift comparison against its stored percentiles.
+// ResponseTimes is optional: when the caller still has the prior run's raw
+// per-request latencies (e.g. a back-to-back comparison run kept in
+// memory), it enables the Mann-Whitney U significance test; an aggregate
+// row alone can't drive that, only the percentage comparison.
+type BaselineRun struct {
+	Result        *database.SimulationResult
+	ResponseTimes []time.Duration
 }
 
 // BodyValidationType defines the type of body validation
@@ -45,7 +147,12 @@ type JSONPathAssertion struct {
 type XPathAssertion struct {
 	XPath    string `json:"xpath"`
 	Expected string `json:"expected"`
-	Operator string `json:"operator"`
+	Operator string `json:"operator"` // equals, not_equals, contains, gt, lt, gte, lte, exists
+
+	// Namespaces maps prefixes used in XPath to their URIs (e.g.
+	// {"soap": "http://schemas.xmlsoap.org/soap/envelope/"}), so XPath can
+	// use prefixed names like "//soap:Body" against namespaced XML.
+	Namespaces map[string]string `json:"namespaces,omitempty"`
 }
 
 // RegexAssertion defines regular expression assertions
@@ -67,13 +174,47 @@ type TimeValidation struct {
 	P99Threshold    time.Duration `json:"p99_threshold,omitempty"`
 }
 
-// Assertion defines custom JavaScript assertions
+// Assertion defines a custom assertion expression, evaluated by
+// AssertionEngine against the response's status, body, headers, size, and
+// duration, e.g. "status == 200 && contains(body, 'ok')".
 type Assertion struct {
 	Name        string `json:"name"`
-	Script      string `json:"script"`      // JavaScript expression
+	Script      string `json:"script"` // Assertion expression, see AssertionEngine
 	Description string `json:"description"`
+
+	// BodyType overrides the response Content-Type sniffing that decides
+	// whether xpath/xmlcontains/soap parse the body as XML. Usually left
+	// empty; set to "xml" or "json" to force it when the target doesn't
+	// set Content-Type correctly.
+	BodyType string `json:"body_type,omitempty"`
+
+	// Type selects which engine evaluates this assertion. Empty (or
+	// AssertionTypeScript) runs Script through AssertionEngine's hand-rolled
+	// expression language; AssertionTypeCEL instead runs Expression as a CEL
+	// program (see AssertionEngine.evaluateCEL); AssertionTypeJS runs
+	// Expression as JavaScript (see AssertionEngine.evaluateJS).
+	Type string `json:"type,omitempty"`
+
+	// Expression is the CEL program to run when Type == AssertionTypeCEL,
+	// e.g. "response.status == 200 && json.items.size() > 0 && duration <
+	// duration('500ms')". It exposes response (status, headers, bodyBytes),
+	// json (the parsed body, or null if it isn't valid JSON), xml (the body
+	// parsed as XML, as nested maps — see xmlNodeToCELValue), duration (the
+	// response time), and a match(pattern, string) regex function. When
+	// Type == AssertionTypeJS, Expression is instead a JavaScript snippet
+	// evaluated in a sandboxed goja.Runtime exposing a Postman-style
+	// pm.response object (json(), text(), headers, code, responseTime) —
+	// see AssertionEngine.evaluateJS.
+	Expression string `json:"expression,omitempty"`
 }
 
+// Assertion.Type values.
+const (
+	AssertionTypeScript = "script"
+	AssertionTypeCEL    = "cel"
+	AssertionTypeJS     = "js"
+)
+
 // ValidationResult represents the result of response validation
 type ValidationResult struct {
 	Passed      bool                    `json:"passed"`
@@ -81,15 +222,35 @@ type ValidationResult struct {
 	Warnings    []ValidationWarning     `json:"warnings,omitempty"`
 	Duration    time.Duration          `json:"duration"`
 	Assertions  []AssertionResult      `json:"assertions,omitempty"`
+
+	// TLSPeerCertificates is the server's negotiated certificate chain, set
+	// when the request was made over TLS, so validation (e.g. a CEL
+	// assertion) can check issuer/SAN/expiry without reaching into
+	// http.Response.TLS directly.
+	TLSPeerCertificates []PeerCertificate `json:"tls_peer_certificates,omitempty"`
+}
+
+// PeerCertificate summarizes one certificate from a response's negotiated
+// TLS chain.
+type PeerCertificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
 }
 
 // ValidationError represents a validation failure
 type ValidationError struct {
-	Type        string `json:"type"`
-	Field       string `json:"field,omitempty"`
-	Expected    string `json:"expected"`
-	Actual      string `json:"actual"`
-	Message     string `json:"message"`
+	Type     string `json:"type"`
+	Field    string `json:"field,omitempty"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Message  string `json:"message"`
+
+	// Pointer is the RFC 6901 JSON pointer to the offending value, set by
+	// json_schema errors (see ValidationEngine.validateJSONSchema).
+	Pointer string `json:"pointer,omitempty"`
 }
 
 // ValidationWarning represents a validation warning (non-fatal)
@@ -1,176 +1,634 @@
 package simulator
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/origo-stack/performance-simulator/internal/simulator/providers"
 )
 
+// templatePattern matches a "{{...}}" placeholder body, e.g. the
+// `random_int 100 999` in `{{random_int 100 999}}`.
+var templatePattern = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// charsets are the character sets random_string can draw from.
+var charsets = map[string]string{
+	"alnum":  "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	"alpha":  "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"hex":    "0123456789abcdef",
+	"digits": "0123456789",
+}
+
 // VariableResolver handles dynamic variable substitution in request bodies and headers
 type VariableResolver struct {
 	variables map[string]string
-	functions map[string]func() string
+	functions map[string]VariableFunc
+	filters   map[string]FilterFunc
+
+	// parent, when set, is consulted for variables/functions/filters this
+	// resolver doesn't have its own entry for. NewChildVariableResolver uses
+	// this to scope captured variables (see CaptureRule) to a virtual user
+	// or simulation while still resolving the same built-in functions.
+	parent *VariableResolver
+
+	// Locale selects which providers.Dataset the name/address/phone/company
+	// functions draw from by default, e.g. "de_DE". A per-call `locale=`
+	// kwarg overrides this for a single expression. Defaults to
+	// providers.DefaultLocale.
+	Locale string
+
+	// rng backs every random_* function on this resolver. It's local to the
+	// resolver (rather than a single package-level generator) so a
+	// simulation and its virtual users can each be seeded independently and
+	// still reproduce the same sequence on replay; rngMu guards it since a
+	// resolver's functions are called concurrently from multiple workers.
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	// VUID identifies the virtual user this resolver is scoped to, exposed
+	// to templates via {{vu_id}}. Set directly by engine.go when it creates
+	// a simulation's per-VU resolvers; left at its zero value on resolvers
+	// that aren't VU-scoped.
+	VUID int
+
+	// iteration counts how many requests this resolver has started (see
+	// BeginIteration), exposed to templates via {{iteration}}.
+	iteration int64
+
+	// feeders is the registry {{csv}}/{{jsonl}} draw rows from. It's nil on
+	// a fresh resolver; engine.go assigns a simulation a registry of its
+	// own on that simulation's simResolver, and NewChildVariableResolver
+	// propagates the same pointer to every resolver descended from it (the
+	// simulation's virtual users), so they all rotate through one shared
+	// cursor per file rather than each starting from row one.
+	feeders *dataFeederRegistry
+
+	// feederRowCache holds the row {{csv}}/{{jsonl}} returned for a given
+	// file path during the current iteration (see BeginIteration), so two
+	// calls naming the same file within one request — e.g. "email" and
+	// "password" columns of the same users.csv row — resolve to the same
+	// row instead of each advancing the shared cursor independently.
+	feederRowCache map[string]map[string]string
 }
 
-// NewVariableResolver creates a new variable resolver with default functions
+// NewVariableResolver creates a new root variable resolver with default
+// functions, seeded from the global random source.
 func NewVariableResolver() *VariableResolver {
+	return newVariableResolver(rand.New(rand.NewSource(rand.Int63())))
+}
+
+// NewVariableResolverWithSeed creates a new root variable resolver whose
+// random_* functions deterministically reproduce the same sequence for a
+// given seed, so a simulation run can be replayed exactly.
+func NewVariableResolverWithSeed(seed int64) *VariableResolver {
+	return newVariableResolver(rand.New(rand.NewSource(seed)))
+}
+
+// NewChildVariableResolver creates a resolver scoped under parent: it starts
+// with no variables of its own, falling back to parent's for anything it
+// doesn't have, but registers its own default functions/filters so its
+// random_* calls draw from its own rng rather than parent's. An optional
+// seed makes the child's random_* sequence reproducible; with none given it
+// draws its own seed from parent's rng, so a deterministic parent still
+// yields deterministic (but distinct) children. SetVariable/SetFunction/
+// SetFilter on the child never mutate parent.
+func NewChildVariableResolver(parent *VariableResolver, seed ...int64) *VariableResolver {
+	var s int64
+	if len(seed) > 0 {
+		s = seed[0]
+	} else {
+		s = parent.nextSeed()
+	}
+	vr := newVariableResolver(rand.New(rand.NewSource(s)))
+	vr.parent = parent
+	vr.Locale = parent.Locale
+	vr.feeders = parent.feeders
+	return vr
+}
+
+// newVariableResolver builds a resolver around rng, registering the default
+// functions/filters so they're bound to this instance's own generator.
+func newVariableResolver(rng *rand.Rand) *VariableResolver {
 	vr := &VariableResolver{
 		variables: make(map[string]string),
-		functions: make(map[string]func() string),
+		functions: make(map[string]VariableFunc),
+		filters:   make(map[string]FilterFunc),
+		rng:       rng,
+		Locale:    providers.DefaultLocale,
 	}
 
-	// Register default functions
 	vr.registerDefaultFunctions()
+	vr.registerDefaultFilters()
 	return vr
 }
 
+// nextSeed draws a seed for a child resolver from vr's own rng, so that a
+// deterministically-seeded resolver produces deterministic child seeds too.
+func (vr *VariableResolver) nextSeed() int64 {
+	vr.rngMu.Lock()
+	defer vr.rngMu.Unlock()
+	return vr.rng.Int63()
+}
+
+// intn, float32 and float64 wrap the resolver's own *rand.Rand so random_*
+// functions don't touch the global math/rand source, keeping each
+// resolver's sequence independent and reproducible from its seed.
+func (vr *VariableResolver) intn(n int) int {
+	vr.rngMu.Lock()
+	defer vr.rngMu.Unlock()
+	return vr.rng.Intn(n)
+}
+
+func (vr *VariableResolver) float32() float32 {
+	vr.rngMu.Lock()
+	defer vr.rngMu.Unlock()
+	return vr.rng.Float32()
+}
+
+func (vr *VariableResolver) float64() float64 {
+	vr.rngMu.Lock()
+	defer vr.rngMu.Unlock()
+	return vr.rng.Float64()
+}
+
+// EnableDataFeeders gives vr its own data feeder registry, so {{csv}}/
+// {{jsonl}} calls work on vr and on every resolver later created from it
+// via NewChildVariableResolver, which propagates the same registry so they
+// all share one cursor per file. A resolver with no registry — the
+// default — rejects {{csv}}/{{jsonl}} with an error rather than silently
+// reading a file with no cursor to share across virtual users.
+func (vr *VariableResolver) EnableDataFeeders() {
+	if vr.feeders == nil {
+		vr.feeders = newDataFeederRegistry()
+	}
+}
+
+// BeginIteration starts a new request iteration on vr: it bumps the
+// counter {{iteration}} reports and clears the per-iteration row cache that
+// keeps {{csv}}/{{jsonl}} calls for the same file consistent with each
+// other within one request. Engine.resolveVariables calls this once per
+// request, before resolving that request's variables.
+func (vr *VariableResolver) BeginIteration() int64 {
+	n := atomic.AddInt64(&vr.iteration, 1)
+	vr.feederRowCache = make(map[string]map[string]string)
+	return n
+}
+
+// feederRow returns the row at path for the current iteration, loading the
+// file with load on first use and caching the result so every
+// {{csv}}/{{jsonl}} call naming the same path within one iteration returns
+// the same row.
+func (vr *VariableResolver) feederRow(path string, mode FeederMode, load func(string, FeederMode) (DataFeeder, error)) (map[string]string, error) {
+	if vr.feeders == nil {
+		return nil, fmt.Errorf("no data feeders configured for this simulation")
+	}
+	if row, ok := vr.feederRowCache[path]; ok {
+		return row, nil
+	}
+
+	feeder, err := vr.feeders.get(path, mode, load)
+	if err != nil {
+		return nil, err
+	}
+	row, err := feeder.Next()
+	if err != nil {
+		return nil, err
+	}
+	if vr.feederRowCache == nil {
+		vr.feederRowCache = make(map[string]map[string]string)
+	}
+	vr.feederRowCache[path] = row
+	return row, nil
+}
+
+// localeDataset resolves the providers.Dataset a call should use: kwargs's
+// "locale" override if present, otherwise vr.Locale.
+func (vr *VariableResolver) localeDataset(kwargs map[string]Value) (*providers.Dataset, error) {
+	locale := vr.Locale
+	if override, ok := kwargs["locale"]; ok {
+		locale = override.AsString()
+	}
+	return providers.Get(locale)
+}
+
 // registerDefaultFunctions registers built-in variable functions
 func (vr *VariableResolver) registerDefaultFunctions() {
 	// Random generators
-	vr.functions["random_int"] = func() string {
-		return strconv.Itoa(rand.Intn(1000000))
-	}
-
-	vr.functions["random_string"] = func() string {
-		const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-		b := make([]byte, 10)
+	vr.functions["random_int"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		min, max := 0, 999999
+		switch len(args) {
+		case 0:
+			// defaults above
+		case 1:
+			m, err := args[0].AsInt()
+			if err != nil {
+				return "", fmt.Errorf("random_int: %w", err)
+			}
+			max = int(m)
+		default:
+			lo, err := args[0].AsInt()
+			if err != nil {
+				return "", fmt.Errorf("random_int: %w", err)
+			}
+			hi, err := args[1].AsInt()
+			if err != nil {
+				return "", fmt.Errorf("random_int: %w", err)
+			}
+			min, max = int(lo), int(hi)
+		}
+		if max < min {
+			return "", fmt.Errorf("random_int: max %d is less than min %d", max, min)
+		}
+		return strconv.Itoa(min + vr.intn(max-min+1)), nil
+	}
+
+	vr.functions["random_string"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		length := 10
+		charsetName := "alnum"
+		if len(args) >= 1 {
+			n, err := args[0].AsInt()
+			if err != nil {
+				return "", fmt.Errorf("random_string: %w", err)
+			}
+			length = int(n)
+		}
+		if len(args) >= 2 {
+			charsetName = args[1].AsString()
+		}
+		charset, ok := charsets[charsetName]
+		if !ok {
+			return "", fmt.Errorf("random_string: unknown charset %q", charsetName)
+		}
+		b := make([]byte, length)
 		for i := range b {
-			b[i] = charset[rand.Intn(len(charset))]
+			b[i] = charset[vr.intn(len(charset))]
 		}
-		return string(b)
+		return string(b), nil
 	}
 
-	vr.functions["random_email"] = func() string {
+	vr.functions["random_email"] = func(args []Value, kwargs map[string]Value) (string, error) {
 		domains := []string{"gmail.com", "yahoo.com", "hotmail.com", "example.com", "test.org"}
-		username := vr.functions["random_string"]()
-		domain := domains[rand.Intn(len(domains))]
-		return fmt.Sprintf("%s@%s", strings.ToLower(username), domain)
+		username, err := vr.functions["random_string"](nil, nil)
+		if err != nil {
+			return "", err
+		}
+		domain := domains[vr.intn(len(domains))]
+		return fmt.Sprintf("%s@%s", strings.ToLower(username), domain), nil
 	}
 
-	vr.functions["random_phone"] = func() string {
-		return fmt.Sprintf("+1%03d%03d%04d", 
-			rand.Intn(900)+100, 
-			rand.Intn(900)+100, 
-			rand.Intn(10000))
+	vr.functions["random_phone"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("random_phone: %w", err)
+		}
+		return ds.Phone(vr.intn), nil
 	}
 
-	vr.functions["random_bool"] = func() string {
-		if rand.Float32() > 0.5 {
-			return "true"
+	vr.functions["random_bool"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		if vr.float32() > 0.5 {
+			return "true", nil
 		}
-		return "false"
+		return "false", nil
 	}
 
-	vr.functions["random_float"] = func() string {
-		return fmt.Sprintf("%.2f", rand.Float64()*100)
+	vr.functions["random_float"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return fmt.Sprintf("%.2f", vr.float64()*100), nil
+	}
+
+	// Selection
+	vr.functions["pick"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		if len(args) == 0 {
+			return "", fmt.Errorf("pick: requires at least one value")
+		}
+		return args[vr.intn(len(args))].AsString(), nil
 	}
 
 	// Time-based functions
-	vr.functions["timestamp"] = func() string {
-		return strconv.FormatInt(time.Now().Unix(), 10)
+	vr.functions["timestamp"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return strconv.FormatInt(time.Now().Unix(), 10), nil
 	}
 
-	vr.functions["timestamp_ms"] = func() string {
-		return strconv.FormatInt(time.Now().UnixMilli(), 10)
+	vr.functions["timestamp_ms"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return strconv.FormatInt(time.Now().UnixMilli(), 10), nil
 	}
 
-	vr.functions["iso_timestamp"] = func() string {
-		return time.Now().Format(time.RFC3339)
+	vr.functions["iso_timestamp"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return time.Now().Format(time.RFC3339), nil
 	}
 
-	vr.functions["date"] = func() string {
-		return time.Now().Format("2006-01-02")
+	vr.functions["date"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		format := "2006-01-02"
+		if len(args) >= 1 {
+			format = args[0].AsString()
+		}
+		t := time.Now()
+		if offset, ok := kwargs["offset"]; ok {
+			d, err := parseOffset(offset.AsString())
+			if err != nil {
+				return "", fmt.Errorf("date: %w", err)
+			}
+			t = t.Add(d)
+		}
+		return t.Format(format), nil
 	}
 
-	vr.functions["time"] = func() string {
-		return time.Now().Format("15:04:05")
+	vr.functions["time"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return time.Now().Format("15:04:05"), nil
 	}
 
-	vr.functions["datetime"] = func() string {
-		return time.Now().Format("2006-01-02 15:04:05")
+	vr.functions["datetime"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return time.Now().Format("2006-01-02 15:04:05"), nil
 	}
 
 	// UUID functions
-	vr.functions["uuid"] = func() string {
-		return uuid.New().String()
+	vr.functions["uuid"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return uuid.New().String(), nil
 	}
 
-	vr.functions["uuid_short"] = func() string {
-		return strings.ReplaceAll(uuid.New().String(), "-", "")
+	vr.functions["uuid_short"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return strings.ReplaceAll(uuid.New().String(), "-", ""), nil
 	}
 
-	// Name generators
-	vr.functions["first_name"] = func() string {
-		names := []string{"John", "Jane", "Michael", "Sarah", "David", "Lisa", "Robert", "Emily", 
-						  "James", "Anna", "Christopher", "Jessica", "Matthew", "Ashley", "Daniel", "Amanda"}
-		return names[rand.Intn(len(names))]
+	// Name generators draw from the locale dataset selected by vr.Locale, or
+	// by a per-call locale= override, e.g. {{last_name locale="ja_JP"}}.
+	vr.functions["first_name"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("first_name: %w", err)
+		}
+		return ds.FirstName(vr.intn), nil
 	}
 
-	vr.functions["last_name"] = func() string {
-		names := []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
-						  "Rodriguez", "Martinez", "Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas"}
-		return names[rand.Intn(len(names))]
+	vr.functions["last_name"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("last_name: %w", err)
+		}
+		return ds.LastName(vr.intn), nil
 	}
 
-	vr.functions["full_name"] = func() string {
-		return fmt.Sprintf("%s %s", vr.functions["first_name"](), vr.functions["last_name"]())
+	vr.functions["full_name"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		first, err := vr.functions["first_name"](nil, kwargs)
+		if err != nil {
+			return "", err
+		}
+		last, err := vr.functions["last_name"](nil, kwargs)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s", first, last), nil
 	}
 
-	vr.functions["username"] = func() string {
-		firstName := strings.ToLower(vr.functions["first_name"]())
-		lastName := strings.ToLower(vr.functions["last_name"]())
-		number := rand.Intn(9999)
-		return fmt.Sprintf("%s.%s%d", firstName, lastName, number)
+	vr.functions["username"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		first, err := vr.functions["first_name"](nil, kwargs)
+		if err != nil {
+			return "", err
+		}
+		last, err := vr.functions["last_name"](nil, kwargs)
+		if err != nil {
+			return "", err
+		}
+		number := vr.intn(9999)
+		return fmt.Sprintf("%s.%s%d", strings.ToLower(first), strings.ToLower(last), number), nil
 	}
 
-	// Company/Business generators
-	vr.functions["company"] = func() string {
+	// Company/Business generators. company/domain are locale-agnostic brand
+	// names; company_suffix supplies the locale-appropriate legal-entity
+	// suffix (e.g. "GmbH" for de_DE) to append to one.
+	vr.functions["company"] = func(args []Value, kwargs map[string]Value) (string, error) {
 		companies := []string{"TechCorp", "DataSystems", "CloudWorks", "InnovateLab", "DevHub",
-							  "CodeCraft", "SystemPro", "NetSolutions", "WebForge", "AppFactory"}
-		return companies[rand.Intn(len(companies))]
+			"CodeCraft", "SystemPro", "NetSolutions", "WebForge", "AppFactory"}
+		return companies[vr.intn(len(companies))], nil
+	}
+
+	vr.functions["company_suffix"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("company_suffix: %w", err)
+		}
+		return ds.CompanySuffix(vr.intn), nil
 	}
 
-	vr.functions["domain"] = func() string {
-		company := strings.ToLower(vr.functions["company"]())
+	vr.functions["domain"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		company, err := vr.functions["company"](nil, nil)
+		if err != nil {
+			return "", err
+		}
 		extensions := []string{".com", ".org", ".net", ".io", ".co"}
-		return company + extensions[rand.Intn(len(extensions))]
+		return strings.ToLower(company) + extensions[vr.intn(len(extensions))], nil
 	}
 
 	// Geographic data
-	vr.functions["country"] = func() string {
+	vr.functions["country"] = func(args []Value, kwargs map[string]Value) (string, error) {
 		countries := []string{"USA", "Canada", "UK", "Germany", "France", "Japan", "Australia", "Brazil"}
-		return countries[rand.Intn(len(countries))]
+		return countries[vr.intn(len(countries))], nil
+	}
+
+	vr.functions["city"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("city: %w", err)
+		}
+		return ds.RandomCity(vr.intn).Name, nil
+	}
+
+	vr.functions["zipcode"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("zipcode: %w", err)
+		}
+		return ds.RandomCity(vr.intn).PostalCode, nil
 	}
 
-	vr.functions["city"] = func() string {
-		cities := []string{"New York", "Los Angeles", "Chicago", "Houston", "Phoenix", "Philadelphia",
-						   "San Antonio", "San Diego", "Dallas", "San Jose", "Austin", "Jacksonville"}
-		return cities[rand.Intn(len(cities))]
+	// Address generators, namespaced under "address." so a single call can
+	// override the locale for just that field, e.g.
+	// {{address.city locale="de_DE"}}.
+	vr.functions["address.street"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("address.street: %w", err)
+		}
+		return ds.StreetAddress(vr.intn), nil
 	}
 
-	vr.functions["zipcode"] = func() string {
-		return fmt.Sprintf("%05d", rand.Intn(99999))
+	vr.functions["address.city"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("address.city: %w", err)
+		}
+		return ds.RandomCity(vr.intn).Name, nil
+	}
+
+	vr.functions["address.region"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("address.region: %w", err)
+		}
+		return ds.RandomCity(vr.intn).Region, nil
+	}
+
+	vr.functions["address.postal_code"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("address.postal_code: %w", err)
+		}
+		return ds.RandomCity(vr.intn).PostalCode, nil
+	}
+
+	vr.functions["address.full"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("address.full: %w", err)
+		}
+		return ds.FullAddress(vr.intn), nil
+	}
+
+	// currency_amount formats a random amount in the locale's own currency,
+	// e.g. "¥1234.00" for ja_JP.
+	vr.functions["currency_amount"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		ds, err := vr.localeDataset(kwargs)
+		if err != nil {
+			return "", fmt.Errorf("currency_amount: %w", err)
+		}
+		return ds.Amount(vr.intn), nil
 	}
 
 	// Status and categories
-	vr.functions["status"] = func() string {
+	vr.functions["status"] = func(args []Value, kwargs map[string]Value) (string, error) {
 		statuses := []string{"active", "inactive", "pending", "completed", "failed", "processing"}
-		return statuses[rand.Intn(len(statuses))]
+		return statuses[vr.intn(len(statuses))], nil
 	}
 
-	vr.functions["priority"] = func() string {
+	vr.functions["priority"] = func(args []Value, kwargs map[string]Value) (string, error) {
 		priorities := []string{"low", "medium", "high", "critical"}
-		return priorities[rand.Intn(len(priorities))]
+		return priorities[vr.intn(len(priorities))], nil
 	}
 
-	vr.functions["category"] = func() string {
+	vr.functions["category"] = func(args []Value, kwargs map[string]Value) (string, error) {
 		categories := []string{"technology", "business", "finance", "healthcare", "education", "retail"}
-		return categories[rand.Intn(len(categories))]
+		return categories[vr.intn(len(categories))], nil
+	}
+
+	// Per-iteration context
+	vr.functions["vu_id"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return strconv.Itoa(vr.VUID), nil
+	}
+
+	vr.functions["iteration"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		return strconv.FormatInt(atomic.LoadInt64(&vr.iteration), 10), nil
+	}
+
+	// Data feeders: {{csv "users.csv" "email"}} / {{jsonl "users.jsonl" "email"}},
+	// optionally with mode="round_robin" (default), "sequential", or
+	// "random". See DataFeeder.
+	vr.functions["csv"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		path, column, mode, err := feederCallArgs("csv", args, kwargs)
+		if err != nil {
+			return "", err
+		}
+		row, err := vr.feederRow(path, mode, func(p string, m FeederMode) (DataFeeder, error) {
+			return NewCSVDataFeeder(p, m)
+		})
+		if err != nil {
+			return "", fmt.Errorf("csv: %w", err)
+		}
+		value, ok := row[column]
+		if !ok {
+			return "", fmt.Errorf("csv: column %q not found in %q", column, path)
+		}
+		return value, nil
+	}
+
+	vr.functions["jsonl"] = func(args []Value, kwargs map[string]Value) (string, error) {
+		path, column, mode, err := feederCallArgs("jsonl", args, kwargs)
+		if err != nil {
+			return "", err
+		}
+		row, err := vr.feederRow(path, mode, func(p string, m FeederMode) (DataFeeder, error) {
+			return NewJSONLinesDataFeeder(p, m)
+		})
+		if err != nil {
+			return "", fmt.Errorf("jsonl: %w", err)
+		}
+		value, ok := row[column]
+		if !ok {
+			return "", fmt.Errorf("jsonl: column %q not found in %q", column, path)
+		}
+		return value, nil
+	}
+}
+
+// feederCallArgs parses the common "path column mode=..." argument shape
+// shared by the csv and jsonl template functions.
+func feederCallArgs(fn string, args []Value, kwargs map[string]Value) (path, column string, mode FeederMode, err error) {
+	if len(args) < 2 {
+		return "", "", "", fmt.Errorf("%s: requires a file path and a column name", fn)
+	}
+	mode = FeederModeRoundRobin
+	if m, ok := kwargs["mode"]; ok {
+		mode = FeederMode(m.AsString())
+	}
+	return args[0].AsString(), args[1].AsString(), mode, nil
+}
+
+// parseOffset parses a duration offset such as "-7d", "+3h", or "30m". The
+// "d" (day) unit isn't understood by time.ParseDuration, so it's handled
+// separately; everything else is delegated to the standard parser.
+func parseOffset(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid day offset %q", s)
+		}
+		return time.Duration(days * 24 * float64(time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// registerDefaultFilters registers built-in post-processing filters applied
+// with the {{expr | filter}} pipeline syntax.
+func (vr *VariableResolver) registerDefaultFilters() {
+	vr.filters["upper"] = func(value string) (string, error) { return strings.ToUpper(value), nil }
+	vr.filters["lower"] = func(value string) (string, error) { return strings.ToLower(value), nil }
+	vr.filters["trim"] = func(value string) (string, error) { return strings.TrimSpace(value), nil }
+
+	vr.filters["md5"] = func(value string) (string, error) {
+		sum := md5.Sum([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	vr.filters["sha256"] = func(value string) (string, error) {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	vr.filters["base64"] = func(value string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	}
+
+	vr.filters["jsonescape"] = func(value string) (string, error) {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return strings.Trim(string(encoded), `"`), nil
+	}
+
+	vr.filters["urlencode"] = func(value string) (string, error) {
+		return url.QueryEscape(value), nil
 	}
 }
 
@@ -179,65 +637,164 @@ func (vr *VariableResolver) SetVariable(key, value string) {
 	vr.variables[key] = value
 }
 
-// SetFunction sets a custom function for dynamic value generation
-func (vr *VariableResolver) SetFunction(key string, fn func() string) {
+// SetFunction registers a custom function for dynamic value generation. fn
+// receives the positional and keyword arguments parsed from the template
+// expression, e.g. {{myFunc 1 2 key=val}}; functions that ignore them remain
+// valid as plain zero-arg calls like {{myFunc}}.
+func (vr *VariableResolver) SetFunction(key string, fn VariableFunc) {
 	vr.functions[key] = fn
 }
 
-// Resolve replaces all variables in the input content
-func (vr *VariableResolver) Resolve(content string) string {
-	// Pattern to match {{variable}} syntax
-	re := regexp.MustCompile(`\{\{([^}]+)\}\}`)
-	
-	return re.ReplaceAllStringFunc(content, func(match string) string {
-		varName := strings.Trim(match, "{}")
-		varName = strings.TrimSpace(varName)
+// SetFilter registers a custom filter for the {{expr | filter}} pipeline.
+func (vr *VariableResolver) SetFilter(key string, fn FilterFunc) {
+	vr.filters[key] = fn
+}
 
-		// Check static variables first
-		if value, exists := vr.variables[varName]; exists {
-			return value
+// Resolve replaces all {{expr}} placeholders in content, where expr is
+// "name arg1 arg2 key=value | filter1 | filter2". A placeholder that fails
+// to parse or evaluate is left untouched in the output, and the first such
+// error encountered is returned so callers can decide whether to proceed.
+func (vr *VariableResolver) Resolve(content string) (string, error) {
+	var firstErr error
+	result := templatePattern.ReplaceAllStringFunc(content, func(match string) string {
+		raw := strings.TrimSpace(match[2 : len(match)-2])
+		resolved, err := vr.resolveExpression(raw)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("resolve %q: %w", raw, err)
+			}
+			return match
 		}
+		return resolved
+	})
+	return result, firstErr
+}
+
+// resolveExpression parses and evaluates a single placeholder body, applying
+// any pipeline filters to the result.
+func (vr *VariableResolver) resolveExpression(raw string) (string, error) {
+	expr, err := parseTemplateExpr(raw)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := vr.evalCall(expr)
+	if err != nil {
+		return "", err
+	}
 
-		// Check dynamic functions
-		if fn, exists := vr.functions[varName]; exists {
-			return fn()
+	for _, filterName := range expr.Filters {
+		filterFn, ok := vr.lookupFilter(filterName)
+		if !ok {
+			return "", fmt.Errorf("unknown filter %q", filterName)
+		}
+		value, err = filterFn(value)
+		if err != nil {
+			return "", fmt.Errorf("filter %q: %w", filterName, err)
 		}
+	}
+	return value, nil
+}
 
-		// Return original if not found
-		return match
-	})
+// evalCall resolves the call portion of a template expression: a bare name
+// with no arguments may be a static variable; anything else must be a
+// registered function.
+func (vr *VariableResolver) evalCall(expr *templateExpr) (string, error) {
+	if len(expr.Args) == 0 && len(expr.Kwargs) == 0 {
+		if value, exists := vr.lookupVariable(expr.Name); exists {
+			return value, nil
+		}
+	}
+	fn, exists := vr.lookupFunction(expr.Name)
+	if !exists {
+		return "", fmt.Errorf("unknown variable or function %q", expr.Name)
+	}
+	return fn(expr.Args, expr.Kwargs)
 }
 
-// ResolveHeaders resolves variables in HTTP headers
-func (vr *VariableResolver) ResolveHeaders(headers map[string]string) map[string]string {
+// lookupVariable walks vr and its ancestors for a static variable, so a
+// simulation- or global-scoped capture is visible from a virtual user's
+// child resolver.
+func (vr *VariableResolver) lookupVariable(name string) (string, bool) {
+	for r := vr; r != nil; r = r.parent {
+		if value, exists := r.variables[name]; exists {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// lookupFunction walks vr and its ancestors for a registered function.
+func (vr *VariableResolver) lookupFunction(name string) (VariableFunc, bool) {
+	for r := vr; r != nil; r = r.parent {
+		if fn, exists := r.functions[name]; exists {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+// lookupFilter walks vr and its ancestors for a registered filter.
+func (vr *VariableResolver) lookupFilter(name string) (FilterFunc, bool) {
+	for r := vr; r != nil; r = r.parent {
+		if fn, exists := r.filters[name]; exists {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+// ResolveHeaders resolves variables in HTTP headers, returning a best-effort
+// resolved map alongside the first resolution error encountered, if any.
+func (vr *VariableResolver) ResolveHeaders(headers map[string]string) (map[string]string, error) {
 	resolved := make(map[string]string)
+	var firstErr error
 	for key, value := range headers {
-		resolvedKey := vr.Resolve(key)
-		resolvedValue := vr.Resolve(value)
+		resolvedKey, err := vr.Resolve(key)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		resolvedValue, err := vr.Resolve(value)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
 		resolved[resolvedKey] = resolvedValue
 	}
-	return resolved
+	return resolved, firstErr
 }
 
 // ResolveBody resolves variables in request body
-func (vr *VariableResolver) ResolveBody(body *RequestBody) *RequestBody {
+func (vr *VariableResolver) ResolveBody(body *RequestBody) (*RequestBody, error) {
 	if body == nil {
-		return nil
+		return nil, nil
 	}
 
 	resolvedBody := &RequestBody{
-		Type: body.Type,
+		Type:      body.Type,
+		Generator: body.Generator,
 	}
 
+	var firstErr error
+
 	// Resolve content
-	resolvedBody.Content = vr.Resolve(body.Content)
+	content, err := vr.Resolve(body.Content)
+	if err != nil {
+		firstErr = err
+	}
+	resolvedBody.Content = content
 
 	// Resolve form data
 	if body.FormData != nil {
 		resolvedBody.FormData = make(map[string]string)
 		for key, value := range body.FormData {
-			resolvedKey := vr.Resolve(key)
-			resolvedValue := vr.Resolve(value)
+			resolvedKey, err := vr.Resolve(key)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			resolvedValue, err := vr.Resolve(value)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
 			resolvedBody.FormData[resolvedKey] = resolvedValue
 		}
 	}
@@ -245,22 +802,40 @@ func (vr *VariableResolver) ResolveBody(body *RequestBody) *RequestBody {
 	// Copy files (no variable resolution in binary data)
 	resolvedBody.Files = body.Files
 
-	return resolvedBody
+	return resolvedBody, firstErr
 }
 
 // GetAvailableVariables returns a list of all available variable names
 func (vr *VariableResolver) GetAvailableVariables() map[string]string {
 	variables := make(map[string]string)
-	
+
 	// Add static variables
 	for key, value := range vr.variables {
 		variables[key] = value
 	}
-	
+
 	// Add function names (with sample values)
 	for key, fn := range vr.functions {
-		variables[key] = fn() // Generate sample value
+		if sample, err := fn(nil, nil); err == nil {
+			variables[key] = sample
+		}
 	}
-	
+
 	return variables
 }
+
+// LocaleSamples returns a deterministic preview of every locale with an
+// embedded provider dataset, keyed by locale code, so the /variables
+// endpoint can power a locale picker in the UI without making a guess-and-
+// check request for each one.
+func (vr *VariableResolver) LocaleSamples() map[string]map[string]string {
+	samples := make(map[string]map[string]string)
+	for _, locale := range providers.Locales() {
+		ds, err := providers.Get(locale)
+		if err != nil {
+			continue
+		}
+		samples[locale] = ds.Sample()
+	}
+	return samples
+}
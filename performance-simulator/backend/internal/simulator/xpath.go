@@ -0,0 +1,108 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// XPathEvaluator evaluates a compiled XPath expression against a parsed XML
+// node, abstracting over github.com/antchfx/xmlquery + xpath the same way
+// JSONPathEvaluator (jsonpath.go) abstracts over the JSONPath library.
+type XPathEvaluator interface {
+	Evaluate(node *xmlquery.Node, expr string) (interface{}, error)
+
+	// EvaluateWithNamespaces is Evaluate for an expr that uses prefixed
+	// names (e.g. "//soap:Body"), resolving each prefix against namespaces
+	// so SOAP and other namespaced XML responses can be queried without the
+	// caller having to strip prefixes first.
+	EvaluateWithNamespaces(node *xmlquery.Node, expr string, namespaces map[string]string) (interface{}, error)
+}
+
+type antchfxXPathEvaluator struct{}
+
+// NewXPathEvaluator creates an XPathEvaluator backed by antchfx/xpath.
+func NewXPathEvaluator() XPathEvaluator {
+	return antchfxXPathEvaluator{}
+}
+
+var defaultXPathEvaluator = NewXPathEvaluator()
+
+// Evaluate compiles and runs exprStr against node. A node-set result
+// unwraps the same way JSONPathEvaluator.Evaluate unwraps a JSONPath
+// result: zero matches is nil, one match is its string value, more than
+// one is a []interface{} of string values (so contains/equality get
+// array-membership semantics for free). Boolean, numeric, and string
+// XPath results (e.g. from count(...) or a comparison) pass through as-is.
+func (antchfxXPathEvaluator) Evaluate(node *xmlquery.Node, exprStr string) (interface{}, error) {
+	return evaluateXPath(node, exprStr, nil)
+}
+
+// EvaluateWithNamespaces is Evaluate, additionally resolving namespaces'
+// prefixes in exprStr via xpath.CompileWithNS.
+func (antchfxXPathEvaluator) EvaluateWithNamespaces(node *xmlquery.Node, exprStr string, namespaces map[string]string) (interface{}, error) {
+	return evaluateXPath(node, exprStr, namespaces)
+}
+
+func evaluateXPath(node *xmlquery.Node, exprStr string, namespaces map[string]string) (interface{}, error) {
+	if node == nil {
+		return nil, fmt.Errorf("no XML node to evaluate against")
+	}
+
+	var expr *xpath.Expr
+	var err error
+	if len(namespaces) > 0 {
+		expr, err = xpath.CompileWithNS(exprStr, namespaces)
+	} else {
+		expr, err = xpath.Compile(exprStr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compile xpath %q: %w", exprStr, err)
+	}
+
+	switch result := expr.Evaluate(xmlquery.CreateXPathNavigator(node)).(type) {
+	case *xpath.NodeIterator:
+		var values []interface{}
+		for result.MoveNext() {
+			values = append(values, result.Current().Value())
+		}
+		switch len(values) {
+		case 0:
+			return nil, nil
+		case 1:
+			return values[0], nil
+		default:
+			return values, nil
+		}
+	default:
+		return result, nil
+	}
+}
+
+// parseXMLDocument parses body for xpath/xmlcontains/soap assertions.
+func parseXMLDocument(body string) (*xmlquery.Node, error) {
+	return xmlquery.Parse(strings.NewReader(body))
+}
+
+// soapBodyElement returns the first child element of a SOAP envelope's
+// <Body> — the actual response element, e.g. <GetUserResponse> — so
+// soap(...) assertions can XPath against it directly without the caller
+// having to strip the envelope and the Body wrapper themselves.
+func soapBodyElement(doc *xmlquery.Node) (*xmlquery.Node, error) {
+	envelope := xmlquery.FindOne(doc, "//*[local-name()='Envelope']")
+	if envelope == nil {
+		return nil, fmt.Errorf("no SOAP Envelope element found")
+	}
+
+	body := xmlquery.FindOne(envelope, "*[local-name()='Body']")
+	if body == nil {
+		return nil, fmt.Errorf("no SOAP Body element found")
+	}
+
+	if child := xmlquery.FindOne(body, "*[1]"); child != nil {
+		return child, nil
+	}
+	return body, nil
+}
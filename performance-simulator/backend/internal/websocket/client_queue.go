@@ -0,0 +1,199 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// OverflowPolicy controls what happens when a client's outbound queue fills
+// up faster than writePump can drain it.
+type OverflowPolicy string
+
+const (
+	// DropOldest evicts the oldest queued message to make room for the new one.
+	DropOldest OverflowPolicy = "drop_oldest"
+	// DropNewest discards the incoming message, keeping the queue as-is.
+	DropNewest OverflowPolicy = "drop_newest"
+	// Disconnect evicts the client entirely once it can't keep up.
+	Disconnect OverflowPolicy = "disconnect"
+	// Coalesce replaces any already-queued simulation_update for the same
+	// simulation with the latest one, rather than growing the queue.
+	Coalesce OverflowPolicy = "coalesce"
+)
+
+const defaultQueueCapacity = 256
+
+// ClientStats reports per-client outbound queue health, exposed through
+// Hub.Stats() and the Prometheus registry.
+type ClientStats struct {
+	ClientID         string        `json:"client_id"`
+	Queued           int           `json:"queued"`
+	Dropped          int64         `json:"dropped"`
+	BytesSent        int64         `json:"bytes_sent"`
+	LastWriteLatency time.Duration `json:"last_write_latency"`
+}
+
+// outboundQueue is a bounded per-client ring of pending messages. It exists
+// because the hub used to push straight onto a 256-slot channel and, on
+// overflow, close the client's send channel while holding h.mu.RLock in the
+// broadcast fan-out loop — racing the write side which could be reading from
+// that same channel in writePump. Every write to a client now goes through
+// Push, which owns the eviction policy and its own lock.
+type outboundQueue struct {
+	mu       sync.Mutex
+	items    []*Message
+	capacity int
+	policy   OverflowPolicy
+	closed   bool
+	notify   chan struct{}
+
+	dropped          int64
+	bytesSent        int64
+	lastWriteLatency time.Duration
+}
+
+func newOutboundQueue(capacity int, policy OverflowPolicy) *outboundQueue {
+	if capacity <= 0 {
+		capacity = defaultQueueCapacity
+	}
+	return &outboundQueue{
+		capacity: capacity,
+		policy:   policy,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Push enqueues a message, applying the overflow policy if the queue is
+// full. It returns false when the message was dropped, or when the policy
+// says the client should be disconnected (disconnected is reported via the
+// second return value).
+func (q *outboundQueue) Push(msg *Message) (queued bool, disconnect bool) {
+	q.mu.Lock()
+
+	if q.closed {
+		q.mu.Unlock()
+		return false, false
+	}
+
+	if msg.Type == "simulation_update" || msg.Type == "metrics_delta" {
+		if key, ok := coalesceKey(msg); ok {
+			for i, existing := range q.items {
+				if existingKey, ok := coalesceKey(existing); ok && existingKey == key {
+					q.items[i] = msg
+					q.mu.Unlock()
+					q.wake()
+					return true, false
+				}
+			}
+		}
+	}
+
+	if len(q.items) >= q.capacity {
+		switch q.policy {
+		case DropOldest:
+			q.items = append(q.items[1:], msg)
+			q.dropped++
+		case Disconnect:
+			q.mu.Unlock()
+			return false, true
+		case Coalesce:
+			// No matching key to coalesce with above; fall back to dropping
+			// the oldest so the newest state always wins.
+			q.items = append(q.items[1:], msg)
+			q.dropped++
+		case DropNewest:
+			fallthrough
+		default:
+			q.dropped++
+			q.mu.Unlock()
+			return false, false
+		}
+	} else {
+		q.items = append(q.items, msg)
+	}
+
+	q.mu.Unlock()
+	q.wake()
+	return true, false
+}
+
+// wake signals writePump that there's work, without blocking if it's
+// already been signalled.
+func (q *outboundQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Drain removes and returns every currently queued message.
+func (q *outboundQueue) Drain() []*Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return nil
+	}
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// Close marks the queue closed; further Push calls are no-ops.
+func (q *outboundQueue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.wake()
+}
+
+func (q *outboundQueue) isClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+// RecordWrite updates bytes_sent/last_write_latency after writePump
+// successfully flushes a message to the socket.
+func (q *outboundQueue) RecordWrite(bytes int, latency time.Duration) {
+	q.mu.Lock()
+	q.bytesSent += int64(bytes)
+	q.lastWriteLatency = latency
+	q.mu.Unlock()
+}
+
+// Stats snapshots the queue's current counters.
+func (q *outboundQueue) Stats(clientID string) ClientStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return ClientStats{
+		ClientID:         clientID,
+		Queued:           len(q.items),
+		Dropped:          q.dropped,
+		BytesSent:        q.bytesSent,
+		LastWriteLatency: q.lastWriteLatency,
+	}
+}
+
+// coalesceKey derives a dedup key for coalesce-eligible messages: same type
+// plus same simulation_id means "replace, don't queue both".
+func coalesceKey(msg *Message) (string, bool) {
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	simID, ok := data["simulation_id"]
+	if !ok {
+		return "", false
+	}
+	return msg.Type + ":" + toKeyString(simID), true
+}
+
+func toKeyString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	default:
+		return ""
+	}
+}
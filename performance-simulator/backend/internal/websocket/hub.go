@@ -19,14 +19,44 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+
+	// overflowPolicy and queueCapacity configure every client's outboundQueue
+	// at connect time; see client_queue.go for why a bounded ring replaced
+	// the old unbounded-looking 256-slot channel.
+	overflowPolicy OverflowPolicy
+	queueCapacity  int
+
+	// subIndex maps a metric path to the set of clients subscribed to it,
+	// so BroadcastSimulationUpdate can fan out without touching every client.
+	subIndex map[string]map[*Client]bool
+	subMu    sync.RWMutex
+
+	// snapshotFn, when set, lets the hub answer sync_response requests with
+	// the current value of a path before a client starts streaming updates.
+	snapshotFn func(path string) (interface{}, bool)
+
+	// maxFrameBytes caps the size of a single outbound WebSocket frame.
+	// writePump transparently splits any marshaled Message larger than this
+	// into ordered chunked_frame messages, so callers like reportMetrics or
+	// a "get_snapshot" reply carrying a simulation's full history never need
+	// to know or care about the limit.
+	maxFrameBytes int
 }
 
 // Client represents a WebSocket connection
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan *Message
-	id   string
+	hub   *Hub
+	conn  *websocket.Conn
+	queue *outboundQueue
+	id    string
+
+	subs  map[string]*subscription // keyed by metric path
+	subMu sync.RWMutex
+
+	// updates feeds the coalescing loop; pathUpdates arriving within the
+	// same coalesce window are merged into a single outbound frame so a
+	// slow client can't cause head-of-line blocking on the hub.
+	updates chan pathUpdate
 }
 
 // Message represents data sent over WebSocket
@@ -36,6 +66,60 @@ type Message struct {
 	Timestamp int64       `json:"timestamp"`
 }
 
+// SubscriptionMode controls when a subscribed path is pushed to a client.
+type SubscriptionMode string
+
+const (
+	SubscribeOnChange      SubscriptionMode = "ON_CHANGE"
+	SubscribeSample        SubscriptionMode = "SAMPLE"
+	SubscribeTargetDefined SubscriptionMode = "TARGET_DEFINED"
+)
+
+// defaultCoalesceWindow batches updates arriving within this window into a
+// single WebSocket frame per client.
+const defaultCoalesceWindow = 50 * time.Millisecond
+
+// defaultSampleInterval is used for SAMPLE mode subscriptions that don't
+// specify one, and as the fallback heartbeat for TARGET_DEFINED.
+const defaultSampleInterval = 500 * time.Millisecond
+
+// defaultMaxFrameBytes bounds a single outbound WebSocket frame. Chosen well
+// under typical proxy/browser frame limits so a single oversized snapshot or
+// simulation_completed payload can't stall or get rejected; operators can
+// raise it via WithMaxFrameBytes.
+const defaultMaxFrameBytes = 512 * 1024
+
+// Keepalive timings for writePump/readPump. pingPeriod must stay below
+// pongWait so a healthy client always has time to answer one more ping
+// before the deadline trips; writeWait bounds how long a single frame
+// write (including the ping itself) is allowed to block the pump.
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+)
+
+// subscription tracks one client's interest in a single metric path.
+type subscription struct {
+	path              string
+	mode              SubscriptionMode
+	sampleInterval    time.Duration
+	heartbeatInterval time.Duration
+	deltaThreshold    float64
+
+	lastValue   interface{}
+	lastNumeric float64
+	hasNumeric  bool
+	lastSent    time.Time
+}
+
+// pathUpdate is a single value change queued for coalescing before it is
+// flushed to the client as a metrics_delta frame.
+type pathUpdate struct {
+	path  string
+	value interface{}
+}
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -46,28 +130,66 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// HubOption configures optional Hub behavior at construction time.
+type HubOption func(*Hub)
+
+// WithOverflowPolicy sets the eviction policy applied to every client's
+// outbound queue once it fills. Defaults to DropOldest.
+func WithOverflowPolicy(policy OverflowPolicy) HubOption {
+	return func(h *Hub) { h.overflowPolicy = policy }
+}
+
+// WithQueueCapacity sets the per-client outbound queue size. Defaults to
+// defaultQueueCapacity.
+func WithQueueCapacity(capacity int) HubOption {
+	return func(h *Hub) { h.queueCapacity = capacity }
+}
+
+// WithMaxFrameBytes sets the maximum size of a single outbound WebSocket
+// frame before writePump splits it into ordered chunked_frame messages.
+// Defaults to defaultMaxFrameBytes.
+func WithMaxFrameBytes(maxBytes int) HubOption {
+	return func(h *Hub) { h.maxFrameBytes = maxBytes }
+}
+
 // NewHub creates a new WebSocket hub
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan *Message),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{
+		clients:        make(map[*Client]bool),
+		broadcast:      make(chan *Message),
+		register:       make(chan *Client),
+		unregister:     make(chan *Client),
+		subIndex:       make(map[string]map[*Client]bool),
+		overflowPolicy: DropOldest,
+		queueCapacity:  defaultQueueCapacity,
+		maxFrameBytes:  defaultMaxFrameBytes,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
+}
+
+// SetSnapshotProvider registers a function used to answer sync_response
+// requests with the current value of a path before a client starts
+// streaming updates for it. Without one, sync_response carries no data.
+func (h *Hub) SetSnapshotProvider(fn func(path string) (interface{}, bool)) {
+	h.snapshotFn = fn
 }
 
 // Run starts the hub's main loop
 func (h *Hub) Run() {
+	go h.runHeartbeats()
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
 			h.mu.Unlock()
-			
+
 			logrus.Infof("Client %s connected. Total clients: %d", client.id, len(h.clients))
-			
-			// Send welcome message
+
 			welcomeMsg := &Message{
 				Type: "connection_established",
 				Data: map[string]interface{}{
@@ -76,34 +198,288 @@ func (h *Hub) Run() {
 				},
 				Timestamp: getCurrentTimestamp(),
 			}
-			
-			select {
-			case client.send <- welcomeMsg:
-			default:
-				close(client.send)
-				delete(h.clients, client)
+
+			if _, disconnect := client.queue.Push(welcomeMsg); disconnect {
+				h.removeClient(client)
 			}
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				logrus.Infof("Client %s disconnected. Total clients: %d", client.id, len(h.clients))
-			}
-			h.mu.Unlock()
+			h.removeClient(client)
 
 		case message := <-h.broadcast:
 			h.mu.RLock()
+			toEvict := make([]*Client, 0)
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
+				if _, disconnect := client.queue.Push(message); disconnect {
+					toEvict = append(toEvict, client)
 				}
 			}
 			h.mu.RUnlock()
+
+			for _, client := range toEvict {
+				h.removeClient(client)
+			}
+		}
+	}
+}
+
+// removeClient is the single path that tears a client down: it owns the
+// clients-map mutation and the queue close, so callers never race each
+// other closing the same client twice.
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		client.queue.Close()
+		logrus.Infof("Client %s disconnected. Total clients: %d", client.id, len(h.clients))
+	}
+	h.mu.Unlock()
+	h.unsubscribeAll(client)
+}
+
+// send enqueues a message for a client, evicting it if the queue's overflow
+// policy says to disconnect. Used by every call site outside Run()'s own
+// broadcast loop, which batches eviction itself to avoid recursive locking.
+func (h *Hub) send(client *Client, msg *Message) {
+	if _, disconnect := client.queue.Push(msg); disconnect {
+		h.removeClient(client)
+	}
+}
+
+// runHeartbeats periodically re-emits the last known value of idle paths so
+// subscribers can tell the stream is still alive even when nothing changed.
+func (h *Hub) runHeartbeats() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		h.subMu.RLock()
+		for path, clients := range h.subIndex {
+			for client := range clients {
+				client.subMu.Lock()
+				sub := client.subs[path]
+				if sub == nil || sub.heartbeatInterval <= 0 || sub.lastSent.IsZero() {
+					client.subMu.Unlock()
+					continue
+				}
+				if now.Sub(sub.lastSent) >= sub.heartbeatInterval {
+					client.enqueueUpdate(path, sub.lastValue)
+					sub.lastSent = now
+				}
+				client.subMu.Unlock()
+			}
+		}
+		h.subMu.RUnlock()
+	}
+}
+
+// Subscribe registers a client's interest in a metric path under the given
+// mode, keyed by (clientID, path, mode) via the per-client subscription map.
+func (h *Hub) Subscribe(client *Client, path string, mode SubscriptionMode, sampleInterval, heartbeatInterval time.Duration, deltaThreshold float64) {
+	if sampleInterval <= 0 {
+		sampleInterval = defaultSampleInterval
+	}
+
+	sub := &subscription{
+		path:              path,
+		mode:              mode,
+		sampleInterval:    sampleInterval,
+		heartbeatInterval: heartbeatInterval,
+		deltaThreshold:    deltaThreshold,
+	}
+
+	client.subMu.Lock()
+	client.subs[path] = sub
+	client.subMu.Unlock()
+
+	h.subMu.Lock()
+	if h.subIndex[path] == nil {
+		h.subIndex[path] = make(map[*Client]bool)
+	}
+	h.subIndex[path][client] = true
+	h.subMu.Unlock()
+
+	if h.snapshotFn != nil {
+		if value, ok := h.snapshotFn(path); ok {
+			h.send(client, &Message{
+				Type: "sync_response",
+				Data: map[string]interface{}{
+					"path":  path,
+					"value": value,
+				},
+				Timestamp: getCurrentTimestamp(),
+			})
+		}
+	}
+}
+
+// Unsubscribe removes a single path subscription for a client.
+func (h *Hub) Unsubscribe(client *Client, path string) {
+	client.subMu.Lock()
+	delete(client.subs, path)
+	client.subMu.Unlock()
+
+	h.subMu.Lock()
+	if clients, ok := h.subIndex[path]; ok {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(h.subIndex, path)
+		}
+	}
+	h.subMu.Unlock()
+}
+
+// unsubscribeAll tears down every subscription held by a disconnecting client.
+func (h *Hub) unsubscribeAll(client *Client) {
+	client.subMu.Lock()
+	paths := make([]string, 0, len(client.subs))
+	for path := range client.subs {
+		paths = append(paths, path)
+	}
+	client.subMu.Unlock()
+
+	h.subMu.Lock()
+	for _, path := range paths {
+		if clients, ok := h.subIndex[path]; ok {
+			delete(clients, client)
+			if len(clients) == 0 {
+				delete(h.subIndex, path)
+			}
+		}
+	}
+	h.subMu.Unlock()
+}
+
+// Publish fans a value for a metric path out to every subscribed client,
+// respecting each subscription's mode instead of blasting every client.
+func (h *Hub) Publish(path string, value interface{}) {
+	h.subMu.RLock()
+	clients := h.subIndex[path]
+	// Copy the subscriber set so we don't hold subMu while enqueueing.
+	subs := make(map[*Client]*subscription, len(clients))
+	for client := range clients {
+		client.subMu.RLock()
+		if sub, ok := client.subs[path]; ok {
+			subs[client] = sub
+		}
+		client.subMu.RUnlock()
+	}
+	h.subMu.RUnlock()
+
+	now := time.Now()
+	for client, sub := range subs {
+		client.subMu.Lock()
+		emit := sub.shouldEmit(value, now)
+		if emit {
+			sub.lastValue = value
+			sub.lastSent = now
+		}
+		client.subMu.Unlock()
+		if !emit {
+			continue
+		}
+		client.enqueueUpdate(path, value)
+	}
+}
+
+// shouldEmit decides whether a value crosses the threshold for its mode.
+func (s *subscription) shouldEmit(value interface{}, now time.Time) bool {
+	switch s.mode {
+	case SubscribeSample:
+		return now.Sub(s.lastSent) >= s.sampleInterval
+
+	case SubscribeOnChange:
+		return s.hasChanged(value)
+
+	case SubscribeTargetDefined:
+		fallthrough
+	default:
+		// Server picks the best mode per path: numeric series sample at the
+		// default interval, everything else pushes only on change.
+		if _, ok := toNumeric(value); ok {
+			return now.Sub(s.lastSent) >= defaultSampleInterval
+		}
+		return s.hasChanged(value)
+	}
+}
+
+// hasChanged reports whether value differs from the last seen value,
+// using the configured delta threshold for numeric series.
+func (s *subscription) hasChanged(value interface{}) bool {
+	if num, ok := toNumeric(value); ok {
+		if !s.hasNumeric {
+			s.lastNumeric = num
+			s.hasNumeric = true
+			return true
+		}
+		delta := num - s.lastNumeric
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta >= s.deltaThreshold {
+			s.lastNumeric = num
+			return true
+		}
+		return false
+	}
+	return s.lastValue == nil || fmt.Sprintf("%v", value) != fmt.Sprintf("%v", s.lastValue)
+}
+
+// toNumeric converts common numeric JSON/Go types to float64.
+func toNumeric(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// enqueueUpdate hands a path update to the client's coalescing loop.
+func (c *Client) enqueueUpdate(path string, value interface{}) {
+	select {
+	case c.updates <- pathUpdate{path: path, value: value}:
+	default:
+		logrus.Warnf("Update queue full for client %s, dropping update for %s", c.id, path)
+	}
+}
+
+// coalesceLoop batches updates arriving within defaultCoalesceWindow into a
+// single metrics_delta frame, so a burst of fast-changing paths doesn't
+// create head-of-line blocking for a slow client.
+func (c *Client) coalesceLoop() {
+	ticker := time.NewTicker(defaultCoalesceWindow)
+	defer ticker.Stop()
+
+	pending := make(map[string]interface{})
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		c.hub.send(c, &Message{
+			Type:      "metrics_delta",
+			Data:      pending,
+			Timestamp: getCurrentTimestamp(),
+		})
+		pending = make(map[string]interface{})
+	}
+
+	for {
+		select {
+		case update, ok := <-c.updates:
+			if !ok {
+				return
+			}
+			pending[update.path] = update.value
+		case <-ticker.C:
+			flush()
 		}
 	}
 }
@@ -122,17 +498,20 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 	}
 
 	client := &Client{
-		hub:  h,
-		conn: conn,
-		send: make(chan *Message, 256),
-		id:   clientID,
+		hub:     h,
+		conn:    conn,
+		queue:   newOutboundQueue(h.queueCapacity, h.overflowPolicy),
+		id:      clientID,
+		subs:    make(map[string]*subscription),
+		updates: make(chan pathUpdate, 256),
 	}
 
 	client.hub.register <- client
 
-	// Start goroutines for reading and writing
+	// Start goroutines for reading, writing, and coalescing subscription updates
 	go client.writePump()
 	go client.readPump()
+	go client.coalesceLoop()
 }
 
 // Broadcast sends a message to all connected clients
@@ -159,18 +538,18 @@ func (h *Hub) BroadcastToClient(clientID string, messageType string, data interf
 	}
 
 	h.mu.RLock()
-	defer h.mu.RUnlock()
-
+	var target *Client
 	for client := range h.clients {
 		if client.id == clientID {
-			select {
-			case client.send <- message:
-			default:
-				logrus.Warnf("Failed to send message to client %s", clientID)
-			}
+			target = client
 			break
 		}
 	}
+	h.mu.RUnlock()
+
+	if target != nil {
+		h.send(target, message)
+	}
 }
 
 // GetConnectedClients returns the number of connected clients
@@ -180,6 +559,19 @@ func (h *Hub) GetConnectedClients() int {
 	return len(h.clients)
 }
 
+// Stats reports outbound-queue health for every connected client, so
+// operators can see backpressure and drops before a client fully stalls.
+func (h *Hub) Stats() []ClientStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make([]ClientStats, 0, len(h.clients))
+	for client := range h.clients {
+		stats = append(stats, client.queue.Stats(client.id))
+	}
+	return stats
+}
+
 // readPump handles incoming messages from client
 func (c *Client) readPump() {
 	defer func() {
@@ -187,9 +579,13 @@ func (c *Client) readPump() {
 		c.conn.Close()
 	}()
 
-	// Set read limits and deadlines
 	c.conn.SetReadLimit(512)
-	
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
@@ -211,73 +607,178 @@ func (c *Client) readPump() {
 	}
 }
 
-// writePump handles outgoing messages to client
+// writePump handles outgoing messages to client. It drains the client's
+// outboundQueue whenever Push signals new work, and sends a ping on
+// pingPeriod so a peer that stops reading (but never errors on write, e.g.
+// a half-open TCP connection) gets evicted once pongWait elapses without a
+// matching pong resetting the read deadline in readPump.
 func (c *Client) writePump() {
-	defer c.conn.Close()
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
 
 	for {
 		select {
-		case message, ok := <-c.send:
-			if !ok {
+		case <-c.queue.notify:
+			messages := c.queue.Drain()
+			for _, message := range messages {
+				start := time.Now()
+				data, err := json.Marshal(message)
+				if err != nil {
+					logrus.Errorf("Failed to marshal message for client %s: %v", c.id, err)
+					continue
+				}
+
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if len(data) > c.hub.maxFrameBytes && c.hub.maxFrameBytes > 0 {
+					if err := c.writeChunked(message, data); err != nil {
+						logrus.Errorf("Failed to write chunked message to client %s: %v", c.id, err)
+						return
+					}
+				} else if err := c.conn.WriteJSON(message); err != nil {
+					logrus.Errorf("Failed to write message to client %s: %v", c.id, err)
+					return
+				}
+				c.queue.RecordWrite(len(data), time.Since(start))
+			}
+			if c.queue.isClosed() {
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			if err := c.conn.WriteJSON(message); err != nil {
-				logrus.Errorf("Failed to write message to client %s: %v", c.id, err)
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				logrus.Errorf("Failed to ping client %s: %v", c.id, err)
 				return
 			}
 		}
 	}
 }
 
+// writeChunked splits the already-marshaled JSON for message across multiple
+// chunked_frame frames, each under c.hub.maxFrameBytes, so a client that
+// reassembles them in seq order (concatenating the chunk strings, then
+// JSON-unmarshaling the result) recovers the original Message. sim_id is
+// included on every chunk purely so a frontend juggling several simulations
+// can route an in-progress reassembly to the right one.
+func (c *Client) writeChunked(message *Message, data []byte) error {
+	chunkSize := c.hub.maxFrameBytes
+	total := (len(data) + chunkSize - 1) / chunkSize
+	simID := simulationIDFromMessage(message)
+
+	for seq := 0; seq < total; seq++ {
+		start := seq * chunkSize
+		end := start + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		frame := &Message{
+			Type: "chunked_frame",
+			Data: map[string]interface{}{
+				"sim_id": simID,
+				"seq":    seq,
+				"total":  total,
+				"chunk":  string(data[start:end]),
+			},
+			Timestamp: getCurrentTimestamp(),
+		}
+
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteJSON(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulationIDFromMessage best-effort extracts a simulation_id from a
+// message's Data payload, falling back to 0 when the message isn't
+// simulation-scoped (e.g. a global broadcast).
+func simulationIDFromMessage(message *Message) interface{} {
+	data, ok := message.Data.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	if simID, ok := data["simulation_id"]; ok {
+		return simID
+	}
+	return 0
+}
+
 // handleClientMessage processes messages received from clients
 func (c *Client) handleClientMessage(msg *Message) {
 	switch msg.Type {
 	case "ping":
 		// Respond to ping with pong
-		pongMsg := &Message{
+		c.hub.send(c, &Message{
 			Type:      "pong",
 			Data:      map[string]interface{}{"message": "pong"},
 			Timestamp: getCurrentTimestamp(),
-		}
-		
-		select {
-		case c.send <- pongMsg:
-		default:
-			logrus.Warnf("Failed to send pong to client %s", c.id)
-		}
+		})
 
 	case "subscribe_simulation":
-		// Handle simulation subscription
+		// Register a streaming subscription for one or more metric paths,
+		// e.g. "simulations/{id}/response_times/p95" or "throughput_data".
 		if data, ok := msg.Data.(map[string]interface{}); ok {
-			simulationID := data["simulation_id"]
-			logrus.Infof("Client %s subscribed to simulation %v", c.id, simulationID)
+			paths := pathsFromData(data)
+			mode := SubscriptionMode(stringOr(data["mode"], string(SubscribeTargetDefined)))
+			sampleInterval := durationFromMillis(data["sample_interval_ms"], defaultSampleInterval)
+			heartbeatInterval := durationFromMillis(data["heartbeat_interval_ms"], 10*time.Second)
+			deltaThreshold := floatOr(data["delta_threshold"], 0)
+
+			for _, path := range paths {
+				c.hub.Subscribe(c, path, mode, sampleInterval, heartbeatInterval, deltaThreshold)
+			}
+			logrus.Infof("Client %s subscribed to %v (mode=%s)", c.id, paths, mode)
 		}
 
 	case "unsubscribe_simulation":
-		// Handle simulation unsubscription
+		// Remove previously registered subscriptions.
 		if data, ok := msg.Data.(map[string]interface{}); ok {
-			simulationID := data["simulation_id"]
-			logrus.Infof("Client %s unsubscribed from simulation %v", c.id, simulationID)
+			paths := pathsFromData(data)
+			for _, path := range paths {
+				c.hub.Unsubscribe(c, path)
+			}
+			logrus.Infof("Client %s unsubscribed from %v", c.id, paths)
+		}
+
+	case "get_snapshot":
+		// One-shot request for the full current value of a path, as opposed
+		// to subscribe_simulation's streaming updates. Answered through the
+		// hub's snapshotFn (see SetSnapshotProvider); large snapshots (e.g. a
+		// simulation's entire time-series history) are split transparently
+		// by writePump if they exceed the hub's maxFrameBytes.
+		if data, ok := msg.Data.(map[string]interface{}); ok {
+			path := stringOr(data["path"], "")
+			if path != "" && c.hub.snapshotFn != nil {
+				if value, ok := c.hub.snapshotFn(path); ok {
+					c.hub.send(c, &Message{
+						Type: "snapshot",
+						Data: map[string]interface{}{
+							"path":  path,
+							"value": value,
+						},
+						Timestamp: getCurrentTimestamp(),
+					})
+				}
+			}
 		}
 
 	case "get_status":
 		// Send current status
-		statusMsg := &Message{
+		c.hub.send(c, &Message{
 			Type: "status_update",
 			Data: map[string]interface{}{
 				"connected_clients": c.hub.GetConnectedClients(),
 				"server_status":     "running",
 			},
 			Timestamp: getCurrentTimestamp(),
-		}
-		
-		select {
-		case c.send <- statusMsg:
-		default:
-			logrus.Warnf("Failed to send status to client %s", c.id)
-		}
+		})
 
 	default:
 		logrus.Warnf("Unknown message type '%s' from client %s", msg.Type, c.id)
@@ -290,6 +791,48 @@ func getCurrentTimestamp() int64 {
 	return time.Now().UnixMilli()
 }
 
+// pathsFromData extracts a "paths" array or single "path"/"simulation_id"
+// field from a subscribe/unsubscribe message payload.
+func pathsFromData(data map[string]interface{}) []string {
+	if raw, ok := data["paths"].([]interface{}); ok {
+		paths := make([]string, 0, len(raw))
+		for _, p := range raw {
+			if s, ok := p.(string); ok {
+				paths = append(paths, s)
+			}
+		}
+		return paths
+	}
+	if path, ok := data["path"].(string); ok {
+		return []string{path}
+	}
+	if simID, ok := data["simulation_id"]; ok {
+		return []string{fmt.Sprintf("simulations/%v/metrics", simID)}
+	}
+	return nil
+}
+
+func stringOr(value interface{}, fallback string) string {
+	if s, ok := value.(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
+
+func floatOr(value interface{}, fallback float64) float64 {
+	if f, ok := value.(float64); ok {
+		return f
+	}
+	return fallback
+}
+
+func durationFromMillis(value interface{}, fallback time.Duration) time.Duration {
+	if f, ok := value.(float64); ok && f > 0 {
+		return time.Duration(f) * time.Millisecond
+	}
+	return fallback
+}
+
 func generateClientID() string {
 	// Simple client ID generation
 	// In production, use a more robust method
@@ -306,12 +849,11 @@ func (h *Hub) BroadcastSimulationStart(simulationID int64, config interface{}) {
 	})
 }
 
-// BroadcastSimulationUpdate sends real-time simulation metrics
+// BroadcastSimulationUpdate sends real-time simulation metrics. Rather than
+// blasting every client, it fans the update out through the subscription
+// index so only clients actually watching this simulation's paths pay for it.
 func (h *Hub) BroadcastSimulationUpdate(simulationID int64, metrics interface{}) {
-	h.Broadcast("simulation_update", map[string]interface{}{
-		"simulation_id": simulationID,
-		"metrics":       metrics,
-	})
+	h.Publish(fmt.Sprintf("simulations/%d/metrics", simulationID), metrics)
 }
 
 // BroadcastSimulationComplete notifies about simulation completion
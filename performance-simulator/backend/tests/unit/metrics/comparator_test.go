@@ -0,0 +1,77 @@
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/metrics"
+)
+
+func TestCompareServicesWithStrategyLatencyWeighted(t *testing.T) {
+	collector := metrics.NewCollector()
+
+	services := []metrics.ServicePerformance{
+		{Name: "Go Service", MaxRPS: 20000, P95Latency: 80 * time.Millisecond},
+		{Name: "Java Service", MaxRPS: 45000, P95Latency: 200 * time.Millisecond},
+	}
+
+	comparison := collector.CompareServicesWithStrategy(services, metrics.LatencyWeightedComparator{})
+
+	if comparison.Summary.BestPerformer != "Go Service" {
+		t.Errorf("expected Go Service to win on latency, got %s", comparison.Summary.BestPerformer)
+	}
+}
+
+func TestCompareServicesWithStrategySignificanceGate(t *testing.T) {
+	collector := metrics.NewCollector()
+
+	identical := make([]time.Duration, 200)
+	for i := range identical {
+		identical[i] = time.Duration(100+i%5) * time.Millisecond
+	}
+
+	services := []metrics.ServicePerformance{
+		{Name: "Service A", MaxRPS: 10000, P95Latency: 100 * time.Millisecond, RawResponseTimes: identical},
+		{Name: "Service B", MaxRPS: 9000, P95Latency: 102 * time.Millisecond, RawResponseTimes: identical},
+	}
+
+	comparison := collector.CompareServicesWithStrategy(services, metrics.ThroughputWeightedComparator{})
+
+	if comparison.Summary.PValue < 0.5 {
+		t.Errorf("expected near-identical samples to be statistically indistinguishable, got p=%f", comparison.Summary.PValue)
+	}
+	if comparison.Summary.ConfidenceInterval == nil {
+		t.Error("expected a confidence interval to be reported")
+	}
+	if comparison.Summary.Recommendation == "" {
+		t.Fatal("expected a recommendation")
+	}
+	if comparison.Summary.Recommendation != "Observed difference is not statistically significant at alpha=0.05; no migration recommended." {
+		t.Errorf("expected recommendation to refuse migration for a non-significant difference, got: %s", comparison.Summary.Recommendation)
+	}
+}
+
+func TestCompareServicesWithStrategySignificantDifference(t *testing.T) {
+	collector := metrics.NewCollector()
+
+	fast := make([]time.Duration, 200)
+	slow := make([]time.Duration, 200)
+	for i := range fast {
+		fast[i] = time.Duration(50+i%5) * time.Millisecond
+		slow[i] = time.Duration(300+i%5) * time.Millisecond
+	}
+
+	services := []metrics.ServicePerformance{
+		{Name: "Fast Service", MaxRPS: 40000, P95Latency: 55 * time.Millisecond, RawResponseTimes: fast},
+		{Name: "Slow Service", MaxRPS: 10000, P95Latency: 305 * time.Millisecond, RawResponseTimes: slow},
+	}
+
+	comparison := collector.CompareServicesWithStrategy(services, metrics.ThroughputWeightedComparator{}, metrics.WithBootstrapResamples(200))
+
+	if comparison.Summary.PValue > 0.05 {
+		t.Errorf("expected a clearly separated sample pair to be significant, got p=%f", comparison.Summary.PValue)
+	}
+	if comparison.Summary.ConfidenceInterval.Upper <= 0 {
+		t.Errorf("expected the slower service's resampled P95 gap to skew positive, got %+v", comparison.Summary.ConfidenceInterval)
+	}
+}
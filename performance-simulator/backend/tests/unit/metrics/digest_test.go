@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/metrics"
+)
+
+func TestDigestQuantilesOnUniformSamples(t *testing.T) {
+	digest := metrics.NewDigest()
+	for i := 1; i <= 1000; i++ {
+		digest.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	if digest.Count() != 1000 {
+		t.Errorf("expected 1000 samples recorded, got %d", digest.Count())
+	}
+
+	p50 := digest.Quantile(0.5)
+	if p50 < 450*time.Millisecond || p50 > 550*time.Millisecond {
+		t.Errorf("expected P50 near 500ms, got %v", p50)
+	}
+
+	p99 := digest.Quantile(0.99)
+	if p99 < 950*time.Millisecond {
+		t.Errorf("expected P99 near 990ms, got %v", p99)
+	}
+}
+
+func TestDigestMerge(t *testing.T) {
+	a := metrics.NewDigest()
+	b := metrics.NewDigest()
+
+	for i := 1; i <= 500; i++ {
+		a.Add(time.Duration(i) * time.Millisecond)
+	}
+	for i := 501; i <= 1000; i++ {
+		b.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	a.Merge(b)
+
+	if a.Count() != 1000 {
+		t.Errorf("expected merged digest to have 1000 samples, got %d", a.Count())
+	}
+	if a.Max() < 990*time.Millisecond {
+		t.Errorf("expected merged digest max near 1000ms, got %v", a.Max())
+	}
+}
@@ -0,0 +1,59 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/metrics"
+)
+
+func TestPrometheusExporterScrape(t *testing.T) {
+	collector := metrics.NewCollector()
+	exporter := metrics.NewPrometheusExporter(collector)
+
+	exporter.SetServiceLabels(1, "go", "checkout-service")
+	exporter.RecordRequest(1, "success")
+	exporter.RecordRequest(1, "success")
+	exporter.RecordRequest(1, "error")
+	exporter.UpdateThroughput(1, 1234.5)
+	exporter.UpdateErrorRate(1, 0.01)
+	exporter.UpdateResourceUsage(1, &metrics.ResourceUsage{CPUPercent: 42.5, MemoryPercent: 60})
+
+	collector.RecordMetrics(1, &metrics.SimulationMetrics{
+		SimulationID:  1,
+		TotalRequests: 3,
+		ResponseTimes: &metrics.ResponseTimes{
+			Median: 100 * time.Millisecond,
+			P95:    250 * time.Millisecond,
+			P99:    400 * time.Millisecond,
+			Mean:   120 * time.Millisecond,
+		},
+	})
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	exporter.Handler().ServeHTTP(recorder, req)
+
+	body, err := io.ReadAll(recorder.Result().Body)
+	if err != nil {
+		t.Fatalf("failed to read scrape body: %v", err)
+	}
+	output := string(body)
+
+	for _, want := range []string{
+		"simulation_requests_total",
+		"simulation_throughput_rps",
+		"simulation_error_rate",
+		"simulation_resource_cpu_percent",
+		"simulation_response_time_seconds",
+		`technology="go"`,
+		`service_name="checkout-service"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected scrape output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package metrics_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/metrics"
+)
+
+func TestSQLiteStoreRollupAndQuery(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "metrics.db")
+	store, err := metrics.NewSQLiteStore(dbPath, metrics.DefaultRetentionPolicy())
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Now().Add(-2 * time.Minute)
+	for i := 0; i < 10; i++ {
+		point := metrics.MetricPoint{
+			SimulationID: 1,
+			Kind:         metrics.KindThroughput,
+			Timestamp:    base.Add(time.Duration(i) * time.Second),
+			Value:        float64(100 + i),
+		}
+		if err := store.WritePoint(ctx, point); err != nil {
+			t.Fatalf("failed to write point: %v", err)
+		}
+	}
+
+	if err := store.CompactNow(); err != nil {
+		t.Fatalf("compaction failed: %v", err)
+	}
+
+	series, err := store.Query(ctx, 1, metrics.KindThroughput, base.Add(-time.Minute), time.Now(), 10*time.Second)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if series.Granularity != metrics.Granularity10s {
+		t.Errorf("expected 10s granularity for a 10s step, got %s", series.Granularity)
+	}
+	if len(series.Points) == 0 {
+		t.Fatal("expected at least one rollup bucket")
+	}
+	for _, p := range series.Points {
+		if p.Count == 0 {
+			t.Errorf("rollup bucket %v has zero count", p.BucketStart)
+		}
+	}
+}
+
+func TestPickGranularity(t *testing.T) {
+	cases := []struct {
+		step time.Duration
+		want metrics.Granularity
+	}{
+		{5 * time.Second, metrics.Granularity10s},
+		{45 * time.Second, metrics.Granularity10s},
+		{90 * time.Second, metrics.Granularity1m},
+		{10 * time.Minute, metrics.Granularity5m},
+		{2 * time.Hour, metrics.Granularity1h},
+	}
+
+	for _, tc := range cases {
+		if got := metrics.PickGranularity(tc.step); got != tc.want {
+			t.Errorf("PickGranularity(%v) = %s, want %s", tc.step, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package simulator_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestExecuteAssertionsEvaluatesCompoundExpression(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}
+	body := []byte(`{"status": "ok", "count": 3}`)
+
+	results := ae.ExecuteAssertions(resp, body, []simulator.Assertion{
+		{Name: "ok", Script: `status == 200 && contains(body, 'ok') && !contains(body, 'error')`},
+		{Name: "jsonpath", Script: `jsonpath('$.count') == 3`},
+		{Name: "header", Script: `header('Content-Type') == 'application/json'`},
+		{Name: "duration", Script: `duration_ms < 1000`},
+	}, 120*time.Millisecond)
+
+	for _, r := range results {
+		if r.Error != "" {
+			t.Fatalf("assertion %q errored: %s", r.Name, r.Error)
+		}
+		if !r.Passed {
+			t.Errorf("expected assertion %q to pass, got value %v", r.Name, r.Value)
+		}
+	}
+}
+
+func TestExecuteAssertionsReportsParseError(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+
+	results := ae.ExecuteAssertions(resp, []byte("{}"), []simulator.Assertion{
+		{Name: "broken", Script: "status == "},
+	}, 0)
+
+	if results[0].Error == "" {
+		t.Fatal("expected a parse error for a malformed assertion script")
+	}
+	if results[0].Passed {
+		t.Error("expected a malformed assertion to not pass")
+	}
+}
+
+func TestValidateAssertionRejectsUnknownFunction(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+
+	if err := ae.ValidateAssertion(&simulator.Assertion{Script: "status == 200"}); err != nil {
+		t.Errorf("expected a valid script to pass validation, got %v", err)
+	}
+	if err := ae.ValidateAssertion(&simulator.Assertion{Script: "bogus(body)"}); err == nil {
+		t.Error("expected an unknown function to fail validation")
+	}
+}
@@ -0,0 +1,109 @@
+package simulator_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func fakeJWT(t *testing.T, expiresAt int64) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload, err := json.Marshal(map[string]int64{"exp": expiresAt})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+func TestApplyAuthStaticSchemes(t *testing.T) {
+	am := simulator.NewAuthManager()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, &simulator.AuthConfig{Type: simulator.AuthTypeBearer, BearerToken: &simulator.BearerAuth{Token: "tok"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("expected Bearer token header, got %q", got)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, &simulator.AuthConfig{Type: simulator.AuthTypeAPIKey, APIKey: &simulator.APIKeyAuth{Key: "X-Api-Key", Value: "secret", Location: "header"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := req.Header.Get("X-Api-Key"); got != "secret" {
+		t.Errorf("expected API key header, got %q", got)
+	}
+}
+
+func TestApplyAuthJWTRefreshesNearExpiryToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "refreshed-token",
+			"expires_in":   3600,
+		})
+	}))
+	defer server.Close()
+
+	am := simulator.NewAuthManager()
+	auth := &simulator.JWTAuth{
+		Token:        fakeJWT(t, time.Now().Add(-time.Minute).Unix()),
+		RefreshToken: "rt",
+		RefreshURL:   server.URL,
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, &simulator.AuthConfig{Type: simulator.AuthTypeJWT, JWT: auth}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := req.Header.Get("Authorization"); got != "Bearer refreshed-token" {
+		t.Errorf("expected the expired token to be refreshed, got %q", got)
+	}
+	if auth.Token != "refreshed-token" {
+		t.Errorf("expected JWTAuth.Token to be updated in place, got %q", auth.Token)
+	}
+}
+
+func TestApplyAuthOAuth2ClientCredentialsCachesToken(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "cc-token",
+			"token_type":   "bearer",
+		})
+	}))
+	defer server.Close()
+
+	am := simulator.NewAuthManager()
+	cfg := &simulator.AuthConfig{
+		Type: simulator.AuthTypeOAuth2,
+		OAuth2: &simulator.OAuth2Auth{
+			ClientID:     "client",
+			ClientSecret: "secret",
+			TokenURL:     server.URL,
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		if err := am.ApplyAuth(req, cfg); err != nil {
+			t.Fatalf("unexpected error on request %d: %v", i, err)
+		}
+		if got := req.Header.Get("Authorization"); got != "Bearer cc-token" {
+			t.Errorf("expected OAuth2 bearer token, got %q", got)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("expected the token endpoint to be hit once and the token reused, got %d requests", requests)
+	}
+}
@@ -0,0 +1,71 @@
+package simulator_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestCapabilityProberInvalidateCachesAndMergesFlags(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"features": map[string]bool{"new_checkout": true},
+		})
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	prober := simulator.NewCapabilityProberWithClock(func() time.Time { return now })
+
+	prober.Invalidate(server.URL)
+
+	snapshot, ok := prober.Get(server.URL)
+	if !ok {
+		t.Fatalf("expected a cached snapshot after Invalidate")
+	}
+	if !snapshot.FeatureFlags["new_checkout"] {
+		t.Fatalf("expected new_checkout feature flag to be true, got %+v", snapshot.FeatureFlags)
+	}
+	if !snapshot.LastProbedAt.Equal(now) {
+		t.Fatalf("expected LastProbedAt %v, got %v", now, snapshot.LastProbedAt)
+	}
+	if hits != 2 {
+		t.Fatalf("expected 2 hits (one per default capability path), got %d", hits)
+	}
+}
+
+func TestCapabilityProberInvalidateRespectsMinInterval(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	prober := simulator.NewCapabilityProberWithClock(func() time.Time { return now })
+
+	prober.Invalidate(server.URL)
+	firstHits := hits
+
+	// A second Invalidate less than a minute later must not trigger another
+	// probe, so a burst of calls can't stampede the target.
+	prober.Invalidate(server.URL)
+	if hits != firstHits {
+		t.Fatalf("expected Invalidate to be rate-limited, hits went from %d to %d", firstHits, hits)
+	}
+
+	now = now.Add(2 * time.Minute)
+	prober.Invalidate(server.URL)
+	if hits <= firstHits {
+		t.Fatalf("expected Invalidate to probe again once past the min interval, hits stayed at %d", hits)
+	}
+}
@@ -0,0 +1,63 @@
+package simulator_test
+
+import (
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestEvaluateJSONPath(t *testing.T) {
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"token": "abc123",
+			"items": []interface{}{
+				map[string]interface{}{"id": "first"},
+				map[string]interface{}{"id": "second"},
+			},
+		},
+	}
+
+	cases := []struct {
+		path string
+		want interface{}
+	}{
+		{"$.data.token", "abc123"},
+		{"$.data.items[0].id", "first"},
+		{"$.data.items[1].id", "second"},
+		{"$.data.items[*].id", "first"},
+	}
+
+	for _, tc := range cases {
+		got, ok := simulator.EvaluateJSONPath(data, tc.path)
+		if !ok {
+			t.Errorf("EvaluateJSONPath(%q) did not match", tc.path)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("EvaluateJSONPath(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	if _, ok := simulator.EvaluateJSONPath(data, "$.data.missing"); ok {
+		t.Error("expected a miss for a path that doesn't exist")
+	}
+}
+
+func TestChildVariableResolverScoping(t *testing.T) {
+	root := simulator.NewVariableResolver()
+	child := simulator.NewChildVariableResolver(root)
+
+	child.SetVariable("token", "captured-value")
+
+	resolved, err := child.Resolve("Bearer {{token}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved != "Bearer captured-value" {
+		t.Errorf("expected the child's own variable to resolve, got %q", resolved)
+	}
+
+	if _, err := root.Resolve("{{token}}"); err == nil {
+		t.Error("expected a variable set on a child resolver not to leak to its parent")
+	}
+}
@@ -0,0 +1,50 @@
+package simulator_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestExecuteAssertionsCELEvaluatesResponseJSONAndDuration(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"X-Request-Id": []string{"abc"}}}
+	body := []byte(`{"items": [1, 2, 3]}`)
+
+	results := ae.ExecuteAssertions(resp, body, []simulator.Assertion{
+		{
+			Name:       "ok",
+			Type:       simulator.AssertionTypeCEL,
+			Expression: `response.status == 200 && json.items.size() > 0 && duration < duration('500ms')`,
+		},
+		{
+			Name:       "header-match",
+			Type:       simulator.AssertionTypeCEL,
+			Expression: `match('^[a-z]+$', response.headers["X-Request-Id"])`,
+		},
+	}, 10*time.Millisecond)
+
+	for _, r := range results {
+		if r.Error != "" {
+			t.Fatalf("assertion %q errored: %s", r.Name, r.Error)
+		}
+		if !r.Passed {
+			t.Errorf("expected assertion %q to pass, got %v", r.Name, r.Value)
+		}
+	}
+}
+
+func TestExecuteAssertionsCELFailsOnBadExpression(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+
+	results := ae.ExecuteAssertions(resp, []byte(`{}`), []simulator.Assertion{
+		{Name: "bad", Type: simulator.AssertionTypeCEL, Expression: "response.status =="},
+	}, 0)
+
+	if results[0].Error == "" {
+		t.Fatalf("expected a malformed CEL expression to report an error")
+	}
+}
@@ -0,0 +1,122 @@
+package simulator_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestBuildCorpusFromHARGroupsByEndpoint(t *testing.T) {
+	har := `{
+		"log": {
+			"entries": [
+				{"request": {"method": "GET", "url": "https://api.example.com/users?id=1", "headers": [{"name": "Accept", "value": "application/json"}]}},
+				{"request": {"method": "GET", "url": "https://api.example.com/users?id=2", "headers": []}},
+				{"request": {"method": "POST", "url": "https://api.example.com/orders", "headers": [], "postData": {"mimeType": "application/json", "text": "{\"item\":\"widget\"}"}}}
+			]
+		}
+	}`
+
+	corpus, err := simulator.BuildCorpusFromHAR([]byte(har))
+	if err != nil {
+		t.Fatalf("BuildCorpusFromHAR failed: %v", err)
+	}
+	if len(corpus.Entries) != 2 {
+		t.Fatalf("expected 2 grouped endpoints, got %d: %+v", len(corpus.Entries), corpus.Entries)
+	}
+
+	var getEntry, postEntry *simulator.CorpusEntry
+	for i := range corpus.Entries {
+		switch corpus.Entries[i].Method {
+		case "GET":
+			getEntry = &corpus.Entries[i]
+		case "POST":
+			postEntry = &corpus.Entries[i]
+		}
+	}
+	if getEntry == nil || getEntry.Weight != 2 {
+		t.Fatalf("expected GET /users weight 2, got %+v", getEntry)
+	}
+	if postEntry == nil || postEntry.Body == nil || postEntry.Body.Type != simulator.BodyTypeJSON {
+		t.Fatalf("expected POST /orders with a JSON body, got %+v", postEntry)
+	}
+}
+
+func TestBuildCorpusFromOpenAPIGeneratesBodyFromSchema(t *testing.T) {
+	spec := `{
+		"openapi": "3.0.0",
+		"servers": [{"url": "https://api.example.com"}],
+		"paths": {
+			"/users/{id}": {
+				"get": {"operationId": "getUser"}
+			},
+			"/users": {
+				"post": {
+					"operationId": "createUser",
+					"requestBody": {
+						"content": {
+							"application/json": {
+								"schema": {
+									"type": "object",
+									"properties": {
+										"email": {"type": "string", "format": "email"},
+										"id": {"type": "string", "format": "uuid"}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}`
+
+	corpus, err := simulator.BuildCorpusFromOpenAPI([]byte(spec), "")
+	if err != nil {
+		t.Fatalf("BuildCorpusFromOpenAPI failed: %v", err)
+	}
+	if len(corpus.Entries) != 2 {
+		t.Fatalf("expected 2 operations, got %d", len(corpus.Entries))
+	}
+
+	var getEntry, postEntry *simulator.CorpusEntry
+	for i := range corpus.Entries {
+		switch corpus.Entries[i].Name {
+		case "getUser":
+			getEntry = &corpus.Entries[i]
+		case "createUser":
+			postEntry = &corpus.Entries[i]
+		}
+	}
+	if getEntry == nil || !strings.Contains(getEntry.URL, "{{id}}") {
+		t.Fatalf("expected getUser's path parameter templated, got %+v", getEntry)
+	}
+	if postEntry == nil || postEntry.Body == nil {
+		t.Fatalf("expected createUser to have a generated body, got %+v", postEntry)
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal([]byte(postEntry.Body.Content), &body); err != nil {
+		t.Fatalf("generated body is not valid JSON: %v", err)
+	}
+	if body["email"] != "{{random_email}}" || body["id"] != "{{uuid}}" {
+		t.Fatalf("expected format-based placeholders, got %+v", body)
+	}
+}
+
+func TestRequestCorpusNextSamplesProportionalToWeight(t *testing.T) {
+	corpus := &simulator.RequestCorpus{Entries: []simulator.CorpusEntry{
+		{Name: "light", URL: "https://example.com/light", Weight: 1},
+		{Name: "heavy", URL: "https://example.com/heavy", Weight: 3},
+	}}
+
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		counts[corpus.Next().Name]++
+	}
+	if counts["heavy"] != 3*counts["light"] {
+		t.Fatalf("expected heavy to be sampled 3x as often as light, got %+v", counts)
+	}
+}
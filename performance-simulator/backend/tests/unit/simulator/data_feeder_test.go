@@ -0,0 +1,133 @@
+package simulator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func writeTestCSV(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "users.csv")
+	content := "email,password\nalice@example.com,alice-pw\nbob@example.com,bob-pw\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write csv fixture: %v", err)
+	}
+	return path
+}
+
+func writeTestJSONLines(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	content := "{\"email\": \"carol@example.com\"}\n{\"email\": \"dave@example.com\"}\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write jsonl fixture: %v", err)
+	}
+	return path
+}
+
+func TestCSVDataFeederRoundRobinsByDefault(t *testing.T) {
+	feeder, err := simulator.NewCSVDataFeeder(writeTestCSV(t), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var emails []string
+	for i := 0; i < 4; i++ {
+		row, err := feeder.Next()
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		emails = append(emails, row["email"])
+	}
+	want := []string{"alice@example.com", "bob@example.com", "alice@example.com", "bob@example.com"}
+	for i, e := range emails {
+		if e != want[i] {
+			t.Fatalf("row %d: got %q, want %q", i, e, want[i])
+		}
+	}
+}
+
+func TestCSVDataFeederSequentialExhausts(t *testing.T) {
+	feeder, err := simulator.NewCSVDataFeeder(writeTestCSV(t), simulator.FeederModeSequential)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := feeder.Next(); err != nil {
+		t.Fatalf("Next 1: %v", err)
+	}
+	if _, err := feeder.Next(); err != nil {
+		t.Fatalf("Next 2: %v", err)
+	}
+	if _, err := feeder.Next(); err == nil {
+		t.Fatal("expected sequential feeder to error once exhausted")
+	}
+}
+
+func TestJSONLinesDataFeederReadsRows(t *testing.T) {
+	feeder, err := simulator.NewJSONLinesDataFeeder(writeTestJSONLines(t), simulator.FeederModeSequential)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, err := feeder.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if first["email"] != "carol@example.com" {
+		t.Fatalf("got %q, want carol@example.com", first["email"])
+	}
+}
+
+func TestResolveCSVFunctionKeepsColumnsFromTheSameRowWithinOneIteration(t *testing.T) {
+	root := simulator.NewVariableResolver()
+	sim := simulator.NewChildVariableResolver(root, 1)
+	sim.EnableDataFeeders()
+	vu := simulator.NewChildVariableResolver(sim, 2)
+
+	path := writeTestCSV(t)
+	for i := 0; i < 3; i++ {
+		vu.BeginIteration()
+		got, err := vu.Resolve(`{{csv "` + path + `" "email"}}:{{csv "` + path + `" "password"}}`)
+		if err != nil {
+			t.Fatalf("iteration %d: unexpected error: %v", i, err)
+		}
+		if got != "alice@example.com:alice-pw" && got != "bob@example.com:bob-pw" {
+			t.Fatalf("iteration %d: email/password pair from different rows: %q", i, got)
+		}
+	}
+}
+
+func TestResolveCSVFunctionRequiresAFeederRegistry(t *testing.T) {
+	vr := simulator.NewVariableResolver()
+	vr.BeginIteration()
+	if _, err := vr.Resolve(`{{csv "` + writeTestCSV(t) + `" "email"}}`); err == nil {
+		t.Fatal("expected an error resolving {{csv}} on a resolver with no simulation-scoped feeder registry")
+	}
+}
+
+func TestResolveIterationAndVUIDFunctions(t *testing.T) {
+	vr := simulator.NewVariableResolver()
+	vr.VUID = 7
+
+	vr.BeginIteration()
+	got, err := vr.Resolve("{{vu_id}}/{{iteration}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "7/1" {
+		t.Fatalf("got %q, want 7/1", got)
+	}
+
+	vr.BeginIteration()
+	got, err = vr.Resolve("{{iteration}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "2" {
+		t.Fatalf("got %q, want 2 after a second BeginIteration", got)
+	}
+}
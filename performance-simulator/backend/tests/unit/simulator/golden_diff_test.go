@@ -0,0 +1,100 @@
+package simulator_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+type fakeGoldenStore struct {
+	goldens map[string]*simulator.GoldenResponse
+}
+
+func newFakeGoldenStore() *fakeGoldenStore {
+	return &fakeGoldenStore{goldens: make(map[string]*simulator.GoldenResponse)}
+}
+
+func (s *fakeGoldenStore) key(simulationID int64, key string) string {
+	return fmt.Sprintf("%d|%s", simulationID, key)
+}
+
+func (s *fakeGoldenStore) GetGolden(simulationID int64, key string) (*simulator.GoldenResponse, error) {
+	golden, ok := s.goldens[s.key(simulationID, key)]
+	if !ok {
+		return nil, errNotRecorded
+	}
+	return golden, nil
+}
+
+func (s *fakeGoldenStore) SaveGolden(simulationID int64, key string, resp *simulator.GoldenResponse) error {
+	s.goldens[s.key(simulationID, key)] = resp
+	return nil
+}
+
+var errNotRecorded = &notRecordedError{}
+
+type notRecordedError struct{}
+
+func (*notRecordedError) Error() string { return "golden not recorded" }
+
+func TestValidateDiffRecordsThenPassesAnIdenticalResponse(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	ve.SetGoldenStore(newFakeGoldenStore())
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+	validation := &simulator.ResponseValidation{
+		Body: &simulator.BodyValidation{
+			Type: simulator.ValidationTypeJSON,
+			Diff: &simulator.DiffValidation{Key: "get-user", Record: true},
+		},
+	}
+
+	recordResult := ve.ValidateResponseForSimulation(resp, []byte(`{"id": 1, "name": "ada"}`), validation, time.Millisecond, 42)
+	if !recordResult.Passed {
+		t.Fatalf("expected recording to succeed, got errors: %+v", recordResult.Errors)
+	}
+
+	validation.Body.Diff.Record = false
+	sameResult := ve.ValidateResponseForSimulation(resp, []byte(`{"id": 1, "name": "ada"}`), validation, time.Millisecond, 42)
+	if !sameResult.Passed {
+		t.Fatalf("expected identical response to pass diff validation, got errors: %+v", sameResult.Errors)
+	}
+
+	diffResult := ve.ValidateResponseForSimulation(resp, []byte(`{"id": 1, "name": "grace"}`), validation, time.Millisecond, 42)
+	if diffResult.Passed {
+		t.Fatalf("expected a changed field to fail diff validation")
+	}
+	found := false
+	for _, e := range diffResult.Errors {
+		if e.Type == "diff" && e.Field == "/name" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a diff error for /name, got %+v", diffResult.Errors)
+	}
+}
+
+func TestValidateDiffIgnoresConfiguredPaths(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	ve.SetGoldenStore(newFakeGoldenStore())
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+	validation := &simulator.ResponseValidation{
+		Body: &simulator.BodyValidation{
+			Type: simulator.ValidationTypeJSON,
+			Diff: &simulator.DiffValidation{Key: "get-user", Record: true, IgnorePaths: []string{"/requestId"}},
+		},
+	}
+
+	ve.ValidateResponseForSimulation(resp, []byte(`{"id": 1, "requestId": "abc"}`), validation, time.Millisecond, 7)
+
+	validation.Body.Diff.Record = false
+	result := ve.ValidateResponseForSimulation(resp, []byte(`{"id": 1, "requestId": "xyz"}`), validation, time.Millisecond, 7)
+	if !result.Passed {
+		t.Fatalf("expected a change to an ignored path to still pass, got errors: %+v", result.Errors)
+	}
+}
@@ -0,0 +1,61 @@
+package simulator_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestExecuteAssertionsReadsGRPCStatusAndTrailer(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{
+		StatusCode: 0,
+		Header:     http.Header{},
+		Trailer:    http.Header{"Grpc-Message": []string{"ok"}},
+	}
+
+	results := ae.ExecuteAssertions(resp, []byte(`{}`), []simulator.Assertion{
+		{Name: "ok", Script: `status == 0`},
+		{Name: "trailer", Script: `trailer('grpc-message') == 'ok'`},
+	}, 0)
+
+	for _, r := range results {
+		if r.Error != "" {
+			t.Fatalf("assertion %q errored: %s", r.Name, r.Error)
+		}
+		if !r.Passed {
+			t.Errorf("expected assertion %q to pass, got %v", r.Name, r.Value)
+		}
+	}
+}
+
+func TestExecuteAssertionsDistinguishesGraphQLErrorsFromData(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+
+	successBody := []byte(`{"data": {"user": {"id": 7}}}`)
+	results := ae.ExecuteAssertions(resp, successBody, []simulator.Assertion{
+		{Name: "no-errors", Script: `len(graphqlErrors()) == 0`},
+		{Name: "data", Script: `graphqlData('$.user.id') == 7`},
+	}, 0)
+	for _, r := range results {
+		if r.Error != "" {
+			t.Fatalf("assertion %q errored: %s", r.Name, r.Error)
+		}
+		if !r.Passed {
+			t.Errorf("expected assertion %q to pass against a success response, got %v", r.Name, r.Value)
+		}
+	}
+
+	errorBody := []byte(`{"data": null, "errors": [{"message": "not found"}]}`)
+	errResults := ae.ExecuteAssertions(resp, errorBody, []simulator.Assertion{
+		{Name: "has-errors", Script: `contains(graphqlErrors(), 'not found')`},
+	}, 0)
+	if errResults[0].Error != "" {
+		t.Fatalf("assertion errored: %s", errResults[0].Error)
+	}
+	if !errResults[0].Passed {
+		t.Errorf("expected graphqlErrors() to surface the error message, got %v", errResults[0].Value)
+	}
+}
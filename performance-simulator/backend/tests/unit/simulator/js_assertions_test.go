@@ -0,0 +1,90 @@
+package simulator_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestExecuteAssertionsJSEvaluatesPMResponse(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"X-Request-Id": []string{"abc"}}}
+	body := []byte(`{"items": [1, 2, 3]}`)
+
+	results := ae.ExecuteAssertions(resp, body, []simulator.Assertion{
+		{
+			Name:       "status-and-json",
+			Type:       simulator.AssertionTypeJS,
+			Expression: `pm.response.code === 200 && pm.response.json().items.length === 3`,
+		},
+		{
+			Name:       "headers-and-timing",
+			Type:       simulator.AssertionTypeJS,
+			Expression: `pm.response.headers["X-Request-Id"] === "abc" && pm.response.responseTime < 500`,
+		},
+	}, 10*time.Millisecond)
+
+	for _, r := range results {
+		if r.Error != "" {
+			t.Fatalf("assertion %q errored: %s", r.Name, r.Error)
+		}
+		if !r.Passed {
+			t.Errorf("expected assertion %q to pass, got %v", r.Name, r.Value)
+		}
+	}
+}
+
+func TestExecuteAssertionsJSFailsOnInvalidJSONBody(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+
+	results := ae.ExecuteAssertions(resp, []byte(`not json`), []simulator.Assertion{
+		{Name: "bad-json", Type: simulator.AssertionTypeJS, Expression: `pm.response.json().items.length === 3`},
+	}, 0)
+
+	if results[0].Error == "" {
+		t.Fatalf("expected response.json() against a non-JSON body to report an error")
+	}
+}
+
+func TestExecuteAssertionsJSFailsOnSyntaxError(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+
+	results := ae.ExecuteAssertions(resp, []byte(`{}`), []simulator.Assertion{
+		{Name: "bad-syntax", Type: simulator.AssertionTypeJS, Expression: `pm.response.code ===`},
+	}, 0)
+
+	if results[0].Error == "" {
+		t.Fatalf("expected a malformed JS expression to report an error")
+	}
+}
+
+func TestExecuteAssertionsJSTimesOutOnInfiniteLoop(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+
+	results := ae.ExecuteAssertions(resp, []byte(`{}`), []simulator.Assertion{
+		{Name: "infinite-loop", Type: simulator.AssertionTypeJS, Expression: `while (true) {}`},
+	}, 0)
+
+	if results[0].Error == "" {
+		t.Fatalf("expected a runaway JS assertion to be interrupted and reported as an error")
+	}
+}
+
+func TestValidateAssertionRejectsEmptyOrInvalidJS(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+
+	if err := ae.ValidateAssertion(&simulator.Assertion{Type: simulator.AssertionTypeJS, Expression: ""}); err == nil {
+		t.Fatalf("expected an empty JS expression to be rejected")
+	}
+	if err := ae.ValidateAssertion(&simulator.Assertion{Type: simulator.AssertionTypeJS, Expression: "pm.response.code ==="}); err == nil {
+		t.Fatalf("expected a malformed JS expression to be rejected")
+	}
+	if err := ae.ValidateAssertion(&simulator.Assertion{Type: simulator.AssertionTypeJS, Expression: "pm.response.code === 200"}); err != nil {
+		t.Fatalf("expected a valid JS expression to be accepted, got: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package simulator_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestValidateJSONSchemaRejectsBodyViolatingSchema(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+
+	schema := `{
+		"type": "object",
+		"required": ["id", "email"],
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"email": {"type": "string"}
+		}
+	}`
+
+	result := ve.ValidateResponse(resp, []byte(`{"id": "not-a-uuid", "email": "a@b.com"}`), &simulator.ResponseValidation{
+		Body: &simulator.BodyValidation{Type: simulator.ValidationTypeJSON, JSONSchema: schema},
+	}, time.Millisecond)
+
+	if result.Passed {
+		t.Fatalf("expected schema validation to fail for a malformed uuid")
+	}
+	found := false
+	for _, e := range result.Errors {
+		if e.Type == "json_schema" && e.Field == "id" {
+			found = true
+			if e.Pointer != "/id" {
+				t.Errorf("expected pointer /id, got %q", e.Pointer)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected a json_schema error for field id, got %+v", result.Errors)
+	}
+}
+
+func TestValidateJSONSchemaResolvesRefByJSONSchemaRef(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	ve.RegisterSchema("https://example.com/schemas/user.json", `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {"name": {"type": "string"}}
+	}`)
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+	result := ve.ValidateResponse(resp, []byte(`{"name": "ada"}`), &simulator.ResponseValidation{
+		Body: &simulator.BodyValidation{Type: simulator.ValidationTypeJSON, JSONSchemaRef: "https://example.com/schemas/user.json"},
+	}, time.Millisecond)
+
+	if !result.Passed {
+		t.Fatalf("expected registered schema to pass, got errors: %+v", result.Errors)
+	}
+}
+
+func TestValidateJSONSchemaCustomFormatChecker(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	ve.RegisterFormatChecker("even-length", func(v interface{}) bool {
+		s, ok := v.(string)
+		return !ok || len(s)%2 == 0
+	})
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+	schema := `{"type": "object", "properties": {"code": {"type": "string", "format": "even-length"}}}`
+
+	result := ve.ValidateResponse(resp, []byte(`{"code": "abc"}`), &simulator.ResponseValidation{
+		Body: &simulator.BodyValidation{Type: simulator.ValidationTypeJSON, JSONSchema: schema},
+	}, time.Millisecond)
+
+	if result.Passed {
+		t.Fatalf("expected custom format checker to fail odd-length code")
+	}
+}
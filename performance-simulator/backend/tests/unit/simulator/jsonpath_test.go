@@ -0,0 +1,72 @@
+package simulator_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestJSONPathEvaluatorSupportsWildcardsAndFilters(t *testing.T) {
+	var data interface{}
+	raw := `{"items": [{"id": 1, "price": 5}, {"id": 2, "price": 15}, {"id": 3, "price": 25}]}`
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	evaluator := simulator.NewJSONPathEvaluator()
+
+	ids, err := evaluator.Evaluate(data, "$.items[*].id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	arr, ok := ids.([]interface{})
+	if !ok || len(arr) != 3 {
+		t.Fatalf("expected 3 matches for a wildcard path, got %v", ids)
+	}
+
+	filtered, err := evaluator.Evaluate(data, "$.items[?(@.price>10)].id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	filteredArr, ok := filtered.([]interface{})
+	if !ok || len(filteredArr) != 2 {
+		t.Fatalf("expected 2 matches for a filter expression, got %v", filtered)
+	}
+}
+
+func TestJSONPathEvaluatorSupportsJSONPointer(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{"items": [{"id": 42}]}`), &data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, err := simulator.NewJSONPathEvaluator().Evaluate(data, "/items/0/id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != float64(42) {
+		t.Errorf("expected 42, got %v", value)
+	}
+}
+
+func TestExecuteAssertionsJSONPathArrayResultSupportsContainsAndEquality(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+	body := []byte(`{"items": [{"id": 1}, {"id": 2}, {"id": 3}]}`)
+
+	results := ae.ExecuteAssertions(resp, body, []simulator.Assertion{
+		{Name: "contains", Script: `contains(jsonpath('$.items[*].id'), 2)`},
+		{Name: "equals", Script: `jsonpath('$.items[*].id') == 3`},
+	}, 0)
+
+	for _, r := range results {
+		if r.Error != "" {
+			t.Fatalf("assertion %q errored: %s", r.Name, r.Error)
+		}
+		if !r.Passed {
+			t.Errorf("expected assertion %q to pass against the matched array, got %v", r.Name, r.Value)
+		}
+	}
+}
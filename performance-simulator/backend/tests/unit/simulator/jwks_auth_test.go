@@ -0,0 +1,153 @@
+package simulator_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+// newOIDCTestProvider spins up an httptest server serving OIDC discovery, a
+// JWKS document for signingKey, and a token endpoint that mints an RS256
+// token signed by signingKey with the given kid.
+func newOIDCTestProvider(t *testing.T, signingKey *rsa.PrivateKey, kid string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	var serverURL string
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"jwks_uri":       serverURL + "/jwks",
+			"token_endpoint": serverURL + "/token",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		n := base64.RawURLEncoding.EncodeToString(signingKey.PublicKey.N.Bytes())
+		e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signingKey.PublicKey.E)).Bytes())
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{"kty": "RSA", "kid": kid, "n": n, "e": e}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.MapClaims{
+			"iss": "https://issuer.example.com",
+			"aud": "load-test",
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Unix(),
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("sign test token: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": signed,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	serverURL = server.URL
+	return server
+}
+
+func TestApplyAuthJWTOIDCDiscoversAndVerifiesToken(t *testing.T) {
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	server := newOIDCTestProvider(t, signingKey, "key-1")
+	defer server.Close()
+
+	am := simulator.NewAuthManager()
+	auth := &simulator.JWTAuth{
+		OIDCIssuer:   server.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		Issuer:       "https://issuer.example.com",
+		Audience:     "load-test",
+	}
+	am.SetAuthConfig(1, &simulator.AuthConfig{Type: simulator.AuthTypeJWT, JWT: auth})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuthForSimulation(req, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	authHeader := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		t.Fatalf("expected a Bearer-prefixed Authorization header, got %q", authHeader)
+	}
+}
+
+func TestApplyAuthJWTOIDCRejectsTamperedSignature(t *testing.T) {
+	advertisedKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	actualSigningKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+
+	// The JWKS advertises advertisedKey's public key under "key-1", but the
+	// token endpoint actually signs with a different key under that same
+	// kid — simulating a compromised or misconfigured token issuer.
+	mux := http.NewServeMux()
+	var serverURL string
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"jwks_uri":       serverURL + "/jwks",
+			"token_endpoint": serverURL + "/token",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		n := base64.RawURLEncoding.EncodeToString(advertisedKey.PublicKey.N.Bytes())
+		e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(advertisedKey.PublicKey.E)).Bytes())
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{"kty": "RSA", "kid": "key-1", "n": n, "e": e}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix()}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(actualSigningKey)
+		if err != nil {
+			t.Fatalf("sign test token: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": signed, "token_type": "Bearer", "expires_in": 3600})
+	})
+
+	server := httptest.NewServer(mux)
+	serverURL = server.URL
+	defer server.Close()
+
+	am := simulator.NewAuthManager()
+	auth := &simulator.JWTAuth{
+		OIDCIssuer:   server.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+	}
+	am.SetAuthConfig(2, &simulator.AuthConfig{Type: simulator.AuthTypeJWT, JWT: auth})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuthForSimulation(req, 2); err == nil {
+		t.Fatalf("expected signature verification to fail against a mismatched JWKS key")
+	}
+}
@@ -0,0 +1,102 @@
+package simulator_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestApplyAuthJWTMintsSignedTokenWithResolvedClaims(t *testing.T) {
+	am := simulator.NewAuthManager()
+	auth := &simulator.JWTAuth{
+		Algorithm:  "HS256",
+		SigningKey: "test-secret",
+		Issuer:     "origo-stack",
+		Audience:   "load-test",
+		ClaimTemplate: map[string]interface{}{
+			"sub":  "{{username}}",
+			"role": "tester",
+		},
+	}
+	cfg := &simulator.AuthConfig{Type: simulator.AuthTypeJWT, JWT: auth}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	authHeader := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		t.Fatalf("expected a Bearer-prefixed Authorization header, got %q", authHeader)
+	}
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		return []byte("test-secret"), nil
+	})
+	if err != nil || !token.Valid {
+		t.Fatalf("expected a validly signed token, got err=%v valid=%v", err, token.Valid)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		t.Fatalf("expected MapClaims, got %T", token.Claims)
+	}
+	if claims["iss"] != "origo-stack" {
+		t.Errorf("expected iss claim, got %v", claims["iss"])
+	}
+	if claims["aud"] != "load-test" {
+		t.Errorf("expected aud claim, got %v", claims["aud"])
+	}
+	if claims["role"] != "tester" {
+		t.Errorf("expected static role claim to pass through, got %v", claims["role"])
+	}
+	if claims["exp"] == nil || claims["iat"] == nil || claims["jti"] == nil {
+		t.Errorf("expected exp/iat/jti to be set, got %v", claims)
+	}
+	// sub has no resolver here, so the unresolved "{{username}}" literal
+	// template should pass through unchanged.
+	if claims["sub"] != "{{username}}" {
+		t.Errorf("expected unresolved sub template without a resolver, got %v", claims["sub"])
+	}
+}
+
+func TestApplyAuthJWTRejectsUnknownAlgorithm(t *testing.T) {
+	am := simulator.NewAuthManager()
+	auth := &simulator.JWTAuth{
+		Algorithm:  "HS999",
+		SigningKey: "secret",
+	}
+	cfg := &simulator.AuthConfig{Type: simulator.AuthTypeJWT, JWT: auth}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, cfg); err == nil {
+		t.Fatal("expected an unsupported algorithm to fail")
+	}
+}
+
+func TestApplyAuthJWTUsesCustomHeader(t *testing.T) {
+	am := simulator.NewAuthManager()
+	auth := &simulator.JWTAuth{
+		Algorithm:    "HS256",
+		SigningKey:   "secret",
+		HeaderName:   "X-Service-Token",
+		HeaderPrefix: "",
+	}
+	cfg := &simulator.AuthConfig{Type: simulator.AuthTypeJWT, JWT: auth}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.Header.Get("Authorization") != "" {
+		t.Errorf("expected no Authorization header when HeaderName is overridden")
+	}
+	if req.Header.Get("X-Service-Token") == "" {
+		t.Errorf("expected token on the configured custom header")
+	}
+}
@@ -0,0 +1,176 @@
+package simulator_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+// generateSelfSignedCert writes a freshly generated RSA key and a
+// self-signed certificate for commonName to certPath/keyPath, returning the
+// PEM-encoded certificate bytes as well (for use as a CA bundle).
+func generateSelfSignedCert(t *testing.T, certPath, keyPath, commonName string) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	if err := os.WriteFile(certPath, certPEM, 0600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	return certPEM
+}
+
+func TestValidateAuthAcceptsValidClientCertAndCABundle(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	caPEM := generateSelfSignedCert(t, certPath, keyPath, "client.example.com")
+
+	tlsCfg := &simulator.TLSConfig{
+		CertFile: certPath,
+		KeyFile:  keyPath,
+		CABundle: string(caPEM),
+	}
+
+	am := simulator.NewAuthManager()
+	if _, err := am.CreateHTTPClientWithAuth(tlsCfg); err != nil {
+		t.Fatalf("expected valid mTLS config to build an HTTP client, got: %v", err)
+	}
+}
+
+func TestValidateAuthRejectsGarbageCABundle(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	generateSelfSignedCert(t, certPath, keyPath, "client.example.com")
+
+	tlsCfg := &simulator.TLSConfig{
+		CertFile: certPath,
+		KeyFile:  keyPath,
+		CABundle: "not a certificate",
+	}
+
+	am := simulator.NewAuthManager()
+	if _, err := am.CreateHTTPClientWithAuth(tlsCfg); err == nil {
+		t.Fatalf("expected a garbage CA bundle to fail validation")
+	}
+}
+
+func TestValidateAuthRejectsMismatchedKey(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	otherKeyPath := filepath.Join(dir, "other.key")
+	generateSelfSignedCert(t, certPath, keyPath, "client.example.com")
+	generateSelfSignedCert(t, filepath.Join(dir, "other.crt"), otherKeyPath, "other.example.com")
+
+	tlsCfg := &simulator.TLSConfig{
+		CertFile: certPath,
+		KeyFile:  otherKeyPath,
+	}
+
+	am := simulator.NewAuthManager()
+	if _, err := am.CreateHTTPClientWithAuth(tlsCfg); err == nil {
+		t.Fatalf("expected a cert/key mismatch to fail validation")
+	}
+}
+
+func TestCreateHTTPClientWithAuthComposesMTLSWithHeaderAuth(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	generateSelfSignedCert(t, certPath, keyPath, "client.example.com")
+
+	tlsCfg := &simulator.TLSConfig{CertFile: certPath, KeyFile: keyPath}
+
+	am := simulator.NewAuthManager()
+	client, err := am.CreateHTTPClientWithAuth(tlsCfg)
+	if err != nil {
+		t.Fatalf("expected mTLS config to build an HTTP client, got: %v", err)
+	}
+	if client.Transport == nil {
+		t.Fatalf("expected a transport configured for mTLS")
+	}
+
+	// mTLS transport and header-based auth are independent: a Bearer token
+	// still applies to a request sent over this same client.
+	cfg := &simulator.AuthConfig{
+		Type:        simulator.AuthTypeBearer,
+		BearerToken: &simulator.BearerAuth{Token: "secret-token"},
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	if err := am.ApplyAuth(req, cfg); err != nil {
+		t.Fatalf("unexpected error applying bearer auth: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret-token" {
+		t.Fatalf("expected Bearer secret-token, got %q", got)
+	}
+}
+
+func TestCreateHTTPClientWithAuthAppliesMinVersionAndCipherSuites(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	generateSelfSignedCert(t, certPath, keyPath, "client.example.com")
+
+	tlsCfg := &simulator.TLSConfig{
+		CertFile:     certPath,
+		KeyFile:      keyPath,
+		MinVersion:   "1.2",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	}
+
+	am := simulator.NewAuthManager()
+	client, err := am.CreateHTTPClientWithAuth(tlsCfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig == nil {
+		t.Fatalf("expected an *http.Transport with a TLS config")
+	}
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("expected MinVersion TLS 1.2, got %x", transport.TLSClientConfig.MinVersion)
+	}
+	if len(transport.TLSClientConfig.CipherSuites) != 1 || transport.TLSClientConfig.CipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("expected the configured cipher suite, got %v", transport.TLSClientConfig.CipherSuites)
+	}
+}
@@ -0,0 +1,149 @@
+package simulator_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+// newFormCapturingTokenServer returns an httptest server that parses the
+// incoming token request's form body, hands it to observe, and responds
+// with a minimal valid token response.
+func newFormCapturingTokenServer(t *testing.T, observe func(form url.Values)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse token request form: %v", err)
+		}
+		observe(r.Form)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "tok", "token_type": "bearer",
+		})
+	}))
+}
+
+// fakeOAuth2Fetcher is a test-only simulator.OAuth2TokenFetcher that returns
+// scripted responses instead of calling a real token endpoint, exercising
+// AuthManager's caching/refresh/rotation logic in isolation.
+type fakeOAuth2Fetcher struct {
+	calls     []*simulator.OAuth2Auth
+	responses []*simulator.TokenCache
+}
+
+func (f *fakeOAuth2Fetcher) FetchOAuth2Token(_ context.Context, auth *simulator.OAuth2Auth) (*simulator.TokenCache, error) {
+	f.calls = append(f.calls, auth)
+	resp := f.responses[len(f.calls)-1]
+	return resp, nil
+}
+
+func TestApplyAuthOAuth2InjectedFetcherRotatesRefreshToken(t *testing.T) {
+	am := simulator.NewAuthManager()
+	am.RefreshSkew = 0
+
+	fetcher := &fakeOAuth2Fetcher{
+		responses: []*simulator.TokenCache{
+			{AccessToken: "token-1", RefreshToken: "refresh-1", ExpiresAt: 1},
+			{AccessToken: "token-2", RefreshToken: "refresh-2", ExpiresAt: 2},
+		},
+	}
+	am.OAuth2TokenFetcher = fetcher
+
+	cfg := &simulator.AuthConfig{
+		Type: simulator.AuthTypeOAuth2,
+		OAuth2: &simulator.OAuth2Auth{
+			ClientID: "client", ClientSecret: "secret", TokenURL: "http://token.example.com",
+			RefreshToken: "refresh-0",
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, cfg); err != nil {
+		t.Fatalf("unexpected error on first call: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer token-1" {
+		t.Fatalf("expected Bearer token-1, got %q", got)
+	}
+
+	// The cached token is already expired (ExpiresAt: 1), so the next call
+	// must refresh — and must use refresh-1 (returned by the first call),
+	// not the original refresh-0 configured on cfg.
+	req2, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req2, cfg); err != nil {
+		t.Fatalf("unexpected error on second call: %v", err)
+	}
+	if got := req2.Header.Get("Authorization"); got != "Bearer token-2" {
+		t.Fatalf("expected Bearer token-2 after refresh, got %q", got)
+	}
+
+	if len(fetcher.calls) != 2 {
+		t.Fatalf("expected 2 fetcher calls, got %d", len(fetcher.calls))
+	}
+	if fetcher.calls[1].GrantType != "refresh_token" || fetcher.calls[1].RefreshToken != "refresh-1" {
+		t.Fatalf("expected refresh call to rotate to refresh-1, got grant=%q refresh_token=%q",
+			fetcher.calls[1].GrantType, fetcher.calls[1].RefreshToken)
+	}
+}
+
+func TestApplyAuthOAuth2NonBearerTokenType(t *testing.T) {
+	am := simulator.NewAuthManager()
+	am.OAuth2TokenFetcher = &fakeOAuth2Fetcher{
+		responses: []*simulator.TokenCache{{AccessToken: "mac-token", TokenType: "MAC"}},
+	}
+
+	cfg := &simulator.AuthConfig{
+		Type:   simulator.AuthTypeOAuth2,
+		OAuth2: &simulator.OAuth2Auth{ClientID: "c", ClientSecret: "s", TokenURL: "http://token.example.com"},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "MAC mac-token" {
+		t.Fatalf("expected a MAC-prefixed Authorization header, got %q", got)
+	}
+}
+
+func TestGeneratePKCEPairProducesMatchingS256Challenge(t *testing.T) {
+	verifier, challenge, err := simulator.GeneratePKCEPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verifier == "" || challenge == "" {
+		t.Fatalf("expected non-empty verifier and challenge")
+	}
+	if verifier == challenge {
+		t.Fatalf("expected a distinct S256 challenge from the verifier")
+	}
+}
+
+func TestOAuth2AuthorizationCodeGrantSendsCodeVerifier(t *testing.T) {
+	var gotVerifier string
+	am := simulator.NewAuthManager()
+	server := newFormCapturingTokenServer(t, func(form url.Values) {
+		gotVerifier = form.Get("code_verifier")
+	})
+	defer server.Close()
+
+	cfg := &simulator.AuthConfig{
+		Type: simulator.AuthTypeOAuth2,
+		OAuth2: &simulator.OAuth2Auth{
+			GrantType: "authorization_code", ClientID: "c", ClientSecret: "s",
+			TokenURL: server.URL, AuthorizationCode: "auth-code", CodeVerifier: "test-verifier",
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err := am.ApplyAuth(req, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotVerifier != "test-verifier" {
+		t.Fatalf("expected code_verifier to be sent to the token endpoint, got %q", gotVerifier)
+	}
+}
@@ -0,0 +1,101 @@
+package simulator_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+const testOpenAPISpec = `{
+	"swagger": "2.0",
+	"info": {"title": "Users API", "version": "1.0"},
+	"basePath": "/",
+	"paths": {
+		"/users/{id}": {
+			"get": {
+				"operationId": "getUser",
+				"produces": ["application/json"],
+				"responses": {
+					"200": {
+						"description": "ok",
+						"schema": {
+							"type": "object",
+							"required": ["id", "name"],
+							"properties": {
+								"id": {"type": "integer"},
+								"name": {"type": "string"}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}`
+
+func writeTestOpenAPISpec(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spec.json")
+	if err := os.WriteFile(path, []byte(testOpenAPISpec), 0o644); err != nil {
+		t.Fatalf("failed to write test spec: %v", err)
+	}
+	return path
+}
+
+func TestValidateOpenAPIAcceptsResponseMatchingOperationSchema(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	specPath := writeTestOpenAPISpec(t)
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": []string{"application/json"}}}
+	result := ve.ValidateResponse(resp, []byte(`{"id": 1, "name": "ada"}`), &simulator.ResponseValidation{
+		OpenAPISpec: specPath,
+		OperationID: "getUser",
+	}, time.Millisecond)
+
+	if !result.Passed {
+		t.Fatalf("expected response matching operation schema to pass, got errors: %+v", result.Errors)
+	}
+}
+
+func TestValidateOpenAPIRejectsResponseViolatingOperationSchema(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	specPath := writeTestOpenAPISpec(t)
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": []string{"application/json"}}}
+	result := ve.ValidateResponse(resp, []byte(`{"id": "not-an-integer"}`), &simulator.ResponseValidation{
+		OpenAPISpec: specPath,
+		OperationID: "getUser",
+	}, time.Millisecond)
+
+	if result.Passed {
+		t.Fatalf("expected missing required field and wrong type to fail validation")
+	}
+	found := false
+	for _, e := range result.Errors {
+		if e.Type == "openapi" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an openapi validation error, got %+v", result.Errors)
+	}
+}
+
+func TestValidateOpenAPIUnknownOperationIDReportsError(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	specPath := writeTestOpenAPISpec(t)
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": []string{"application/json"}}}
+	result := ve.ValidateResponse(resp, []byte(`{}`), &simulator.ResponseValidation{
+		OpenAPISpec: specPath,
+		OperationID: "doesNotExist",
+	}, time.Millisecond)
+
+	if result.Passed {
+		t.Fatalf("expected an unknown operationId to fail validation")
+	}
+}
@@ -0,0 +1,40 @@
+package simulator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestLocaleOverridePicksRequestedDataset(t *testing.T) {
+	vr := simulator.NewVariableResolver()
+
+	resolved, err := vr.Resolve(`{{address.postal_code locale="en_GB"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(resolved, " ") {
+		t.Errorf("expected a GB-shaped postal code with a space, got %q", resolved)
+	}
+
+	resolved, err = vr.Resolve(`{{random_phone locale="ja_JP"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(resolved, "+81") {
+		t.Errorf("expected a +81 JP phone number, got %q", resolved)
+	}
+}
+
+func TestLocaleOverrideUnknownLocaleErrors(t *testing.T) {
+	vr := simulator.NewVariableResolver()
+
+	resolved, err := vr.Resolve(`{{first_name locale="xx_XX"}}`)
+	if err == nil {
+		t.Fatal("expected an error for an unknown locale")
+	}
+	if resolved != `{{first_name locale="xx_XX"}}` {
+		t.Errorf("expected the placeholder to be left untouched, got %q", resolved)
+	}
+}
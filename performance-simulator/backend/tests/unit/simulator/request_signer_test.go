@@ -0,0 +1,66 @@
+package simulator_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestApplyAuthAWSSigV4SignsRequest(t *testing.T) {
+	am := simulator.NewAuthManager()
+	req, _ := http.NewRequest(http.MethodPut, "https://bucket.s3.amazonaws.com/key", strings.NewReader("payload"))
+
+	err := am.ApplyAuth(req, &simulator.AuthConfig{
+		Type: simulator.AuthTypeAWSSigV4,
+		AWSSigV4: &simulator.AWSSigV4Auth{
+			Region:          "us-east-1",
+			Service:         "s3",
+			AccessKeyID:     "AKIAEXAMPLE",
+			SecretAccessKey: "secret",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIAEXAMPLE/") {
+		t.Errorf("unexpected Authorization header: %q", auth)
+	}
+	if !strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("expected signed headers to include host/date/content-sha256, got %q", auth)
+	}
+	if req.Header.Get("x-amz-date") == "" {
+		t.Error("expected x-amz-date header to be set")
+	}
+	if req.Header.Get("x-amz-content-sha256") == "" {
+		t.Error("expected x-amz-content-sha256 header to be set")
+	}
+}
+
+func TestApplyAuthHMACSignsConfiguredHeadersAndBody(t *testing.T) {
+	am := simulator.NewAuthManager()
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/webhook", strings.NewReader(`{"ok":true}`))
+	req.Header.Set("X-Timestamp", "1700000000")
+
+	err := am.ApplyAuth(req, &simulator.AuthConfig{
+		Type: simulator.AuthTypeHMAC,
+		HMAC: &simulator.HMACAuth{
+			Secret:        "shh",
+			HeaderName:    "X-Signature",
+			Prefix:        "sha256=",
+			SignedHeaders: []string{"X-Timestamp"},
+			IncludeBody:   true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := req.Header.Get("X-Signature")
+	if !strings.HasPrefix(got, "sha256=") || len(got) != len("sha256=")+64 {
+		t.Errorf("expected a sha256= prefixed 64-char hex digest, got %q", got)
+	}
+}
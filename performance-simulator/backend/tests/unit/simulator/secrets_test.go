@@ -0,0 +1,117 @@
+package simulator_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestSecretManagerResolveEnvAndFile(t *testing.T) {
+	sm := simulator.NewSecretManager()
+
+	t.Setenv("ORIGO_TEST_SECRET", "env-value")
+	got, err := sm.Resolve("env://ORIGO_TEST_SECRET")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "env-value" {
+		t.Fatalf("expected env-value, got %q", got)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(path, []byte("file-value\n"), 0600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+	got, err = sm.Resolve("file://" + path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "file-value" {
+		t.Fatalf("expected file-value, got %q", got)
+	}
+
+	// A literal value (no "scheme://") passes through unchanged.
+	got, err = sm.Resolve("literal-value")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "literal-value" {
+		t.Fatalf("expected literal-value, got %q", got)
+	}
+}
+
+func TestSecretManagerResolvePathMaterializesTempFile(t *testing.T) {
+	sm := simulator.NewSecretManager()
+	t.Setenv("ORIGO_TEST_CERT", "pem-content")
+
+	path, err := sm.ResolvePath("env://ORIGO_TEST_CERT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read materialized secret file: %v", err)
+	}
+	if string(data) != "pem-content" {
+		t.Fatalf("expected pem-content, got %q", string(data))
+	}
+
+	// A literal path passes through unchanged rather than being copied.
+	literalPath, err := sm.ResolvePath("/etc/hosts")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if literalPath != "/etc/hosts" {
+		t.Fatalf("expected literal path to pass through, got %q", literalPath)
+	}
+}
+
+func TestSecretManagerUnknownSchemeErrors(t *testing.T) {
+	sm := simulator.NewSecretManager()
+	if _, err := sm.Resolve("vault://secret/data#field"); err == nil {
+		t.Fatalf("expected an error for an unregistered vault scheme")
+	}
+}
+
+// newVaultTestServer fakes a Vault KV v2 "read secret" endpoint for
+// mount/path, returning field as a string under data.data.
+func newVaultTestServer(t *testing.T, mount, path, field, value string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/"+mount+"/"+path {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data": map[string]interface{}{field: value},
+			},
+		})
+	}))
+}
+
+func TestVaultSecretResolverStaticTokenResolvesKVv2Field(t *testing.T) {
+	server := newVaultTestServer(t, "secret", "data/myapp", "password", "hunter2")
+	defer server.Close()
+
+	sm := simulator.NewSecretManager()
+	sm.RegisterResolver("vault", &simulator.VaultSecretResolver{
+		Addr:  server.URL,
+		Token: "static-token",
+	})
+
+	got, err := sm.Resolve("vault://secret/data/myapp#password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "hunter2" {
+		t.Fatalf("expected hunter2, got %q", got)
+	}
+}
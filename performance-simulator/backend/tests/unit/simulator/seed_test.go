@@ -0,0 +1,46 @@
+package simulator_test
+
+import (
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestSeededResolverIsDeterministic(t *testing.T) {
+	a := simulator.NewVariableResolverWithSeed(42)
+	b := simulator.NewVariableResolverWithSeed(42)
+
+	for i := 0; i < 5; i++ {
+		wantA, err := a.Resolve("{{random_int 0 1000000}}-{{random_string 12 \"alnum\"}}")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wantB, err := b.Resolve("{{random_int 0 1000000}}-{{random_string 12 \"alnum\"}}")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if wantA != wantB {
+			t.Fatalf("iteration %d: resolvers with the same seed diverged: %q vs %q", i, wantA, wantB)
+		}
+	}
+}
+
+func TestChildResolverSeedIsReproducible(t *testing.T) {
+	parentA := simulator.NewVariableResolverWithSeed(7)
+	childA := simulator.NewChildVariableResolver(parentA, 99)
+
+	parentB := simulator.NewVariableResolverWithSeed(7)
+	childB := simulator.NewChildVariableResolver(parentB, 99)
+
+	gotA, err := childA.Resolve("{{random_int 0 1000000}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gotB, err := childB.Resolve("{{random_int 0 1000000}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotA != gotB {
+		t.Errorf("expected identically-seeded child resolvers to match, got %q vs %q", gotA, gotB)
+	}
+}
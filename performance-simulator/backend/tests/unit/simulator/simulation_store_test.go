@@ -0,0 +1,140 @@
+package simulator_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/database"
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func newTestStore(t *testing.T) simulator.SimulationStore {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "simulations.db")
+	db, err := database.InitializeSQLite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to initialize test database: %v", err)
+	}
+	return simulator.NewGormSimulationStore(db)
+}
+
+func TestGormSimulationStoreSaveAndGetSimulation(t *testing.T) {
+	store := newTestStore(t)
+
+	config := &simulator.SimulationConfig{
+		ID:        1,
+		Name:      "checkout-load",
+		TargetURL: "https://example.com/checkout",
+		Method:    "POST",
+		MaxRPS:    5000,
+	}
+	status := &simulator.SimulationStatus{
+		ID:        1,
+		Name:      config.Name,
+		Status:    "running",
+		StartTime: time.Now(),
+	}
+
+	if err := store.SaveSimulationConfig(config, status); err != nil {
+		t.Fatalf("SaveSimulationConfig failed: %v", err)
+	}
+
+	persisted, err := store.GetSimulation(1)
+	if err != nil {
+		t.Fatalf("GetSimulation failed: %v", err)
+	}
+	if persisted.Status.Name != "checkout-load" || persisted.Config.TargetURL != config.TargetURL {
+		t.Fatalf("unexpected persisted simulation: %+v", persisted)
+	}
+
+	status.Status = "completed"
+	if err := store.SaveSimulationConfig(config, status); err != nil {
+		t.Fatalf("SaveSimulationConfig update failed: %v", err)
+	}
+	persisted, err = store.GetSimulation(1)
+	if err != nil {
+		t.Fatalf("GetSimulation after update failed: %v", err)
+	}
+	if persisted.Status.Status != "completed" {
+		t.Fatalf("expected status to be updated, got %q", persisted.Status.Status)
+	}
+}
+
+func TestGormSimulationStoreListSimulationsFilter(t *testing.T) {
+	store := newTestStore(t)
+
+	base := time.Now().Add(-time.Hour)
+	for i := int64(1); i <= 3; i++ {
+		config := &simulator.SimulationConfig{ID: i, Name: "sim", TargetURL: "https://example.com"}
+		status := &simulator.SimulationStatus{ID: i, Name: "sim", Status: "completed", StartTime: base.Add(time.Duration(i) * time.Minute)}
+		if err := store.SaveSimulationConfig(config, status); err != nil {
+			t.Fatalf("SaveSimulationConfig(%d) failed: %v", i, err)
+		}
+	}
+
+	sims, total, err := store.ListSimulations(simulator.SimulationListFilter{Status: "completed", Limit: 2})
+	if err != nil {
+		t.Fatalf("ListSimulations failed: %v", err)
+	}
+	if total != 3 {
+		t.Fatalf("expected total 3, got %d", total)
+	}
+	if len(sims) != 2 {
+		t.Fatalf("expected page of 2, got %d", len(sims))
+	}
+}
+
+func TestGormSimulationStoreTimeSeriesAndValidationRecords(t *testing.T) {
+	store := newTestStore(t)
+
+	points := []simulator.TimeSeriesPoint{
+		{Timestamp: time.Now(), RPS: 100, TargetRPS: 100, ResponseTime: 12.5, ActiveUsers: 10},
+		{Timestamp: time.Now(), RPS: 120, TargetRPS: 100, ResponseTime: 14.1, ActiveUsers: 10},
+	}
+	if err := store.AppendTimeSeriesPoints(42, points); err != nil {
+		t.Fatalf("AppendTimeSeriesPoints failed: %v", err)
+	}
+	got, err := store.GetTimeSeriesSince(42, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("GetTimeSeriesSince failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(got))
+	}
+
+	records := []simulator.ValidationRecord{
+		{SimulationID: 42, Timestamp: time.Now(), StatusCode: 200, ValidationResult: &simulator.ValidationResult{Passed: true}},
+	}
+	if err := store.AppendValidationRecords(42, records); err != nil {
+		t.Fatalf("AppendValidationRecords failed: %v", err)
+	}
+	listed, total, err := store.ListValidationRecords(42, 10, 0)
+	if err != nil {
+		t.Fatalf("ListValidationRecords failed: %v", err)
+	}
+	if total != 1 || len(listed) != 1 || !listed[0].ValidationResult.Passed {
+		t.Fatalf("unexpected validation records: total=%d listed=%+v", total, listed)
+	}
+}
+
+func TestGormSimulationStoreRetentionSweep(t *testing.T) {
+	store := newTestStore(t)
+
+	past := time.Now().Add(-48 * time.Hour)
+	endTime := past
+	config := &simulator.SimulationConfig{ID: 7, Name: "old-run", TargetURL: "https://example.com"}
+	status := &simulator.SimulationStatus{ID: 7, Name: "old-run", Status: "completed", StartTime: past, EndTime: &endTime}
+	if err := store.SaveSimulationConfig(config, status); err != nil {
+		t.Fatalf("SaveSimulationConfig failed: %v", err)
+	}
+
+	policy := simulator.RetentionPolicy{SimulationTTL: time.Hour}
+	if err := store.RunRetentionSweep(policy); err != nil {
+		t.Fatalf("RunRetentionSweep failed: %v", err)
+	}
+
+	if _, err := store.GetSimulation(7); err == nil {
+		t.Fatalf("expected simulation 7 to be swept, but it was still found")
+	}
+}
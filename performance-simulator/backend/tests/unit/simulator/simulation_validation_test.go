@@ -0,0 +1,110 @@
+package simulator_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/database"
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestValidateSimulationChecksPercentilesAndErrorRate(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+
+	results := make([]simulator.RequestResult, 0, 100)
+	for i := 0; i < 95; i++ {
+		results = append(results, simulator.RequestResult{ResponseTime: 50 * time.Millisecond, Success: true, StatusCode: 200, BodySize: 100})
+	}
+	for i := 0; i < 5; i++ {
+		results = append(results, simulator.RequestResult{ResponseTime: 2 * time.Second, Success: false, StatusCode: 500, BodySize: 100})
+	}
+
+	validation := &simulator.SimulationValidation{
+		MaxP95ResponseTime: 100 * time.Millisecond,
+		MaxErrorRate:       0.01,
+	}
+
+	result := ve.ValidateSimulation(validation, results, nil)
+	if result.Passed {
+		t.Fatalf("expected validation to fail on P95 and error rate")
+	}
+
+	var sawP95, sawErrorRate bool
+	for _, e := range result.Errors {
+		if e.Type == "simulation_p95" {
+			sawP95 = true
+		}
+		if e.Type == "simulation_error_rate" {
+			sawErrorRate = true
+		}
+	}
+	if !sawP95 {
+		t.Errorf("expected a simulation_p95 error, got %+v", result.Errors)
+	}
+	if !sawErrorRate {
+		t.Errorf("expected a simulation_error_rate error, got %+v", result.Errors)
+	}
+}
+
+func TestValidateSimulationFlagsBodySizeOutliers(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+
+	results := make([]simulator.RequestResult, 0, 50)
+	for i := 0; i < 49; i++ {
+		results = append(results, simulator.RequestResult{ResponseTime: 10 * time.Millisecond, Success: true, BodySize: 1000})
+	}
+	results = append(results, simulator.RequestResult{ResponseTime: 10 * time.Millisecond, Success: true, BodySize: 50000})
+
+	validation := &simulator.SimulationValidation{MaxBodySizeSigma: 2}
+
+	result := ve.ValidateSimulation(validation, results, nil)
+	if result.Passed {
+		t.Fatalf("expected the body-size outlier to fail validation")
+	}
+
+	found := false
+	for _, e := range result.Errors {
+		if e.Type == "simulation_body_size_anomaly" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a simulation_body_size_anomaly error, got %+v", result.Errors)
+	}
+}
+
+func TestValidateSimulationDistributionShiftRequiresSignificance(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+
+	// Only 3 samples: nowhere near enough for Mann-Whitney U to call a
+	// shift significant, so a large P95 move should warn, not fail.
+	results := []simulator.RequestResult{
+		{ResponseTime: 300 * time.Millisecond, Success: true},
+		{ResponseTime: 310 * time.Millisecond, Success: true},
+		{ResponseTime: 320 * time.Millisecond, Success: true},
+	}
+	baseline := &simulator.BaselineRun{
+		Result: &database.SimulationResult{
+			MedianResponseTime: int64(100 * time.Millisecond),
+			P95ResponseTime:    int64(100 * time.Millisecond),
+		},
+		ResponseTimes: []time.Duration{95 * time.Millisecond, 100 * time.Millisecond, 105 * time.Millisecond},
+	}
+
+	validation := &simulator.SimulationValidation{MaxResponseTimeShiftPercent: 10}
+
+	result := ve.ValidateSimulation(validation, results, baseline)
+	if !result.Passed {
+		t.Fatalf("expected a shift lacking statistical significance to warn, not fail: %+v", result.Errors)
+	}
+
+	found := false
+	for _, w := range result.Warnings {
+		if w.Type == "simulation_distribution_shift" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a simulation_distribution_shift warning, got %+v", result.Warnings)
+	}
+}
@@ -0,0 +1,66 @@
+package simulator_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestValidationStatsTrackerAggregatesPassFailCounts(t *testing.T) {
+	tracker := simulator.NewValidationStatsTracker()
+	defer tracker.Stop()
+
+	tracker.Record(1, &simulator.ValidationResult{Passed: true})
+	tracker.Record(1, &simulator.ValidationResult{Passed: false, Errors: []simulator.ValidationError{{Type: "status_code"}}})
+	tracker.Record(1, &simulator.ValidationResult{Passed: false, Errors: []simulator.ValidationError{{Type: "status_code"}}})
+
+	stats := tracker.Stats(1)
+	if stats.TotalValidations != 3 || stats.PassedValidations != 1 || stats.FailedValidations != 2 {
+		t.Fatalf("unexpected totals: %+v", stats)
+	}
+	if stats.PassRate < 0.33 || stats.PassRate > 0.34 {
+		t.Fatalf("expected pass rate ~0.333, got %f", stats.PassRate)
+	}
+}
+
+func TestValidationStatsTrackerRanksCommonErrorsByFrequency(t *testing.T) {
+	tracker := simulator.NewValidationStatsTrackerWithInterval(10 * time.Millisecond)
+	defer tracker.Stop()
+
+	tracker.Record(2, &simulator.ValidationResult{Passed: false, Errors: []simulator.ValidationError{{Type: "jsonpath"}}})
+	tracker.Record(2, &simulator.ValidationResult{Passed: false, Errors: []simulator.ValidationError{{Type: "status_code"}}})
+	tracker.Record(2, &simulator.ValidationResult{Passed: false, Errors: []simulator.ValidationError{{Type: "status_code"}}})
+
+	// CommonErrors is only rebuilt by the background refresh loop, not on
+	// every Record call, so give it a moment to tick.
+	deadline := time.Now().Add(2 * time.Second)
+	var common []simulator.ValidationErrorSummary
+	for time.Now().Before(deadline) {
+		common = tracker.Stats(2).CommonErrors
+		if len(common) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(common) != 2 {
+		t.Fatalf("expected CommonErrors to contain both error types once refreshed, got %+v", common)
+	}
+	if common[0].Type != "status_code" || common[0].Count != 2 {
+		t.Fatalf("expected status_code ranked first with count 2, got %+v", common[0])
+	}
+}
+
+func TestValidationStatsTrackerResetClearsSimulation(t *testing.T) {
+	tracker := simulator.NewValidationStatsTracker()
+	defer tracker.Stop()
+
+	tracker.Record(3, &simulator.ValidationResult{Passed: true})
+	tracker.Reset(3)
+
+	stats := tracker.Stats(3)
+	if stats.TotalValidations != 0 {
+		t.Fatalf("expected Reset to clear accumulated stats, got %+v", stats)
+	}
+}
@@ -0,0 +1,64 @@
+package simulator_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestValidateXMLBodyEvaluatesXPathAssertions(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+	body := []byte(`<user><id>42</id><name>Ada</name></user>`)
+
+	result := ve.ValidateResponse(resp, body, &simulator.ResponseValidation{
+		Body: &simulator.BodyValidation{
+			Type: simulator.ValidationTypeXML,
+			XPath: []simulator.XPathAssertion{
+				{XPath: "//id/text()", Expected: "42", Operator: "equals"},
+				{XPath: "//missing", Operator: "exists"},
+			},
+		},
+	}, time.Millisecond)
+
+	if result.Passed {
+		t.Fatalf("expected a missing node to fail the exists assertion")
+	}
+	found := false
+	for _, e := range result.Errors {
+		if e.Type == "xpath" && e.Field == "//missing" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an xpath error for //missing, got %+v", result.Errors)
+	}
+}
+
+func TestValidateXMLBodyResolvesNamespacedXPath(t *testing.T) {
+	ve := simulator.NewValidationEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+	body := []byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+		<soap:Body><GetUserResponse><Name>Grace</Name></GetUserResponse></soap:Body>
+	</soap:Envelope>`)
+
+	result := ve.ValidateResponse(resp, body, &simulator.ResponseValidation{
+		Body: &simulator.BodyValidation{
+			Type: simulator.ValidationTypeXML,
+			XPath: []simulator.XPathAssertion{
+				{
+					XPath:      "//soap:Body/GetUserResponse/Name/text()",
+					Expected:   "Grace",
+					Operator:   "equals",
+					Namespaces: map[string]string{"soap": "http://schemas.xmlsoap.org/soap/envelope/"},
+				},
+			},
+		},
+	}, time.Millisecond)
+
+	if !result.Passed {
+		t.Fatalf("expected namespaced XPath assertion to pass, got errors: %+v", result.Errors)
+	}
+}
@@ -0,0 +1,75 @@
+package simulator_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestResolveParameterizedExpressions(t *testing.T) {
+	vr := simulator.NewVariableResolver()
+
+	resolved, err := vr.Resolve("{{random_int 100 101}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n, err := strconv.Atoi(resolved)
+	if err != nil {
+		t.Fatalf("expected an integer, got %q", resolved)
+	}
+	if n < 100 || n > 101 {
+		t.Errorf("expected random_int in [100,101], got %d", n)
+	}
+
+	resolved, err = vr.Resolve(`{{random_string 8 "hex"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resolved) != 8 {
+		t.Errorf("expected an 8-char string, got %q", resolved)
+	}
+	for _, c := range resolved {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			t.Errorf("expected only hex characters, got %q", resolved)
+		}
+	}
+
+	resolved, err = vr.Resolve(`{{pick "gold","silver","bronze"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved != "gold" && resolved != "silver" && resolved != "bronze" {
+		t.Errorf("expected one of the picked values, got %q", resolved)
+	}
+
+	resolved, err = vr.Resolve("{{uuid | upper}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved != strings.ToUpper(resolved) {
+		t.Errorf("expected an upper-cased uuid, got %q", resolved)
+	}
+
+	// Old no-arg calls must keep working unmodified.
+	resolved, err = vr.Resolve("{{random_bool}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved != "true" && resolved != "false" {
+		t.Errorf("expected true/false, got %q", resolved)
+	}
+}
+
+func TestResolveUnknownFunctionReturnsError(t *testing.T) {
+	vr := simulator.NewVariableResolver()
+
+	resolved, err := vr.Resolve("id: {{does_not_exist}}")
+	if err == nil {
+		t.Fatal("expected an error for an unknown function")
+	}
+	if resolved != "id: {{does_not_exist}}" {
+		t.Errorf("expected the placeholder to be left untouched, got %q", resolved)
+	}
+}
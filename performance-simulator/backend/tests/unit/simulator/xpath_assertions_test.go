@@ -0,0 +1,49 @@
+package simulator_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/origo-stack/performance-simulator/internal/simulator"
+)
+
+func TestExecuteAssertionsXPathAndXMLContains(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": []string{"application/xml"}}}
+	body := []byte(`<user><id>42</id><name>Ada</name></user>`)
+
+	results := ae.ExecuteAssertions(resp, body, []simulator.Assertion{
+		{Name: "id", Script: `xpath('//id/text()') == '42'`},
+		{Name: "name-contains", Script: `xmlcontains('//name', 'Ada')`},
+	}, 0)
+
+	for _, r := range results {
+		if r.Error != "" {
+			t.Fatalf("assertion %q errored: %s", r.Name, r.Error)
+		}
+		if !r.Passed {
+			t.Errorf("expected assertion %q to pass, got %v", r.Name, r.Value)
+		}
+	}
+}
+
+func TestExecuteAssertionsSoapEvaluatesAgainstResponseElement(t *testing.T) {
+	ae := simulator.NewAssertionEngine()
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": []string{"application/soap+xml"}}}
+	body := []byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+		<soap:Body>
+			<GetUserResponse><Name>Grace</Name></GetUserResponse>
+		</soap:Body>
+	</soap:Envelope>`)
+
+	results := ae.ExecuteAssertions(resp, body, []simulator.Assertion{
+		{Name: "name", Script: `soap('//Name/text()') == 'Grace'`},
+	}, 0)
+
+	if results[0].Error != "" {
+		t.Fatalf("assertion errored: %s", results[0].Error)
+	}
+	if !results[0].Passed {
+		t.Errorf("expected soap assertion to pass, got %v", results[0].Value)
+	}
+}
@@ -0,0 +1,140 @@
+package websocket_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/origo-stack/performance-simulator/internal/websocket"
+)
+
+// readMessage reads and decodes the next frame from conn as a generic
+// map, skipping the connection_established welcome frame if it's next.
+func readMessage(t *testing.T, conn *gorillaws.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatalf("failed to unmarshal message: %v", err)
+		}
+		if msg["type"] == "connection_established" {
+			continue
+		}
+		return msg
+	}
+}
+
+// TestHubSplitsOversizedFramesIntoChunkedFrames verifies that a broadcast
+// payload larger than the hub's configured maxFrameBytes arrives as ordered
+// chunked_frame messages whose concatenated chunks reassemble the original
+// message's JSON.
+func TestHubSplitsOversizedFramesIntoChunkedFrames(t *testing.T) {
+	const maxFrameBytes = 256
+
+	hub := websocket.NewHub(websocket.WithMaxFrameBytes(maxFrameBytes))
+	go hub.Run()
+
+	server := newTestServer(t, hub)
+	defer server.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	bigPayload := strings.Repeat("x", maxFrameBytes*4)
+	hub.Broadcast("simulation_completed", map[string]interface{}{
+		"simulation_id": 7,
+		"results":       bigPayload,
+	})
+
+	var total int
+	chunks := make(map[int]string)
+	for {
+		msg := readMessage(t, conn)
+		if msg["type"] != "chunked_frame" {
+			t.Fatalf("expected chunked_frame, got %v", msg["type"])
+		}
+		data := msg["data"].(map[string]interface{})
+		seq := int(data["seq"].(float64))
+		total = int(data["total"].(float64))
+		if simID := data["sim_id"]; simID == nil {
+			t.Fatalf("expected sim_id on chunked_frame, got nil")
+		}
+		chunks[seq] = data["chunk"].(string)
+		if len(chunks) == total {
+			break
+		}
+	}
+
+	var reassembled strings.Builder
+	for i := 0; i < total; i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			t.Fatalf("missing chunk for seq %d", i)
+		}
+		reassembled.WriteString(chunk)
+	}
+
+	var original websocket.Message
+	if err := json.Unmarshal([]byte(reassembled.String()), &original); err != nil {
+		t.Fatalf("reassembled chunks did not form valid JSON: %v", err)
+	}
+	if original.Type != "simulation_completed" {
+		t.Errorf("expected reassembled type simulation_completed, got %s", original.Type)
+	}
+	resultData := original.Data.(map[string]interface{})
+	if resultData["results"] != bigPayload {
+		t.Errorf("reassembled payload did not match original big payload")
+	}
+}
+
+// TestHubGetSnapshotReturnsProviderValue verifies that a client's
+// get_snapshot request is answered with the registered snapshot provider's
+// current value for the requested path.
+func TestHubGetSnapshotReturnsProviderValue(t *testing.T) {
+	hub := websocket.NewHub()
+	hub.SetSnapshotProvider(func(path string) (interface{}, bool) {
+		if path == "simulations/42/history" {
+			return []int{1, 2, 3}, true
+		}
+		return nil, false
+	})
+	go hub.Run()
+
+	server := newTestServer(t, hub)
+	defer server.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	request := websocket.Message{
+		Type: "get_snapshot",
+		Data: map[string]interface{}{"path": "simulations/42/history"},
+	}
+	if err := conn.WriteJSON(request); err != nil {
+		t.Fatalf("failed to send get_snapshot request: %v", err)
+	}
+
+	msg := readMessage(t, conn)
+	if msg["type"] != "snapshot" {
+		t.Fatalf("expected snapshot response, got %v", msg["type"])
+	}
+	data := msg["data"].(map[string]interface{})
+	if data["path"] != "simulations/42/history" {
+		t.Errorf("expected path echoed back, got %v", data["path"])
+	}
+	value := data["value"].([]interface{})
+	if len(value) != 3 {
+		t.Errorf("expected 3 values in snapshot, got %d", len(value))
+	}
+}
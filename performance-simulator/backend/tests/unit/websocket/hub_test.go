@@ -0,0 +1,112 @@
+package websocket_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/origo-stack/performance-simulator/internal/websocket"
+)
+
+func newTestServer(t *testing.T, hub *websocket.Hub) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", hub.HandleWebSocket)
+	return httptest.NewServer(router)
+}
+
+func dial(t *testing.T, server *httptest.Server) *gorillaws.Conn {
+	t.Helper()
+	url := "ws" + server.URL[len("http"):] + "/ws"
+	conn, _, err := gorillaws.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial test server: %v", err)
+	}
+	return conn
+}
+
+// TestHubBroadcastNoLostMessagesForHealthyClients spins up many clients, lets
+// a subset stop reading entirely, and asserts every healthy client still
+// receives every broadcast message despite the slow ones falling behind.
+func TestHubBroadcastNoLostMessagesForHealthyClients(t *testing.T) {
+	hub := websocket.NewHub(websocket.WithQueueCapacity(8), websocket.WithOverflowPolicy(websocket.DropOldest))
+	go hub.Run()
+
+	server := newTestServer(t, hub)
+	defer server.Close()
+
+	const (
+		healthyClients = 20
+		slowClients    = 5
+		messages       = 30
+	)
+
+	healthyConns := make([]*gorillaws.Conn, 0, healthyClients)
+	for i := 0; i < healthyClients; i++ {
+		healthyConns = append(healthyConns, dial(t, server))
+	}
+	slowConns := make([]*gorillaws.Conn, 0, slowClients)
+	for i := 0; i < slowClients; i++ {
+		slowConns = append(slowConns, dial(t, server))
+	}
+	defer func() {
+		for _, conn := range healthyConns {
+			conn.Close()
+		}
+		for _, conn := range slowConns {
+			conn.Close()
+		}
+	}()
+
+	// Give the hub a moment to register everyone before the slow clients
+	// stop draining their socket buffers.
+	time.Sleep(100 * time.Millisecond)
+
+	received := make([]int, len(healthyConns))
+	done := make(chan struct{})
+	for i, conn := range healthyConns {
+		go func(i int, conn *gorillaws.Conn) {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			count := 0
+			for {
+				_, _, err := conn.ReadMessage()
+				if err != nil {
+					break
+				}
+				count++
+				if count >= messages+1 { // +1 for connection_established
+					break
+				}
+			}
+			received[i] = count
+			done <- struct{}{}
+		}(i, conn)
+	}
+
+	for i := 0; i < messages; i++ {
+		hub.Broadcast("simulation_started", map[string]interface{}{"seq": i})
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	for range healthyConns {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for healthy client to drain messages")
+		}
+	}
+
+	for i, count := range received {
+		if count < messages {
+			t.Errorf("healthy client %d received %d messages, want at least %d", i, count, messages)
+		}
+	}
+
+	stats := hub.Stats()
+	if len(stats) != healthyClients+slowClients {
+		t.Errorf("expected stats for %d clients, got %d", healthyClients+slowClients, len(stats))
+	}
+}